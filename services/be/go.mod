@@ -7,6 +7,7 @@ require (
 	connectrpc.com/validate v0.6.0
 	github.com/google/uuid v1.6.0
 	github.com/jackc/pgx/v5 v5.7.2
+	github.com/klauspost/compress v1.18.0
 	github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go v0.0.0
 	golang.org/x/net v0.37.0
 	google.golang.org/protobuf v1.36.11
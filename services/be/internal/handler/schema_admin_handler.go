@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	adminv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/admin/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SchemaController interface for controlling the loaded schema, implemented by schemamanager.SchemaManager
+type SchemaController interface {
+	Target() string
+	CurrentVersion() string
+	PinnedVersion() string
+	History() []validator.LoadedVersion
+	ShadowStats() (validator.ShadowStats, bool)
+	Rollback(version string) error
+	Pin(version string) error
+	Unpin(ctx context.Context) string
+}
+
+// SchemaControllers looks up the SchemaController of a schema target; "" selects the default target
+type SchemaControllers func(target string) (SchemaController, bool)
+
+// SchemaAdminHandler implements the SchemaAdminService
+type SchemaAdminHandler struct {
+	schemas SchemaControllers
+}
+
+// NewSchemaAdminHandler creates a new SchemaAdminHandler
+func NewSchemaAdminHandler(schemas SchemaControllers) *SchemaAdminHandler {
+	return &SchemaAdminHandler{schemas: schemas}
+}
+
+func (h *SchemaAdminHandler) controller(target string) (SchemaController, error) {
+	schemas, ok := h.schemas(target)
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("unknown schema target %q", target))
+	}
+	return schemas, nil
+}
+
+// GetSchemaState returns the current, pinned and previously loaded versions,
+// and the disagreements of a running canary
+func (h *SchemaAdminHandler) GetSchemaState(
+	ctx context.Context,
+	req *connect.Request[adminv1.GetSchemaStateRequest],
+) (*connect.Response[adminv1.GetSchemaStateResponse], error) {
+	schemas, err := h.controller(req.Msg.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	history := schemas.History()
+	loaded := make([]*adminv1.LoadedVersion, 0, len(history))
+	for _, v := range history {
+		loaded = append(loaded, &adminv1.LoadedVersion{
+			Version:  v.Version,
+			LoadedAt: timestamppb.New(v.LoadedAt),
+		})
+	}
+
+	resp := &adminv1.GetSchemaStateResponse{
+		CurrentVersion: schemas.CurrentVersion(),
+		PinnedVersion:  schemas.PinnedVersion(),
+		History:        loaded,
+		Target:         schemas.Target(),
+	}
+	if stats, ok := schemas.ShadowStats(); ok {
+		resp.CanaryVersion = stats.Version
+		for _, d := range stats.Examples {
+			resp.CanaryDisagreements = append(resp.CanaryDisagreements, &adminv1.CanaryDisagreement{
+				Message: d.Message,
+				Current: d.Current,
+				Canary:  d.Shadow,
+			})
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// Rollback switches back to a previously loaded version and pins it
+func (h *SchemaAdminHandler) Rollback(
+	ctx context.Context,
+	req *connect.Request[adminv1.RollbackRequest],
+) (*connect.Response[adminv1.RollbackResponse], error) {
+	schemas, err := h.controller(req.Msg.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := schemas.CurrentVersion()
+	if err := schemas.Rollback(req.Msg.Version); err != nil {
+		return nil, schemaControlError(err)
+	}
+
+	return connect.NewResponse(&adminv1.RollbackResponse{
+		PreviousVersion: previous,
+		CurrentVersion:  schemas.CurrentVersion(),
+	}), nil
+}
+
+// Pin keeps the current version loaded until Unpin
+func (h *SchemaAdminHandler) Pin(
+	ctx context.Context,
+	req *connect.Request[adminv1.PinRequest],
+) (*connect.Response[adminv1.PinResponse], error) {
+	schemas, err := h.controller(req.Msg.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schemas.Pin(req.Msg.Version); err != nil {
+		return nil, schemaControlError(err)
+	}
+
+	return connect.NewResponse(&adminv1.PinResponse{
+		PinnedVersion: req.Msg.Version,
+	}), nil
+}
+
+// Unpin resumes following ISR
+func (h *SchemaAdminHandler) Unpin(
+	ctx context.Context,
+	req *connect.Request[adminv1.UnpinRequest],
+) (*connect.Response[adminv1.UnpinResponse], error) {
+	schemas, err := h.controller(req.Msg.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	unpinned := schemas.Unpin(ctx)
+
+	return connect.NewResponse(&adminv1.UnpinResponse{
+		UnpinnedVersion: unpinned,
+		CurrentVersion:  schemas.CurrentVersion(),
+	}), nil
+}
+
+func schemaControlError(err error) error {
+	switch {
+	case errors.Is(err, validator.ErrUnknownVersion):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, validator.ErrNotCurrent):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	default:
+		return connect.NewError(connect.CodeInternal, err)
+	}
+}
+
+// NewAdminTokenInterceptor returns a connect interceptor that rejects requests without
+// "Authorization: Bearer <token>" (Unauthenticated)
+func NewAdminTokenInterceptor(token string) connect.UnaryInterceptorFunc {
+	want := []byte("Bearer " + token)
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			got := []byte(req.Header().Get("Authorization"))
+			if subtle.ConstantTimeCompare(got, want) != 1 {
+				return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("invalid or missing admin token"))
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	adminv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/admin/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/admin/v1/adminv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// createTestDescriptorBytes creates a test FileDescriptorSet from the user.v1 package
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := validator.DescriptorSetFromFiles("user/v1/user.proto")
+	if err != nil {
+		t.Fatalf("failed to create descriptor set: %v", err)
+	}
+	return data
+}
+
+// validatorController controls a SchemaAwareValidator directly, without a schema manager
+type validatorController struct {
+	*validator.SchemaAwareValidator
+	target string
+}
+
+func (c validatorController) Target() string                   { return c.target }
+func (c validatorController) CurrentVersion() string           { return c.GetCurrentVersion() }
+func (c validatorController) Unpin(ctx context.Context) string { return c.SchemaAwareValidator.Unpin() }
+
+func newValidatorController(t *testing.T, target string, versions ...string) validatorController {
+	t.Helper()
+	v := &validator.SchemaAwareValidator{}
+	for _, version := range versions {
+		if err := v.UpdateSchema(createTestDescriptorBytes(t), version); err != nil {
+			t.Fatalf("UpdateSchema failed: %v", err)
+		}
+	}
+	return validatorController{v, target}
+}
+
+// singleTarget serves controller as the default target and under its own name
+func singleTarget(controller validatorController) SchemaControllers {
+	return func(target string) (SchemaController, bool) {
+		if target != "" && target != controller.target {
+			return nil, false
+		}
+		return controller, true
+	}
+}
+
+func TestSchemaAdminHandler_RollbackAndUnpin(t *testing.T) {
+	controller := newValidatorController(t, "1.0", "1.0.5", "1.0.6", "1.0.7")
+	handler := NewSchemaAdminHandler(singleTarget(controller))
+	ctx := context.Background()
+
+	state, err := handler.GetSchemaState(ctx, connect.NewRequest(&adminv1.GetSchemaStateRequest{}))
+	if err != nil {
+		t.Fatalf("GetSchemaState() error = %v", err)
+	}
+	if state.Msg.Target != "1.0" || state.Msg.CurrentVersion != "1.0.7" || state.Msg.PinnedVersion != "" || len(state.Msg.History) != 2 ||
+		state.Msg.History[0].Version != "1.0.6" || state.Msg.History[1].Version != "1.0.5" {
+		t.Errorf("GetSchemaState() = %+v", state.Msg)
+	}
+
+	rollback, err := handler.Rollback(ctx, connect.NewRequest(&adminv1.RollbackRequest{Version: "1.0.6", Target: "1.0"}))
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if rollback.Msg.PreviousVersion != "1.0.7" || rollback.Msg.CurrentVersion != "1.0.6" {
+		t.Errorf("Rollback() = %+v", rollback.Msg)
+	}
+	if pinned := controller.PinnedVersion(); pinned != "1.0.6" {
+		t.Errorf("pinned version = %q, want 1.0.6", pinned)
+	}
+
+	unpin, err := handler.Unpin(ctx, connect.NewRequest(&adminv1.UnpinRequest{}))
+	if err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	if unpin.Msg.UnpinnedVersion != "1.0.6" || unpin.Msg.CurrentVersion != "1.0.6" {
+		t.Errorf("Unpin() = %+v", unpin.Msg)
+	}
+}
+
+// canaryController reports a running canary with fixed statistics
+type canaryController struct {
+	validatorController
+	stats validator.ShadowStats
+}
+
+func (c canaryController) ShadowStats() (validator.ShadowStats, bool) { return c.stats, true }
+
+func TestSchemaAdminHandler_CanaryDisagreements(t *testing.T) {
+	controller := canaryController{
+		validatorController: newValidatorController(t, "1.0", "1.0.0"),
+		stats: validator.ShadowStats{
+			Version:       "1.0.1",
+			Samples:       3,
+			Disagreements: 1,
+			Examples: []validator.Disagreement{
+				{Message: "user.v1.CreateUserRequest", Shadow: "name: value length must be at least 5 characters"},
+			},
+		},
+	}
+	handler := NewSchemaAdminHandler(func(string) (SchemaController, bool) { return controller, true })
+
+	state, err := handler.GetSchemaState(context.Background(), connect.NewRequest(&adminv1.GetSchemaStateRequest{}))
+	if err != nil {
+		t.Fatalf("GetSchemaState() error = %v", err)
+	}
+	if state.Msg.CanaryVersion != "1.0.1" || len(state.Msg.CanaryDisagreements) != 1 {
+		t.Fatalf("GetSchemaState() = %+v, want one disagreement of 1.0.1", state.Msg)
+	}
+	if d := state.Msg.CanaryDisagreements[0]; d.Message != "user.v1.CreateUserRequest" || d.Current != "" ||
+		d.Canary != "name: value length must be at least 5 characters" {
+		t.Errorf("disagreement = %+v, want only the canary to reject", d)
+	}
+}
+
+func TestSchemaAdminHandler_Errors(t *testing.T) {
+	handler := NewSchemaAdminHandler(singleTarget(newValidatorController(t, "1.0", "1.0.0")))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want connect.Code
+	}{
+		{"rollback to an unknown version", func() error {
+			_, err := handler.Rollback(ctx, connect.NewRequest(&adminv1.RollbackRequest{Version: "0.9.0"}))
+			return err
+		}, connect.CodeNotFound},
+		{"pin another version", func() error {
+			_, err := handler.Pin(ctx, connect.NewRequest(&adminv1.PinRequest{Version: "1.0.1"}))
+			return err
+		}, connect.CodeFailedPrecondition},
+		{"unknown target", func() error {
+			_, err := handler.GetSchemaState(ctx, connect.NewRequest(&adminv1.GetSchemaStateRequest{Target: "2.0"}))
+			return err
+		}, connect.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) || connectErr.Code() != tt.want {
+				t.Errorf("error = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAdminTokenInterceptor(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(adminv1connect.NewSchemaAdminServiceHandler(
+		NewSchemaAdminHandler(singleTarget(newValidatorController(t, "1.0", "1.0.0"))),
+		connect.WithInterceptors(NewAdminTokenInterceptor("secret")),
+	))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := adminv1connect.NewSchemaAdminServiceClient(http.DefaultClient, server.URL)
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := connect.NewRequest(&adminv1.GetSchemaStateRequest{})
+		if header != "" {
+			req.Header().Set("Authorization", header)
+		}
+		if _, err := client.GetSchemaState(context.Background(), req); connect.CodeOf(err) != connect.CodeUnauthenticated {
+			t.Errorf("GetSchemaState() with %q error = %v, want Unauthenticated", header, err)
+		}
+	}
+
+	req := connect.NewRequest(&adminv1.GetSchemaStateRequest{})
+	req.Header().Set("Authorization", "Bearer secret")
+	resp, err := client.GetSchemaState(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetSchemaState() error = %v", err)
+	}
+	if resp.Msg.CurrentVersion != "1.0.0" {
+		t.Errorf("current version = %s, want 1.0.0", resp.Msg.CurrentVersion)
+	}
+}
@@ -0,0 +1,290 @@
+package schemamanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// CanaryConfig controls the shadow phase a new schema goes through before it replaces the current one.
+// The zero value hot-swaps immediately.
+type CanaryConfig struct {
+	// Duration is how long a new schema validates live requests alongside the current one
+	// without affecting their result. Zero disables the canary phase.
+	Duration time.Duration
+
+	// Samples ends the canary phase early once this many requests were compared. Zero waits for Duration.
+	Samples int64
+
+	// MaxRejectionRateDelta is the largest allowed difference between the rejection rates of the
+	// new and the current schema (0 to 1, in either direction). Beyond it the new version is blocked.
+	MaxRejectionRateDelta float64
+}
+
+// DefaultCanaryConfig returns the canary settings used by NewConfig: disabled until Duration is set,
+// then blocking versions whose rejection rate differs by more than one percentage point
+func DefaultCanaryConfig() CanaryConfig {
+	return CanaryConfig{
+		MaxRejectionRateDelta: 0.01,
+	}
+}
+
+func (c CanaryConfig) enabled() bool {
+	return c.Duration > 0
+}
+
+// CanaryStatus describes the schema currently shadowing the loaded one
+type CanaryStatus struct {
+	StartedAt time.Time `json:"started_at"`
+	validator.ShadowStats
+	RejectionRateDelta float64 `json:"rejection_rate_delta"`
+}
+
+// BlockedVersion is a version that failed its canary phase and is not loaded again by this instance
+type BlockedVersion struct {
+	Version   string    `json:"version"`
+	Reason    string    `json:"reason"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// canaryRun is a schema in its canary phase, guarded by SchemaManager.canaryMu
+type canaryRun struct {
+	latest    *isrv1.GetLatestPatchResponse
+	version   string
+	startedAt time.Time
+	stop      chan struct{} // Closed when the run is superseded; the run then ends without a decision
+}
+
+// startCanary shadows the current schema with latest. It does nothing if latest is already in its canary phase
+// and supersedes the canary of any other version.
+func (m *SchemaManager) startCanary(latest *isrv1.GetLatestPatchResponse) error {
+	version := latest.Metadata.Version
+
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	if m.canary != nil {
+		if m.canary.version == version {
+			return nil
+		}
+		log.Printf("Canary of schema %s superseded by %s", m.canary.version, version)
+		m.cancelCanaryLocked()
+	}
+
+	done, err := m.validator.StartShadow(latest.SchemaBinary, version, m.config.Canary.Samples)
+	if err != nil {
+		return fmt.Errorf("failed to start canary for schema %s: %w", version, err)
+	}
+	run := &canaryRun{
+		latest:    latest,
+		version:   version,
+		startedAt: m.clock.Now(),
+		stop:      make(chan struct{}),
+	}
+	m.canary = run
+
+	m.canaryWG.Add(1)
+	go func() {
+		defer m.canaryWG.Done()
+		select {
+		case <-done:
+		case <-m.clock.After(m.config.Canary.Duration):
+		case <-run.stop:
+			return
+		case <-m.stopCh:
+			return
+		}
+		m.finishCanary(run)
+	}()
+
+	log.Printf("Canary started: schema %s shadows %s for %s", version, m.validator.GetCurrentVersion(), m.config.Canary.Duration)
+	return nil
+}
+
+// cancelCanary drops the running canary, if any, without promoting or blocking it.
+// Used when ISR moves away from the version under test or an operator pins the schema.
+func (m *SchemaManager) cancelCanary(reason string) {
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	if m.canary != nil {
+		log.Printf("Canary of schema %s cancelled: %s", m.canary.version, reason)
+		m.cancelCanaryLocked()
+	}
+}
+
+// cancelCanaryLocked stops the running canary. m.canaryMu must be held and m.canary set.
+func (m *SchemaManager) cancelCanaryLocked() {
+	close(m.canary.stop)
+	m.canary = nil
+	m.validator.StopShadow()
+}
+
+// finishCanary promotes the shadow schema if its rejection rate stayed within the threshold
+// and blocks its version otherwise. The shadow is always removed.
+func (m *SchemaManager) finishCanary(run *canaryRun) {
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	if m.canary != run {
+		return // Superseded while the decision was pending
+	}
+	m.canary = nil
+
+	stats, _ := m.validator.ShadowStats()
+	delta := stats.RejectionRateDelta()
+	previous := m.validator.GetCurrentVersion()
+
+	if math.Abs(delta) > m.config.Canary.MaxRejectionRateDelta {
+		m.validator.StopShadow()
+		reason := fmt.Sprintf("rejection rate changed by %+.2f%% over %d requests (max %.2f%%), %d disagreements",
+			delta*100, stats.Samples, m.config.Canary.MaxRejectionRateDelta*100, stats.Disagreements)
+		m.blockVersionLocked(run.version, reason)
+		log.Printf("Canary failed: schema %s blocked, keeping %s: %s", run.version, previous, reason)
+		return
+	}
+
+	if err := m.validator.PromoteShadow(run.version); err != nil {
+		m.validator.StopShadow()
+		if errors.Is(err, validator.ErrPinned) {
+			// Pinned while the decision was pending: no canary restarts until Unpin, which retries it
+			log.Printf("Canary of schema %s dropped: %v", run.version, err)
+			return
+		}
+		// Blocked so that the next poll does not run the same canary again
+		m.blockVersionLocked(run.version, fmt.Sprintf("promotion failed: %v", err))
+		log.Printf("Failed to promote canary schema %s, blocked, keeping %s: %v", run.version, previous, err)
+		return
+	}
+	m.setLoaded(SourceISR)
+	if err := m.saveCache(run.latest); err != nil {
+		log.Printf("Failed to cache schema %s: %v", run.version, err)
+	}
+	log.Printf("Hot-swapped validator after canary: %s -> %s (%d requests compared, rejection rate %+.2f%%)",
+		previous, run.version, stats.Samples, delta*100)
+	warnSchemaStatus(run.latest.Metadata)
+}
+
+// canaryStatus returns the running canary, or nil
+func (m *SchemaManager) canaryStatus() *CanaryStatus {
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	if m.canary == nil {
+		return nil
+	}
+	stats, ok := m.validator.ShadowStats()
+	if !ok {
+		return nil
+	}
+	return &CanaryStatus{
+		StartedAt:          m.canary.startedAt,
+		ShadowStats:        stats,
+		RejectionRateDelta: stats.RejectionRateDelta(),
+	}
+}
+
+// blockedVersion returns why version is blocked, if it is
+func (m *SchemaManager) blockedVersion(version string) (BlockedVersion, bool) {
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	blocked, ok := m.blocked[version]
+	return blocked, ok
+}
+
+// blockedVersions returns the blocked versions sorted by version
+func (m *SchemaManager) blockedVersions() []BlockedVersion {
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	return m.blockedVersionsLocked()
+}
+
+func (m *SchemaManager) blockedVersionsLocked() []BlockedVersion {
+	var versions []BlockedVersion
+	for _, blocked := range m.blocked {
+		versions = append(versions, blocked)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions
+}
+
+// blockVersionLocked blocks version and persists the block next to the schema cache. m.canaryMu must be held.
+func (m *SchemaManager) blockVersionLocked(version, reason string) {
+	if m.blocked == nil {
+		m.blocked = make(map[string]BlockedVersion)
+	}
+	m.blocked[version] = BlockedVersion{
+		Version:   version,
+		Reason:    reason,
+		BlockedAt: m.clock.Now(),
+	}
+	if err := m.saveBlocked(m.blockedVersionsLocked()); err != nil {
+		log.Printf("Failed to persist blocked schema versions: %v", err)
+	}
+}
+
+// blockedPath returns the path of the blocked versions file, or "" if caching is disabled
+func (m *SchemaManager) blockedPath() string {
+	path := m.cachePath()
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".blocked.json"
+}
+
+func (m *SchemaManager) saveBlocked(versions []BlockedVersion) error {
+	path := m.blockedPath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked versions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write blocked versions file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename blocked versions file: %w", err)
+	}
+	return nil
+}
+
+// loadBlocked restores the versions blocked before a restart. Delete the file to unblock them.
+func (m *SchemaManager) loadBlocked() error {
+	path := m.blockedPath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read blocked versions file: %w", err)
+	}
+	var versions []BlockedVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return fmt.Errorf("failed to unmarshal blocked versions file %s: %w", path, err)
+	}
+
+	m.canaryMu.Lock()
+	defer m.canaryMu.Unlock()
+	m.blocked = make(map[string]BlockedVersion, len(versions))
+	for _, blocked := range versions {
+		m.blocked[blocked.Version] = blocked
+	}
+	return nil
+}
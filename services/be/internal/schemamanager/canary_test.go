@@ -0,0 +1,208 @@
+package schemamanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	commonv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/common/v1"
+	userv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// createDescriptorBytesWithNameMinLen returns the user.v1 descriptor set with the
+// min_len rule of CreateUserRequest.name replaced, simulating a patch published to ISR.
+func createDescriptorBytesWithNameMinLen(t *testing.T, minLen uint64) []byte {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(createTestDescriptorBytes(t), fds); err != nil {
+		t.Fatalf("failed to unmarshal descriptor set: %v", err)
+	}
+
+	for _, file := range fds.File {
+		if file.GetName() != "user/v1/user.proto" {
+			continue
+		}
+		for _, msg := range file.MessageType {
+			if msg.GetName() != "CreateUserRequest" {
+				continue
+			}
+			for _, field := range msg.Field {
+				if field.GetName() != "name" {
+					continue
+				}
+				rules := proto.GetExtension(field.GetOptions(), validate.E_Field).(*validate.FieldRules)
+				rules.GetString().MinLen = proto.Uint64(minLen)
+			}
+		}
+	}
+
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+// newCanaryManager loads 1.0.0 and makes ISR publish 1.0.1, which requires names of at least 5 characters
+func newCanaryManager(t *testing.T, canary CanaryConfig, cacheDir string) (*SchemaManager, *validator.SchemaAwareValidator, *fakeClock) {
+	t.Helper()
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	clock := newFakeClock()
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+		Canary:          canary,
+		Clock:           clock,
+		CacheDir:        cacheDir,
+	}, schemaValidator)
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	mock.mu.Lock()
+	mock.version = "1.0.1"
+	mock.descriptorData = createDescriptorBytesWithNameMinLen(t, 5)
+	mock.mu.Unlock()
+	return manager, schemaValidator, clock
+}
+
+func validateNames(t *testing.T, v *validator.SchemaAwareValidator, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		v.Validate(&userv1.CreateUserRequest{
+			Name:  name,
+			Email: "user@example.com",
+			Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+		})
+	}
+}
+
+// waitForCanaryEnd waits for the canary goroutine to promote or block the version
+func waitForCanaryEnd(t *testing.T, manager *SchemaManager) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := manager.Status(); status.Canary == nil {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the canary to end")
+	return Status{}
+}
+
+func TestSchemaManager_Canary_Promoted(t *testing.T) {
+	manager, schemaValidator, clock := newCanaryManager(t, CanaryConfig{
+		Duration:              time.Hour,
+		MaxRejectionRateDelta: 0.5,
+	}, "")
+
+	ctx := context.Background()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	w := clock.waitFor(t, time.Hour)
+
+	// The current schema keeps deciding during the canary phase
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("expected version 1.0.0 during the canary, got %s", version)
+	}
+	// Polling the same version again does not restart the canary
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+
+	validateNames(t, schemaValidator, "Alice", "Charlie", "Bob", "")
+	status := manager.Status()
+	if status.Canary == nil {
+		t.Fatal("status has no canary")
+	}
+	if status.Canary.Version != "1.0.1" || status.Canary.Samples != 4 || status.Canary.Disagreements != 1 ||
+		status.Canary.RejectionRateDelta != 0.25 || !status.Canary.StartedAt.Equal(clock.Now()) {
+		t.Errorf("canary status = %+v", status.Canary)
+	}
+	// Disagreements may quote request data, so the public status leaves them out
+	rec := httptest.NewRecorder()
+	manager.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/status", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"disagreements":1`) || strings.Contains(body, "examples") {
+		t.Errorf("status JSON = %s, want the count without examples", body)
+	}
+
+	clock.fire(w)
+	status = waitForCanaryEnd(t, manager)
+	if status.Version != "1.0.1" || status.Source != SourceISR || len(status.BlockedVersions) != 0 {
+		t.Errorf("status after the canary = %+v, want 1.0.1 loaded", status)
+	}
+}
+
+func TestSchemaManager_Canary_Blocked(t *testing.T) {
+	cacheDir := t.TempDir()
+	canary := CanaryConfig{
+		Duration:              time.Hour,
+		Samples:               4,
+		MaxRejectionRateDelta: 0.1,
+	}
+	manager, schemaValidator, clock := newCanaryManager(t, canary, cacheDir)
+
+	ctx := context.Background()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	clock.waitFor(t, time.Hour)
+
+	// The sample limit ends the canary before its duration
+	validateNames(t, schemaValidator, "Alice", "Bob", "Eve", "Charlie")
+	status := waitForCanaryEnd(t, manager)
+	if status.Version != "1.0.0" {
+		t.Errorf("expected version 1.0.0 to stay loaded, got %s", status.Version)
+	}
+	if len(status.BlockedVersions) != 1 || status.BlockedVersions[0].Version != "1.0.1" ||
+		!strings.Contains(status.BlockedVersions[0].Reason, "+50.00%") {
+		t.Errorf("blocked versions = %+v, want 1.0.1", status.BlockedVersions)
+	}
+
+	// A blocked version is neither swapped in nor shadowed again
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if status := manager.Status(); status.Canary != nil || status.Version != "1.0.0" {
+		t.Errorf("status = %+v, want 1.0.0 without canary", status)
+	}
+
+	// The block survives a restart: the cached 1.0.0 is loaded instead of 1.0.1 from ISR
+	restarted := NewSchemaManager(manager.config, &validator.SchemaAwareValidator{})
+	if err := restarted.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if status := restarted.Status(); status.Version != "1.0.0" || status.Source != SourceCache {
+		t.Errorf("status after restart = %+v, want 1.0.0 from the cache", status)
+	}
+}
+
+func TestSchemaManager_Canary_Disabled(t *testing.T) {
+	manager, schemaValidator, _ := newCanaryManager(t, CanaryConfig{}, "")
+
+	if err := manager.checkAndUpdateSchema(context.Background()); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.1" {
+		t.Errorf("expected an immediate hot-swap to 1.0.1, got %s", version)
+	}
+}
@@ -0,0 +1,39 @@
+package schemamanager
+
+import (
+	"connectrpc.com/connect"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdCompression is the name ISR registers zstd under. It is preferred over gzip, which
+// connect clients accept by default, because descriptor sets shrink better at a lower CPU cost.
+const zstdCompression = "zstd"
+
+// maxDecoderMemory bounds the window a response may ask for; ISR serves binaries up to 10MB
+const maxDecoderMemory = 64 << 20
+
+// withZstd lets the ISR client accept zstd-compressed responses.
+// Requests are small and sent uncompressed, so older ISRs without zstd keep working.
+func withZstd() connect.ClientOption {
+	return connect.WithAcceptCompression(zstdCompression, newZstdDecompressor, newZstdCompressor)
+}
+
+// zstdDecompressor adapts zstd.Decoder, whose Close returns nothing, to connect.Decompressor
+type zstdDecompressor struct {
+	*zstd.Decoder
+}
+
+// Close is a no-op: zstd.Decoder.Close stops the decoder for good, while connect resets and reuses it
+func (d *zstdDecompressor) Close() error {
+	return nil
+}
+
+func newZstdDecompressor() connect.Decompressor {
+	d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecoderMemory))
+	return &zstdDecompressor{Decoder: d}
+}
+
+func newZstdCompressor() connect.Compressor {
+	e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	return e
+}
@@ -0,0 +1,51 @@
+package schemamanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func TestSchemaManager_ZstdCompression(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+
+	// An ISR that supports zstd, recording the encoding of each response
+	var encodings []string
+	path, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock,
+		connect.WithCompression(zstdCompression, newZstdDecompressor, newZstdCompressor))
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		encodings = append(encodings, w.Header().Get("Content-Encoding"))
+	}))
+	defer server.Close()
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+	}, schemaValidator)
+
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("expected version 1.0.0, got %s", version)
+	}
+	if len(encodings) != 1 || encodings[0] != zstdCompression {
+		t.Errorf("response encodings = %v, want [%s]", encodings, zstdCompression)
+	}
+}
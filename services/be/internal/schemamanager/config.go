@@ -1,13 +1,22 @@
 package schemamanager
 
-import "time"
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config holds the configuration for the schema manager
 type Config struct {
 	// ISRURL is the URL of the ISR service (e.g., "http://localhost:50051")
 	ISRURL string
 
-	// SchemaTarget is the target schema version in "Major.Minor" format (e.g., "1.0")
+	// SchemaTarget is the target schema version in "Major.Minor" format (e.g., "1.0"),
+	// a version range (e.g., "^1", "~1.2", ">=1.2.3 <2") or a tag (e.g., "tag:prod").
+	// A Group follows several targets, one Config each.
 	SchemaTarget string
 
 	// Major is the major version number
@@ -16,6 +25,118 @@ type Config struct {
 	// Minor is the minor version number
 	Minor int32
 
+	// Constraint is the version range resolved with ResolveVersion instead of
+	// following Major.Minor. Empty when SchemaTarget is "Major.Minor".
+	Constraint string
+
+	// Tag is the ISR tag (e.g., "staging", "prod") to follow instead of Major.Minor.
+	// Promoting another version to the tag in ISR switches the schema.
+	Tag string
+
 	// PollingInterval is the interval between schema update checks
 	PollingInterval time.Duration
+
+	// MaxStaleness fails the readiness check once ISR has not been reached for this long.
+	// Zero disables the staleness check; readiness then only requires a loaded schema.
+	MaxStaleness time.Duration
+
+	// Canary runs a new schema alongside the current one before swapping.
+	// The zero value hot-swaps immediately.
+	Canary CanaryConfig
+
+	// Retry controls backoff, jitter and circuit breaking while ISR is failing
+	Retry RetryConfig
+
+	// Clock is used for every wait of the update loop. Nil uses the system clock.
+	Clock Clock
+
+	// CacheDir is the directory where the last schema fetched from ISR is cached.
+	// The cache is used as a fallback when ISR is unreachable at startup.
+	// An empty value disables the cache.
+	CacheDir string
+
+	// ISRToken is sent as a bearer token when ISR requires authentication.
+	// An empty value sends no credentials.
+	ISRToken string
+
+	// ISRTLS is the client TLS configuration for an https ISR URL, e.g. with a client certificate
+	// for an ISR that authenticates mTLS identities. Nil uses the system roots without a certificate.
+	ISRTLS *tls.Config
+
+	// TrustedKeys are the ed25519 public keys schemas must be signed with before they are loaded.
+	// Empty accepts unsigned schemas. The embedded descriptor set is trusted as part of the binary.
+	TrustedKeys []ed25519.PublicKey
+}
+
+// NewConfig creates a Config from an ISR URL, a schema target and a polling interval.
+// The schema target is "Major.Minor", a version range or "tag:<name>"; ranges and tags are validated by ISR.
+// An ISR URL without a scheme (e.g., "isr:50051") is treated as plain HTTP.
+func NewConfig(isrURL, schemaTarget string, pollingInterval time.Duration) (Config, error) {
+	if isrURL == "" {
+		return Config{}, fmt.Errorf("ISR URL is required")
+	}
+	if !strings.Contains(isrURL, "://") {
+		isrURL = "http://" + isrURL
+	}
+
+	if pollingInterval <= 0 {
+		return Config{}, fmt.Errorf("polling interval must be positive, got %s", pollingInterval)
+	}
+
+	return Config{
+		ISRURL:          isrURL,
+		PollingInterval: pollingInterval,
+		Canary:          DefaultCanaryConfig(),
+		Retry:           DefaultRetryConfig(pollingInterval),
+	}.ForTarget(schemaTarget)
+}
+
+// ForTarget returns a copy of the config that follows another schema target, with every other setting kept.
+// It is used to build the configs of a Group.
+func (c Config) ForTarget(schemaTarget string) (Config, error) {
+	c.Major, c.Minor, c.Constraint, c.Tag = 0, 0, "", ""
+	if name, ok := strings.CutPrefix(schemaTarget, TagPrefix); ok {
+		if name == "" {
+			return Config{}, fmt.Errorf("invalid schema target %q: empty tag", schemaTarget)
+		}
+		c.Tag = name
+	} else if IsVersionRange(schemaTarget) {
+		c.Constraint = schemaTarget
+	} else {
+		var err error
+		c.Major, c.Minor, err = ParseSchemaTarget(schemaTarget)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	c.SchemaTarget = schemaTarget
+	return c, nil
+}
+
+// TagPrefix marks a schema target that follows an ISR tag (e.g., "tag:prod")
+const TagPrefix = "tag:"
+
+// IsVersionRange reports whether a schema target is a version range rather than "Major.Minor"
+func IsVersionRange(target string) bool {
+	return strings.ContainsAny(target, "^~<>=*xX|, ")
+}
+
+// ParseSchemaTarget parses a schema target in "Major.Minor" format (e.g., "1.0")
+func ParseSchemaTarget(target string) (major, minor int32, err error) {
+	parts := strings.Split(target, ".")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid schema target %q: expected Major.Minor", target)
+	}
+
+	majorInt, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil || majorInt < 0 {
+		return 0, 0, fmt.Errorf("invalid major version in schema target %q", target)
+	}
+
+	minorInt, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil || minorInt < 0 {
+		return 0, 0, fmt.Errorf("invalid minor version in schema target %q", target)
+	}
+
+	return int32(majorInt), int32(minorInt), nil
 }
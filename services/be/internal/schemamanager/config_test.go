@@ -0,0 +1,178 @@
+package schemamanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchemaTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		wantMajor int32
+		wantMinor int32
+		wantErr   bool
+	}{
+		{
+			name:      "valid target",
+			target:    "1.0",
+			wantMajor: 1,
+			wantMinor: 0,
+		},
+		{
+			name:      "multi-digit target",
+			target:    "12.34",
+			wantMajor: 12,
+			wantMinor: 34,
+		},
+		{
+			name:    "full version",
+			target:  "1.0.0",
+			wantErr: true,
+		},
+		{
+			name:    "major only",
+			target:  "1",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric minor",
+			target:  "1.x",
+			wantErr: true,
+		},
+		{
+			name:    "negative major",
+			target:  "-1.0",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			target:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			major, minor, err := ParseSchemaTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSchemaTarget() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (major != tt.wantMajor || minor != tt.wantMinor) {
+				t.Errorf("ParseSchemaTarget() = %d.%d, want %d.%d", major, minor, tt.wantMajor, tt.wantMinor)
+			}
+		})
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		isrURL         string
+		target         string
+		interval       time.Duration
+		wantISRURL     string
+		wantConstraint string
+		wantTag        string
+		wantErr        bool
+	}{
+		{
+			name:       "URL with scheme",
+			isrURL:     "http://localhost:50051",
+			target:     "1.0",
+			interval:   time.Minute,
+			wantISRURL: "http://localhost:50051",
+		},
+		{
+			name:       "URL without scheme",
+			isrURL:     "isr:50051",
+			target:     "1.0",
+			interval:   time.Minute,
+			wantISRURL: "http://isr:50051",
+		},
+		{
+			name:           "caret range",
+			isrURL:         "http://localhost:50051",
+			target:         "^1",
+			interval:       time.Minute,
+			wantISRURL:     "http://localhost:50051",
+			wantConstraint: "^1",
+		},
+		{
+			name:           "comparator range",
+			isrURL:         "http://localhost:50051",
+			target:         ">=1.2.3 <2",
+			interval:       time.Minute,
+			wantISRURL:     "http://localhost:50051",
+			wantConstraint: ">=1.2.3 <2",
+		},
+		{
+			name:       "tag",
+			isrURL:     "http://localhost:50051",
+			target:     "tag:prod",
+			interval:   time.Minute,
+			wantISRURL: "http://localhost:50051",
+			wantTag:    "prod",
+		},
+		{
+			name:     "empty tag",
+			isrURL:   "http://localhost:50051",
+			target:   "tag:",
+			interval: time.Minute,
+			wantErr:  true,
+		},
+		{
+			name:     "empty URL",
+			isrURL:   "",
+			target:   "1.0",
+			interval: time.Minute,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid target",
+			isrURL:   "http://localhost:50051",
+			target:   "latest",
+			interval: time.Minute,
+			wantErr:  true,
+		},
+		{
+			name:     "zero interval",
+			isrURL:   "http://localhost:50051",
+			target:   "1.0",
+			interval: 0,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(tt.isrURL, tt.target, tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.ISRURL != tt.wantISRURL {
+				t.Errorf("ISRURL = %v, want %v", config.ISRURL, tt.wantISRURL)
+			}
+			if config.SchemaTarget != tt.target {
+				t.Errorf("SchemaTarget = %v, want %v", config.SchemaTarget, tt.target)
+			}
+			if config.Constraint != tt.wantConstraint {
+				t.Errorf("Constraint = %q, want %q", config.Constraint, tt.wantConstraint)
+			}
+			if config.Tag != tt.wantTag {
+				t.Errorf("Tag = %q, want %q", config.Tag, tt.wantTag)
+			}
+			if config.PollingInterval != tt.interval {
+				t.Errorf("PollingInterval = %v, want %v", config.PollingInterval, tt.interval)
+			}
+			if config.Canary != DefaultCanaryConfig() || config.Canary.enabled() {
+				t.Errorf("Canary = %+v, want the disabled default", config.Canary)
+			}
+		})
+	}
+}
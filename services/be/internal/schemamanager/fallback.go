@@ -0,0 +1,123 @@
+package schemamanager
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// embeddedDescriptor is the descriptor set compiled from the proto/ tree at build time.
+// Regenerate it with `make schema-embed`.
+//
+//go:embed embedded/descriptor.bin
+var embeddedDescriptor []byte
+
+// EmbeddedVersion is the version reported while the embedded descriptor set is loaded
+const EmbeddedVersion = "embedded"
+
+// SchemaSource identifies where the currently loaded schema came from
+type SchemaSource string
+
+const (
+	// SourceNone means no schema has been loaded yet
+	SourceNone SchemaSource = ""
+	// SourceISR means the schema was fetched from ISR
+	SourceISR SchemaSource = "isr"
+	// SourceCache means the schema was read from the on-disk cache of the last successful fetch
+	SourceCache SchemaSource = "cache"
+	// SourceEmbedded means the descriptor set embedded in the binary is in use
+	SourceEmbedded SchemaSource = "embedded"
+)
+
+// cachePath returns the path of the cached schema for the configured target, or "" if caching is disabled
+func (m *SchemaManager) cachePath() string {
+	if m.config.CacheDir == "" {
+		return ""
+	}
+	if m.config.Constraint != "" {
+		// Ranges contain characters that are not safe in file names
+		sum := sha256.Sum256([]byte(m.config.Constraint))
+		return filepath.Join(m.config.CacheDir, fmt.Sprintf("schema-range-%s.binpb", hex.EncodeToString(sum[:8])))
+	}
+	if m.config.Tag != "" {
+		sum := sha256.Sum256([]byte(m.config.Tag))
+		return filepath.Join(m.config.CacheDir, fmt.Sprintf("schema-tag-%s.binpb", hex.EncodeToString(sum[:8])))
+	}
+	return filepath.Join(m.config.CacheDir, fmt.Sprintf("schema-%d.%d.binpb", m.config.Major, m.config.Minor))
+}
+
+// saveCache writes a schema fetched from ISR to the on-disk cache
+func (m *SchemaManager) saveCache(resp *isrv1.GetLatestPatchResponse) error {
+	path := m.cachePath()
+	if path == "" {
+		return nil
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached schema: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file and rename so readers never see a partial file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename cache file: %w", err)
+	}
+	return nil
+}
+
+// loadFromCache loads the schema from the on-disk cache of the last successful fetch
+func (m *SchemaManager) loadFromCache() (string, error) {
+	path := m.cachePath()
+	if path == "" {
+		return "", fmt.Errorf("schema cache is disabled")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	cached := &isrv1.GetLatestPatchResponse{}
+	if err := proto.Unmarshal(data, cached); err != nil {
+		return "", fmt.Errorf("failed to unmarshal cache file: %w", err)
+	}
+	if cached.Metadata == nil {
+		return "", fmt.Errorf("cache file %s is missing metadata", path)
+	}
+
+	if err := verifyContentHash(cached); err != nil {
+		return "", err
+	}
+	// The cache may predate the trusted keys or have been tampered with on disk
+	if err := m.verifySignature(cached); err != nil {
+		return "", err
+	}
+
+	version := cached.Metadata.Version
+	if err := m.validator.UpdateSchema(cached.SchemaBinary, version); err != nil {
+		return "", fmt.Errorf("failed to load cached schema: %w", err)
+	}
+	return version, nil
+}
+
+// loadFromEmbedded loads the descriptor set embedded in the binary
+func (m *SchemaManager) loadFromEmbedded() (string, error) {
+	if err := m.validator.UpdateSchema(embeddedDescriptor, EmbeddedVersion); err != nil {
+		return "", fmt.Errorf("failed to load embedded schema: %w", err)
+	}
+	return EmbeddedVersion, nil
+}
@@ -0,0 +1,147 @@
+package schemamanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/common/v1"
+	userv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func TestSchemaManager_LoadInitialSchema_FallsBackToCache(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	// A successful fetch from ISR populates the cache
+	server, _ := setupMockISRServer(t, "1.0.3", false)
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+		CacheDir:        cacheDir,
+	}
+
+	manager := NewSchemaManager(config, &validator.SchemaAwareValidator{})
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if source := manager.GetSource(); source != SourceISR {
+		t.Errorf("expected source %s, got %s", SourceISR, source)
+	}
+
+	// A fresh manager that cannot reach ISR loads the cached schema
+	failingServer, _ := setupMockISRServer(t, "1.0.3", true)
+	config.ISRURL = failingServer.URL
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager = NewSchemaManager(config, schemaValidator)
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	if source := manager.GetSource(); source != SourceCache {
+		t.Errorf("expected source %s, got %s", SourceCache, source)
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.3" {
+		t.Errorf("expected version 1.0.3, got %s", version)
+	}
+}
+
+func TestSchemaManager_LoadInitialSchema_CacheForOtherTargetIgnored(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	server, _ := setupMockISRServer(t, "1.0.3", false)
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+		CacheDir:        cacheDir,
+	}
+	if err := NewSchemaManager(config, &validator.SchemaAwareValidator{}).LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	failingServer, _ := setupMockISRServer(t, "1.1.0", true)
+	config.ISRURL = failingServer.URL
+	config.SchemaTarget = "1.1"
+	config.Minor = 1
+
+	manager := NewSchemaManager(config, &validator.SchemaAwareValidator{})
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if source := manager.GetSource(); source != SourceEmbedded {
+		t.Errorf("expected source %s, got %s", SourceEmbedded, source)
+	}
+}
+
+func TestSchemaManager_EmbeddedSchemaValidates(t *testing.T) {
+	failingServer, _ := setupMockISRServer(t, "1.0.0", true)
+	config := Config{
+		ISRURL:          failingServer.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	if err := NewSchemaManager(config, schemaValidator).LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	valid := &userv1.CreateUserRequest{
+		Name:  "John Doe",
+		Email: "john@example.com",
+		Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+	}
+	if err := schemaValidator.Validate(valid); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+
+	invalid := &userv1.CreateUserRequest{
+		Name:  "",
+		Email: "john@example.com",
+		Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+	}
+	if err := schemaValidator.Validate(invalid); err == nil {
+		t.Error("Validate() error = nil, want validation error")
+	}
+}
+
+func TestSchemaManager_CheckAndUpdateSchema_ReplacesFallback(t *testing.T) {
+	failingServer, _ := setupMockISRServer(t, "1.0.0", true)
+	config := Config{
+		ISRURL:          failingServer.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	if err := NewSchemaManager(config, schemaValidator).LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// ISR becomes reachable again
+	server, _ := setupMockISRServer(t, "1.0.2", false)
+	config.ISRURL = server.URL
+	manager := NewSchemaManager(config, schemaValidator)
+	manager.setSource(SourceEmbedded)
+
+	if err := manager.checkAndUpdateSchema(context.Background()); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.2" {
+		t.Errorf("expected version 1.0.2, got %s", version)
+	}
+	if source := manager.GetSource(); source != SourceISR {
+		t.Errorf("expected source %s, got %s", SourceISR, source)
+	}
+}
@@ -0,0 +1,140 @@
+package schemamanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// TargetSeparator separates the schema targets of a Group in CELO_SCHEMA_TARGET (e.g. "1.0;2.0").
+// Version ranges may contain commas and spaces, so neither can be used.
+const TargetSeparator = ";"
+
+// ParseSchemaTargets splits a list of schema targets separated by TargetSeparator
+func ParseSchemaTargets(s string) []string {
+	var targets []string
+	for _, target := range strings.Split(s, TargetSeparator) {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Group follows several schema targets at once, e.g. 1.x and 2.x while clients migrate.
+// Each target has its own SchemaManager and SchemaAwareValidator; the first target is the default.
+type Group struct {
+	managers []*SchemaManager
+}
+
+// NewGroup creates a SchemaManager and a validator for each config. Targets must be distinct.
+func NewGroup(configs ...Config) (*Group, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("at least one schema target is required")
+	}
+
+	g := &Group{}
+	seen := make(map[string]bool)
+	for _, config := range configs {
+		if seen[config.SchemaTarget] {
+			return nil, fmt.Errorf("duplicate schema target %q", config.SchemaTarget)
+		}
+		seen[config.SchemaTarget] = true
+		g.managers = append(g.managers, NewSchemaManager(config, &validator.SchemaAwareValidator{}))
+	}
+	return g, nil
+}
+
+// Managers returns the manager of each target in configuration order
+func (g *Group) Managers() []*SchemaManager {
+	return g.managers
+}
+
+// Manager returns the manager of a schema target; "" selects the default target
+func (g *Group) Manager(target string) (*SchemaManager, bool) {
+	if target == "" {
+		return g.managers[0], true
+	}
+	for _, m := range g.managers {
+		if m.config.SchemaTarget == target {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Routes returns the validator of each target for validator.NewRoutingInterceptor
+func (g *Group) Routes() []validator.Route {
+	routes := make([]validator.Route, 0, len(g.managers))
+	for _, m := range g.managers {
+		routes = append(routes, validator.Route{Validator: m.validator, Target: m.config.SchemaTarget})
+	}
+	return routes
+}
+
+// LoadInitialSchema loads the initial schema of every target (see SchemaManager.LoadInitialSchema)
+func (g *Group) LoadInitialSchema(ctx context.Context) error {
+	for _, m := range g.managers {
+		if err := m.LoadInitialSchema(ctx); err != nil {
+			return fmt.Errorf("target %s: %w", m.config.SchemaTarget, err)
+		}
+	}
+	return nil
+}
+
+// Start starts the update goroutine of every target
+func (g *Group) Start(ctx context.Context) {
+	for _, m := range g.managers {
+		m.Start(ctx)
+	}
+}
+
+// Stop stops every target. It is idempotent like SchemaManager.Stop.
+func (g *Group) Stop() {
+	for _, m := range g.managers {
+		m.Stop()
+	}
+}
+
+// Ready returns an error unless every target is ready
+func (g *Group) Ready() error {
+	for _, m := range g.managers {
+		if err := m.Ready(); err != nil {
+			return fmt.Errorf("target %s: %w", m.config.SchemaTarget, err)
+		}
+	}
+	return nil
+}
+
+// StatusHandler returns an HTTP handler that reports the status of the target given by the "target"
+// query parameter as JSON, or of the default target without it
+func (g *Group) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, ok := g.Manager(r.URL.Query().Get("target"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown schema target %q", r.URL.Query().Get("target")), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// ReadinessHandler returns an HTTP handler for readiness probes:
+// 200 when every target is ready, 503 with the reason otherwise
+func (g *Group) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := g.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+}
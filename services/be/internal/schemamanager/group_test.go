@@ -0,0 +1,89 @@
+package schemamanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchemaTargets(t *testing.T) {
+	got := ParseSchemaTargets(" 1.0; >=2.1, <3 ;;tag:prod")
+	if want := []string{"1.0", ">=2.1, <3", "tag:prod"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ParseSchemaTargets() = %q, want %q", got, want)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	v1 := serveMockISR(t, &mockISRServer{version: "1.0.4", descriptorData: createTestDescriptorBytes(t)})
+	v2 := serveMockISR(t, &mockISRServer{version: "2.0.1", descriptorData: createTestDescriptorBytes(t)})
+
+	config := func(isrURL, target string) Config {
+		config, err := NewConfig(isrURL, target, time.Minute)
+		if err != nil {
+			t.Fatalf("NewConfig failed: %v", err)
+		}
+		return config
+	}
+	if _, err := NewGroup(config(v1.URL, "1.0"), config(v1.URL, "1.0")); err == nil {
+		t.Error("NewGroup() with a duplicate target error = nil, want error")
+	}
+
+	group, err := NewGroup(config(v1.URL, "1.0"), config(v2.URL, "2.0"))
+	if err != nil {
+		t.Fatalf("NewGroup failed: %v", err)
+	}
+	if err := group.Ready(); err == nil {
+		t.Error("Ready() before loading error = nil, want error")
+	}
+	if err := group.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if err := group.Ready(); err != nil {
+		t.Errorf("Ready() error = %v", err)
+	}
+
+	// Each target has its own validator
+	routes := group.Routes()
+	if len(routes) != 2 || routes[0].Validator.GetCurrentVersion() != "1.0.4" || routes[1].Validator.GetCurrentVersion() != "2.0.1" {
+		t.Errorf("routes = %+v, want 1.0.4 and 2.0.1", routes)
+	}
+	if m, ok := group.Manager(""); !ok || m.CurrentVersion() != "1.0.4" {
+		t.Error("Manager(\"\") is not the first target")
+	}
+	if m, ok := group.Manager("2.0"); !ok || m.CurrentVersion() != "2.0.1" {
+		t.Error("Manager(\"2.0\") is not the second target")
+	}
+
+	tests := []struct {
+		query       string
+		wantCode    int
+		wantVersion string
+	}{
+		{"", http.StatusOK, "1.0.4"},
+		{"?target=2.0", http.StatusOK, "2.0.1"},
+		{"?target=3.0", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		group.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/status"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("status %q code = %d, want %d", tt.query, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var status Status
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode status: %v", err)
+		}
+		if status.Version != tt.wantVersion || !strings.HasPrefix(tt.wantVersion, status.Target) {
+			t.Errorf("status %q = %s (target %s), want %s", tt.query, status.Version, status.Target, tt.wantVersion)
+		}
+	}
+}
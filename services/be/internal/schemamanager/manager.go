@@ -2,8 +2,12 @@ package schemamanager
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"net/http"
 	"sync"
 	"time"
@@ -19,55 +23,185 @@ type SchemaManager struct {
 	config    Config
 	validator *validator.SchemaAwareValidator
 	client    isrv1connect.SchemaRegistryServiceClient
+	clock     Clock
+	retry     *retryState
 	stopCh    chan struct{}
 	doneCh    chan struct{}
 	stopOnce  sync.Once
+
+	startedAt time.Time
+
+	mu     sync.RWMutex
+	source SchemaSource
+	health health
+
+	// canaryMu guards the canary phase of new schemas and the versions it blocked.
+	// It is acquired before mu when both are needed.
+	canaryMu sync.Mutex
+	canary   *canaryRun
+	blocked  map[string]BlockedVersion
+	canaryWG sync.WaitGroup
 }
 
 // NewSchemaManager creates a new schema manager
 func NewSchemaManager(config Config, validator *validator.SchemaAwareValidator) *SchemaManager {
+	options := []connect.ClientOption{withZstd()}
+	if config.ISRToken != "" {
+		options = append(options, withBearerToken(config.ISRToken))
+	}
+	httpClient := http.DefaultClient
+	if config.ISRTLS != nil {
+		httpClient = &http.Client{Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   config.ISRTLS,
+			ForceAttemptHTTP2: true,
+		}}
+	}
 	client := isrv1connect.NewSchemaRegistryServiceClient(
-		http.DefaultClient,
+		httpClient,
 		config.ISRURL,
+		options...,
 	)
 
+	clock := config.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
 	return &SchemaManager{
 		config:    config,
 		validator: validator,
 		client:    client,
-		stopCh:    make(chan struct{}),
-		doneCh:    make(chan struct{}),
+		clock:     clock,
+		startedAt: clock.Now(),
+		retry: &retryState{
+			config:   config.Retry,
+			interval: config.PollingInterval,
+			random:   rand.Float64,
+		},
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
 	}
 }
 
-// LoadInitialSchema loads the initial schema from ISR
+// withBearerToken authenticates every request to ISR, the WatchSchema stream included
+func withBearerToken(token string) connect.ClientOption {
+	return connect.WithInterceptors(&bearerTokenInterceptor{header: "Bearer " + token})
+}
+
+type bearerTokenInterceptor struct {
+	header string
+}
+
+func (i *bearerTokenInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		req.Header().Set("Authorization", i.header)
+		return next(ctx, req)
+	}
+}
+
+func (i *bearerTokenInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("Authorization", i.header)
+		return conn
+	}
+}
+
+func (i *bearerTokenInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// LoadInitialSchema loads the initial schema.
+// Sources are tried in order: ISR latest patch, the on-disk cache of the last
+// successful fetch, and finally the descriptor set embedded in the binary.
 func (m *SchemaManager) LoadInitialSchema(ctx context.Context) error {
-	req := connect.NewRequest(&isrv1.GetLatestPatchRequest{
-		Major: m.config.Major,
-		Minor: m.config.Minor,
-	})
+	if err := m.loadBlocked(); err != nil {
+		log.Printf("Failed to load blocked schema versions: %v", err)
+	}
+
+	isrErr := m.loadFromISR(ctx)
+	m.recordHealth(isrErr, false)
+	if isrErr == nil {
+		return nil
+	}
+	log.Printf("Failed to load initial schema from ISR: %v", isrErr)
+
+	version, cacheErr := m.loadFromCache()
+	if cacheErr == nil {
+		m.setLoaded(SourceCache)
+		log.Printf("Schema initialized: target=%s, loaded version=%s, source=%s", m.config.SchemaTarget, version, SourceCache)
+		return nil
+	}
+	log.Printf("Failed to load initial schema from cache: %v", cacheErr)
+
+	version, embeddedErr := m.loadFromEmbedded()
+	if embeddedErr == nil {
+		m.setLoaded(SourceEmbedded)
+		log.Printf("Schema initialized: target=%s, loaded version=%s, source=%s", m.config.SchemaTarget, version, SourceEmbedded)
+		return nil
+	}
 
-	resp, err := m.client.GetLatestPatch(ctx, req)
+	return fmt.Errorf("failed to load initial schema from any source: isr: %v; cache: %v; embedded: %w",
+		isrErr, cacheErr, embeddedErr)
+}
+
+// loadFromISR loads the latest patch for the configured target from ISR
+func (m *SchemaManager) loadFromISR(ctx context.Context) error {
+	latest, err := m.fetchLatest(ctx, "")
 	if err != nil {
 		return fmt.Errorf("failed to get initial schema from ISR: %w", err)
 	}
 
-	if resp.Msg == nil || resp.Msg.Metadata == nil {
+	if latest == nil || latest.Metadata == nil {
 		return fmt.Errorf("invalid response from ISR: missing metadata")
 	}
 
-	version := resp.Msg.Metadata.Version
-	if err := m.validator.UpdateSchema(resp.Msg.SchemaBinary, version); err != nil {
-		return fmt.Errorf("failed to initialize validator with schema: %w", err)
+	version := latest.Metadata.Version
+	if blocked, ok := m.blockedVersion(version); ok {
+		return rejectSchema(fmt.Errorf("schema %s is blocked locally: %s", version, blocked.Reason))
+	}
+
+	if err := verifyContentHash(latest); err != nil {
+		return rejectSchema(err)
+	}
+	if err := m.verifySignature(latest); err != nil {
+		return rejectSchema(err)
+	}
+
+	if err := m.validator.UpdateSchema(latest.SchemaBinary, version); err != nil {
+		return rejectSchema(fmt.Errorf("failed to initialize validator with schema: %w", err))
+	}
+	m.setLoaded(SourceISR)
+
+	if err := m.saveCache(latest); err != nil {
+		log.Printf("Failed to cache schema %s: %v", version, err)
 	}
 
-	log.Printf("Schema initialized: target=%s, loaded version=%s", m.config.SchemaTarget, version)
+	log.Printf("Schema initialized: target=%s, loaded version=%s, source=%s", m.config.SchemaTarget, version, SourceISR)
 	return nil
 }
 
-// Start starts the schema polling goroutine
+// GetSource returns where the currently loaded schema came from
+func (m *SchemaManager) GetSource() SchemaSource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.source
+}
+
+func (m *SchemaManager) setSource(source SchemaSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.source = source
+}
+
+// Start starts the schema update goroutine after a random delay of up to Retry.StartJitter.
+// It follows ISR over the WatchSchema stream, still checking GetLatestPatch every PollingInterval,
+// and falls back to polling while the stream is unavailable,
+// backing off and eventually opening the circuit while polls fail (see RetryConfig).
+// Version range and tag targets are always polled (ResolveVersion / GetSchemaByTag).
 func (m *SchemaManager) Start(ctx context.Context) {
-	go m.pollLoop(ctx)
+	go m.updateLoop(ctx)
 }
 
 // Stop gracefully stops the schema manager
@@ -76,60 +210,378 @@ func (m *SchemaManager) Stop() {
 	m.stopOnce.Do(func() {
 		close(m.stopCh)
 		<-m.doneCh
+		m.canaryWG.Wait()
 		log.Println("Schema manager stopped")
 	})
 }
 
-// pollLoop runs the schema polling loop
-func (m *SchemaManager) pollLoop(ctx context.Context) {
+// updateLoop prefers the WatchSchema stream and polls while it is broken.
+// After each poll the stream is re-established, unless the circuit is open.
+func (m *SchemaManager) updateLoop(ctx context.Context) {
 	defer close(m.doneCh)
 
-	ticker := time.NewTicker(m.config.PollingInterval)
-	defer ticker.Stop()
+	// Cancel the watch stream as soon as Stop is called
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if !m.sleep(ctx, m.retry.startDelay()) {
+		return
+	}
 
+	polling := false
 	for {
-		select {
-		case <-ticker.C:
-			if err := m.checkAndUpdateSchema(ctx); err != nil {
-				log.Printf("Schema polling error (will retry in %s): %v",
+		// An open circuit sends nothing but the probe poll
+		if !m.retry.circuitOpen() {
+			received, err := m.watchSchema(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				m.retry.record(nil)
+			}
+			// Log only when switching from streaming to polling, not on every reconnect attempt
+			if received || !polling {
+				log.Printf("Schema watch unavailable, falling back to polling every %s: %v",
 					m.config.PollingInterval, err)
+				polling = true
 			}
-		case <-m.stopCh:
-			return
-		case <-ctx.Done():
+		}
+
+		if !m.sleep(ctx, m.retry.nextDelay()) {
 			return
 		}
+		err := m.checkAndUpdateSchema(ctx)
+		m.retry.record(isrFailure(err))
+		m.recordHealth(err, m.retry.circuitOpen())
 	}
 }
 
-// checkAndUpdateSchema checks for schema updates and performs hot-swap if needed
-func (m *SchemaManager) checkAndUpdateSchema(ctx context.Context) error {
-	currentVersion := m.validator.GetCurrentVersion()
+// sleep waits for d on the manager's clock. It returns false if ctx is done first.
+func (m *SchemaManager) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-m.clock.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-	req := connect.NewRequest(&isrv1.GetLatestPatchRequest{
+// watchSchema applies schemas pushed over the WatchSchema stream until the stream breaks.
+// It reports whether any message was received before the stream ended.
+// ISR is also polled every PollingInterval while watching: a stream served by one ISR replica
+// may miss changes committed by another, so a poll that finds a version the stream has not
+// delivered applies it and drops the stream, which is re-established after the next poll.
+func (m *SchemaManager) watchSchema(ctx context.Context) (bool, error) {
+	if m.config.Constraint != "" || m.config.Tag != "" {
+		return false, fmt.Errorf("WatchSchema follows a single Major.Minor, not %q", m.config.SchemaTarget)
+	}
+
+	req := connect.NewRequest(&isrv1.WatchSchemaRequest{
 		Major: m.config.Major,
 		Minor: m.config.Minor,
 	})
 
-	resp, err := m.client.GetLatestPatch(ctx, req)
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := m.client.WatchSchema(streamCtx, req)
+	if err != nil {
+		return false, fmt.Errorf("failed to open schema watch stream: %w", err)
+	}
+
+	// Receive on another goroutine so that polls and messages are handled here, one at a time
+	messages := make(chan *isrv1.GetLatestPatchResponse)
+	stopped := make(chan struct{})
+	var streamErr error
+	go func() {
+		defer close(stopped)
+		for stream.Receive() {
+			select {
+			case messages <- stream.Msg().Latest:
+			case <-streamCtx.Done():
+			}
+		}
+		streamErr = stream.Err()
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+		stream.Close()
+	}()
+
+	received := false
+	watched := "" // The last version delivered by the stream
+	poll := m.clock.After(m.config.PollingInterval)
+	for {
+		select {
+		case latest := <-messages:
+			if !received {
+				log.Printf("Watching ISR for schema updates: target=%s", m.config.SchemaTarget)
+				received = true
+				m.setWatching(true)
+				defer m.setWatching(false)
+			}
+			err := m.applySchema(latest)
+			if err != nil {
+				log.Printf("Schema watch error: %v", err)
+			}
+			m.recordHealth(err, false)
+			if latest != nil && latest.Metadata != nil {
+				watched = latest.Metadata.Version
+			}
+
+		case <-poll:
+			if err := m.checkWatch(ctx, watched); err != nil {
+				return received, err
+			}
+			poll = m.clock.After(m.config.PollingInterval)
+
+		case <-stopped:
+			if streamErr != nil {
+				return received, fmt.Errorf("schema watch stream failed: %w", streamErr)
+			}
+			return received, fmt.Errorf("schema watch stream closed by ISR")
+		}
+	}
+}
+
+// checkWatch polls ISR while the watch stream is up. It returns an error when ISR serves a version
+// other than the one the stream delivered last (or the current one, before the first message),
+// after applying it; a failed poll is only recorded, since the stream may still be healthy.
+func (m *SchemaManager) checkWatch(ctx context.Context, watched string) error {
+	known := watched
+	if known == "" {
+		known = m.validator.GetCurrentVersion()
+	}
+
+	latest, err := m.fetchLatest(ctx, known)
+	if ctx.Err() != nil {
+		return nil // Stopping
+	}
+	if connect.CodeOf(err) == connect.CodeNotFound {
+		err = m.checkYanked(ctx, err)
+		if err == nil {
+			m.recordHealth(nil, false)
+			return nil
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to get latest patch from ISR: %w", err)
+		log.Printf("Schema watch check error: %v", err)
+		m.recordHealth(err, false)
+		return nil
+	}
+	if latest.GetNotModified() || latest.GetMetadata().GetVersion() == known {
+		m.recordHealth(nil, false)
+		return nil
+	}
+
+	err = m.applySchema(latest)
+	m.recordHealth(err, false)
+	if err != nil {
+		log.Printf("Schema watch check error: %v", err)
+	}
+	if latest.GetMetadata() == nil {
+		return nil // An invalid response, not a missed version
+	}
+	return fmt.Errorf("schema watch stream missed version %s", latest.Metadata.Version)
+}
+
+// checkAndUpdateSchema checks for schema updates and performs hot-swap if needed
+func (m *SchemaManager) checkAndUpdateSchema(ctx context.Context) error {
+	latest, err := m.fetchLatest(ctx, m.validator.GetCurrentVersion())
+	if connect.CodeOf(err) == connect.CodeNotFound {
+		return m.checkYanked(ctx, err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get latest patch from ISR: %w", err)
 	}
 
-	if resp.Msg == nil || resp.Msg.Metadata == nil {
+	return m.applySchema(latest)
+}
+
+// checkYanked handles a NotFound from ISR for the target. Once every patch of a Major.Minor is
+// yanked, ISR has nothing to roll back to, so the yanked version stays loaded: that case is
+// recorded for the status and logged, and counts as ISR being reached. Otherwise notFound is returned.
+func (m *SchemaManager) checkYanked(ctx context.Context, notFound error) error {
+	current := m.validator.GetCurrentVersion()
+	resp, err := m.client.GetSchemaByVersion(ctx, connect.NewRequest(&isrv1.GetSchemaByVersionRequest{
+		Version: current,
+	}))
+	if err != nil || resp.Msg.Metadata.GetStatus() != isrv1.SchemaStatus_SCHEMA_STATUS_YANKED {
+		return fmt.Errorf("failed to get latest patch from ISR: %w", notFound)
+	}
+
+	if m.setYanked(current, resp.Msg.Metadata.StatusReason) {
+		log.Printf("Warning: schema %s is yanked (%s) and ISR serves no other version for target %s; "+
+			"keeping it loaded until a new version is published or it is rolled back",
+			current, resp.Msg.Metadata.StatusReason, m.config.SchemaTarget)
+	}
+	return nil
+}
+
+// fetchLatest asks ISR for the latest patch of Major.Minor, the highest version
+// matching Constraint, or the version Tag points to, depending on the target
+func (m *SchemaManager) fetchLatest(ctx context.Context, knownVersion string) (*isrv1.GetLatestPatchResponse, error) {
+	if m.config.Tag != "" {
+		resp, err := m.client.GetSchemaByTag(ctx, connect.NewRequest(&isrv1.GetSchemaByTagRequest{
+			Tag:          m.config.Tag,
+			KnownVersion: knownVersion,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg.Latest, nil
+	}
+	if m.config.Constraint != "" {
+		resp, err := m.client.ResolveVersion(ctx, connect.NewRequest(&isrv1.ResolveVersionRequest{
+			Constraint:   m.config.Constraint,
+			KnownVersion: knownVersion,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return resp.Msg.Latest, nil
+	}
+
+	resp, err := m.client.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{
+		Major:        m.config.Major,
+		Minor:        m.config.Minor,
+		KnownVersion: knownVersion,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg, nil
+}
+
+// applySchema hot-swaps the validator if latest differs from the current version.
+// With Canary enabled, a newer version only shadows the current one until the canary decides.
+func (m *SchemaManager) applySchema(latest *isrv1.GetLatestPatchResponse) error {
+	if latest == nil || latest.Metadata == nil {
 		return fmt.Errorf("invalid response from ISR: missing metadata")
 	}
 
-	latestVersion := resp.Msg.Metadata.Version
+	currentVersion := m.validator.GetCurrentVersion()
+	latestVersion := latest.Metadata.Version
 
-	if currentVersion == latestVersion {
+	if latest.NotModified || currentVersion == latestVersion {
+		// A schema loaded from the cache is now confirmed by ISR
+		m.setSource(SourceISR)
+		m.cancelCanary("ISR no longer serves it")
 		return nil // No update needed
 	}
+	if m.validator.PinnedVersion() != "" {
+		return nil // Updates resume after Unpin
+	}
+	if _, ok := m.blockedVersion(latestVersion); ok {
+		return nil // Logged when the version was blocked
+	}
 
-	if err := m.validator.UpdateSchema(resp.Msg.SchemaBinary, latestVersion); err != nil {
-		return fmt.Errorf("failed to update schema: %w", err)
+	if err := verifyContentHash(latest); err != nil {
+		return rejectSchema(err)
 	}
+	if err := m.verifySignature(latest); err != nil {
+		return rejectSchema(err)
+	}
+
+	rollback := isOlderPatch(latestVersion, currentVersion)
+	if m.config.Canary.enabled() && !rollback {
+		return rejectSchema(m.startCanary(latest))
+	}
+	// A rollback is never held back by a canary: the current version was yanked
+	m.cancelCanary("rolling back to " + latestVersion)
+
+	if err := m.validator.UpdateSchema(latest.SchemaBinary, latestVersion); err != nil {
+		return rejectSchema(fmt.Errorf("failed to update schema: %w", err))
+	}
+	m.setLoaded(SourceISR)
+
+	if err := m.saveCache(latest); err != nil {
+		log.Printf("Failed to cache schema %s: %v", latestVersion, err)
+	}
+
+	if rollback {
+		// The latest patch only moves backwards when the current one was yanked in ISR
+		log.Printf("Rolled back validator: %s -> %s (%s was yanked)", currentVersion, latestVersion, currentVersion)
+	} else {
+		log.Printf("Hot-swapped validator: %s -> %s", currentVersion, latestVersion)
+	}
+	warnSchemaStatus(latest.Metadata)
+	return nil
+}
 
-	log.Printf("Hot-swapped validator: %s -> %s", currentVersion, latestVersion)
+// schemaRejectedError is returned when ISR answered but the schema it served cannot be loaded
+// (blocked locally, content hash or signature mismatch, failed compile). ISR was reached, so it
+// counts as a success for health and never opens the circuit: polling faster would only fetch
+// the same schema again.
+type schemaRejectedError struct {
+	err error
+}
+
+func (e *schemaRejectedError) Error() string { return e.err.Error() }
+func (e *schemaRejectedError) Unwrap() error { return e.err }
+
+// rejectSchema wraps err, if any, as a schemaRejectedError
+func rejectSchema(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &schemaRejectedError{err: err}
+}
+
+// isrFailure returns err unless it is nil or only rejects the schema ISR served
+func isrFailure(err error) error {
+	var rejected *schemaRejectedError
+	if errors.As(err, &rejected) {
+		return nil
+	}
+	return err
+}
+
+// warnSchemaStatus logs a warning when a schema that was just loaded is deprecated or yanked
+func warnSchemaStatus(metadata *isrv1.SchemaMetadata) {
+	switch metadata.Status {
+	case isrv1.SchemaStatus_SCHEMA_STATUS_DEPRECATED:
+		log.Printf("Warning: schema %s is deprecated: %s", metadata.Version, metadata.StatusReason)
+	case isrv1.SchemaStatus_SCHEMA_STATUS_YANKED:
+		// Only a tag can still point to a yanked version
+		log.Printf("Warning: schema %s is yanked: %s", metadata.Version, metadata.StatusReason)
+	}
+}
+
+// isOlderPatch reports whether version a has lower precedence than b within the same Major.Minor,
+// pre-releases included (e.g. 1.2.3-rc.1 is older than 1.2.3)
+func isOlderPatch(a, b string) bool {
+	aVersion, okA := parseSchemaVersion(a)
+	bVersion, okB := parseSchemaVersion(b)
+	if !okA || !okB || aVersion.major != bVersion.major || aVersion.minor != bVersion.minor {
+		return false
+	}
+	return compareSchemaVersions(aVersion, bVersion) < 0
+}
+
+// verifyContentHash checks the schema binary against the SHA-256 in its metadata.
+// Schemas without a content hash (uploaded by older ISR versions) are accepted as-is.
+func verifyContentHash(schema *isrv1.GetLatestPatchResponse) error {
+	want := schema.Metadata.ContentHash
+	if want == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(schema.SchemaBinary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("content hash mismatch for schema %s: expected %s, got %s",
+			schema.Metadata.Version, want, got)
+	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,34 +19,115 @@ import (
 	_ "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
 )
 
+// createTestDescriptorBytes creates a test FileDescriptorSet from the user.v1 package
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := validator.DescriptorSetFromFiles("user/v1/user.proto")
+	if err != nil {
+		t.Fatalf("failed to create descriptor set: %v", err)
+	}
+	return data
+}
+
 // mockISRServer creates a mock ISR server for testing
 type mockISRServer struct {
 	isrv1connect.UnimplementedSchemaRegistryServiceHandler
 	version        string
 	descriptorData []byte
+	contentHash    string
+	signature      []byte
 	errorToReturn  error
+	yanked         map[string]string // Reasons of yanked versions, by version
+
+	mu                sync.Mutex
+	knownVersionsSeen []string
+	constraintsSeen   []string
 }
 
 func (m *mockISRServer) GetLatestPatch(
 	ctx context.Context,
 	req *connect.Request[isrv1.GetLatestPatchRequest],
 ) (*connect.Response[isrv1.GetLatestPatchResponse], error) {
-	if m.errorToReturn != nil {
-		return nil, m.errorToReturn
+	m.mu.Lock()
+	m.knownVersionsSeen = append(m.knownVersionsSeen, req.Msg.KnownVersion)
+	errorToReturn := m.errorToReturn
+	metadata := &isrv1.SchemaMetadata{
+		Version:     m.version,
+		ContentHash: m.contentHash,
+		Signature:   m.signature,
+	}
+	m.mu.Unlock()
+
+	if errorToReturn != nil {
+		return nil, errorToReturn
+	}
+
+	if req.Msg.KnownVersion == metadata.Version {
+		return connect.NewResponse(&isrv1.GetLatestPatchResponse{
+			Metadata:    metadata,
+			NotModified: true,
+		}), nil
 	}
 
 	return connect.NewResponse(&isrv1.GetLatestPatchResponse{
-		Metadata: &isrv1.SchemaMetadata{
-			Version: m.version,
-		},
+		Metadata:     metadata,
 		SchemaBinary: m.descriptorData,
 	}), nil
 }
 
+func (m *mockISRServer) GetSchemaByVersion(
+	ctx context.Context,
+	req *connect.Request[isrv1.GetSchemaByVersionRequest],
+) (*connect.Response[isrv1.GetSchemaByVersionResponse], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metadata := &isrv1.SchemaMetadata{Version: req.Msg.Version, Status: isrv1.SchemaStatus_SCHEMA_STATUS_ACTIVE}
+	if reason, ok := m.yanked[req.Msg.Version]; ok {
+		metadata.Status = isrv1.SchemaStatus_SCHEMA_STATUS_YANKED
+		metadata.StatusReason = reason
+	}
+	return connect.NewResponse(&isrv1.GetSchemaByVersionResponse{
+		Metadata:     metadata,
+		SchemaBinary: m.descriptorData,
+	}), nil
+}
+
+func (m *mockISRServer) ResolveVersion(
+	ctx context.Context,
+	req *connect.Request[isrv1.ResolveVersionRequest],
+) (*connect.Response[isrv1.ResolveVersionResponse], error) {
+	m.mu.Lock()
+	m.constraintsSeen = append(m.constraintsSeen, req.Msg.Constraint)
+	m.mu.Unlock()
+
+	latest, err := m.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{
+		KnownVersion: req.Msg.KnownVersion,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&isrv1.ResolveVersionResponse{Latest: latest.Msg}), nil
+}
+
+func (m *mockISRServer) GetSchemaByTag(
+	ctx context.Context,
+	req *connect.Request[isrv1.GetSchemaByTagRequest],
+) (*connect.Response[isrv1.GetSchemaByTagResponse], error) {
+	latest, err := m.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{
+		KnownVersion: req.Msg.KnownVersion,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&isrv1.GetSchemaByTagResponse{Latest: latest.Msg}), nil
+}
+
 func setupMockISRServer(t *testing.T, version string, shouldError bool) (*httptest.Server, []byte) {
 	t.Helper()
 
-	descriptorData := validator.CreateTestDescriptorBytes(t)
+	descriptorData := createTestDescriptorBytes(t)
 
 	mock := &mockISRServer{
 		version:        version,
@@ -56,6 +138,13 @@ func setupMockISRServer(t *testing.T, version string, shouldError bool) (*httpte
 		mock.errorToReturn = errors.New("ISR service error")
 	}
 
+	return serveMockISR(t, mock), descriptorData
+}
+
+// serveMockISR starts an HTTP server for the given mock ISR implementation
+func serveMockISR(t *testing.T, mock isrv1connect.SchemaRegistryServiceHandler) *httptest.Server {
+	t.Helper()
+
 	mux := http.NewServeMux()
 	path, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock)
 	mux.Handle(path, handler)
@@ -63,7 +152,7 @@ func setupMockISRServer(t *testing.T, version string, shouldError bool) (*httpte
 	server := httptest.NewServer(mux)
 	t.Cleanup(server.Close)
 
-	return server, descriptorData
+	return server
 }
 
 func TestSchemaManager_LoadInitialSchema_Success(t *testing.T) {
@@ -108,8 +197,15 @@ func TestSchemaManager_LoadInitialSchema_ISRError(t *testing.T) {
 
 	ctx := context.Background()
 	err := manager.LoadInitialSchema(ctx)
-	if err == nil {
-		t.Fatal("expected error from ISR, got nil")
+	if err != nil {
+		t.Fatalf("LoadInitialSchema should fall back to the embedded schema, got error: %v", err)
+	}
+
+	if source := manager.GetSource(); source != SourceEmbedded {
+		t.Errorf("expected source %s, got %s", SourceEmbedded, source)
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != EmbeddedVersion {
+		t.Errorf("expected version %s, got %s", EmbeddedVersion, version)
 	}
 }
 
@@ -195,6 +291,207 @@ func TestSchemaManager_CheckAndUpdateSchema_HotSwap(t *testing.T) {
 	}
 }
 
+func TestSchemaManager_CheckAndUpdateSchema_RollbackOnYank(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.1",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// 1.0.1 is yanked, so ISR reports 1.0.0 as the latest patch again
+	mock.version = "1.0.0"
+
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+
+	if got := schemaValidator.GetCurrentVersion(); got != "1.0.0" {
+		t.Errorf("expected rollback to 1.0.0, got %s", got)
+	}
+	if source := manager.GetSource(); source != SourceISR {
+		t.Errorf("expected source %s, got %s", SourceISR, source)
+	}
+}
+
+func TestSchemaManager_CheckAndUpdateSchema_AllPatchesYanked(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// Without a yanked version, NotFound is an error
+	mock.mu.Lock()
+	mock.errorToReturn = connect.NewError(connect.CodeNotFound, errors.New("no schema found for version 1.0"))
+	mock.mu.Unlock()
+	if err := manager.checkAndUpdateSchema(ctx); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("checkAndUpdateSchema() error = %v, want NotFound", err)
+	}
+
+	// 1.0.0 was the only patch and is yanked: ISR has nothing to roll back to
+	mock.mu.Lock()
+	mock.yanked = map[string]string{"1.0.0": "broke email validation"}
+	mock.mu.Unlock()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema() error = %v, want nil", err)
+	}
+	if got := schemaValidator.GetCurrentVersion(); got != "1.0.0" {
+		t.Errorf("version = %s, want 1.0.0 to stay loaded", got)
+	}
+	status := manager.Status()
+	if !status.Yanked || status.YankedReason != "broke email validation" {
+		t.Errorf("status = %+v, want the loaded version reported as yanked", status)
+	}
+
+	// A new patch replaces it
+	mock.mu.Lock()
+	mock.errorToReturn = nil
+	mock.version = "1.0.1"
+	mock.mu.Unlock()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema() error = %v", err)
+	}
+	if status := manager.Status(); status.Version != "1.0.1" || status.Yanked {
+		t.Errorf("status = %+v, want 1.0.1 and not yanked", status)
+	}
+}
+
+func TestIsOlderPatch(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "1.0.0", b: "1.0.1", want: true},
+		{a: "1.0.2", b: "1.0.10", want: true},
+		{a: "1.0.1", b: "1.0.0", want: false},
+		{a: "1.0.1", b: "1.0.1", want: false},
+		{a: "1.0.0", b: "1.1.1", want: false},
+		{a: "1.0.0", b: "", want: false},
+		{a: "1.0.x", b: "1.0.1", want: false},
+		{a: "1.0.1-rc.1", b: "1.0.1", want: true},
+		{a: "1.0.1", b: "1.0.1-rc.1", want: false},
+		{a: "1.0.1-rc.2", b: "1.0.1-rc.10", want: true},
+		{a: "1.0.1-alpha", b: "1.0.1-alpha.1", want: true},
+		{a: "1.0.1-rc.1", b: "1.0.1-beta", want: false},
+		{a: "1.0.0", b: "1.0.1-rc.1", want: true},
+		{a: "1.0.1+build.2", b: "1.0.1+build.1", want: false},
+		{a: "1.0.1-", b: "1.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isOlderPatch(tt.a, tt.b); got != tt.want {
+			t.Errorf("isOlderPatch(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSchemaManager_VersionRange(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.2.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	config, err := NewConfig(server.URL, "^1", time.Minute)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if got := schemaValidator.GetCurrentVersion(); got != "1.2.0" {
+		t.Errorf("expected version 1.2.0, got %s", got)
+	}
+
+	// A new minor within the range is followed as well
+	mock.version = "1.3.0"
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if got := schemaValidator.GetCurrentVersion(); got != "1.3.0" {
+		t.Errorf("expected version 1.3.0, got %s", got)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if len(mock.constraintsSeen) != 2 || mock.constraintsSeen[0] != "^1" {
+		t.Errorf("ResolveVersion constraints = %v, want [^1 ^1]", mock.constraintsSeen)
+	}
+	if got := mock.knownVersionsSeen; len(got) != 2 || got[1] != "1.2.0" {
+		t.Errorf("known versions = %v, want [\"\" 1.2.0]", got)
+	}
+}
+
+func TestSchemaManager_Tag(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.3",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	config, err := NewConfig(server.URL, "tag:prod", time.Minute)
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if got := schemaValidator.GetCurrentVersion(); got != "1.0.3" {
+		t.Errorf("expected version 1.0.3, got %s", got)
+	}
+
+	// Promoting another version to the tag switches the schema
+	mock.version = "1.1.0"
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if got := schemaValidator.GetCurrentVersion(); got != "1.1.0" {
+		t.Errorf("expected version 1.1.0, got %s", got)
+	}
+
+	if _, err := manager.watchSchema(ctx); err == nil {
+		t.Error("watchSchema() error = nil, want error for a tag target")
+	}
+}
+
 func TestSchemaManager_CheckAndUpdateSchema_InvalidSchema(t *testing.T) {
 	server, _ := setupMockISRServer(t, "1.0.0", false)
 
@@ -247,6 +544,83 @@ func TestSchemaManager_CheckAndUpdateSchema_InvalidSchema(t *testing.T) {
 	}
 }
 
+func TestSchemaManager_CheckAndUpdateSchema_NotModified(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// The mock omits the binary for the known version; an empty binary must not be applied
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("expected version 1.0.0, got %s", version)
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	want := []string{"", "1.0.0"}
+	if len(mock.knownVersionsSeen) != len(want) || mock.knownVersionsSeen[0] != want[0] || mock.knownVersionsSeen[1] != want[1] {
+		t.Errorf("known versions sent = %v, want %v", mock.knownVersionsSeen, want)
+	}
+}
+
+func TestSchemaManager_CheckAndUpdateSchema_ContentHashMismatch(t *testing.T) {
+	server, _ := setupMockISRServer(t, "1.0.0", false)
+
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// ISR serves 1.0.1 with a hash that does not match the binary
+	corruptServer := serveMockISR(t, &mockISRServer{
+		version:        "1.0.1",
+		descriptorData: createTestDescriptorBytes(t),
+		contentHash:    "0000000000000000000000000000000000000000000000000000000000000000",
+	})
+	config.ISRURL = corruptServer.URL
+	manager = NewSchemaManager(config, schemaValidator)
+
+	if err := manager.checkAndUpdateSchema(ctx); err == nil {
+		t.Fatal("expected error for content hash mismatch, got nil")
+	}
+
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("version should not have changed on hash mismatch, got %s", version)
+	}
+}
+
 func TestSchemaManager_StartStop(t *testing.T) {
 	server, _ := setupMockISRServer(t, "1.0.0", false)
 
@@ -286,3 +660,34 @@ func TestSchemaManager_StartStop(t *testing.T) {
 		t.Error("manager did not stop within timeout")
 	}
 }
+
+func TestSchemaManager_BearerToken(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	_, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock)
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+		ISRToken:        "be-token",
+	}, schemaValidator)
+
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if len(authorizations) != 1 || authorizations[0] != "Bearer be-token" {
+		t.Errorf("Authorization headers = %q, want [\"Bearer be-token\"]", authorizations)
+	}
+}
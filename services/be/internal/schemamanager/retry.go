@@ -0,0 +1,121 @@
+package schemamanager
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+// Clock abstracts time so that retry schedules can be tested without waiting
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RetryConfig controls how ISR is polled while the WatchSchema stream is unavailable.
+// The zero value polls every PollingInterval, without jitter or circuit breaking.
+type RetryConfig struct {
+	// Multiplier grows the delay between polls after each consecutive failure, up to MaxBackoff.
+	// Backoff is disabled unless Multiplier > 1 and MaxBackoff > PollingInterval.
+	Multiplier float64
+	MaxBackoff time.Duration
+
+	// Jitter randomizes every delay by up to ±Jitter of its length (0 to 1),
+	// so that replicas failing together do not retry together
+	Jitter float64
+
+	// StartJitter is the upper bound of a random delay before the first request after Start,
+	// so that replicas deployed together do not poll in lockstep
+	StartJitter time.Duration
+
+	// FailureThreshold consecutive failures open the circuit: the stream is not retried and
+	// only a single probe poll is sent every OpenDuration until ISR answers again.
+	// Zero disables the circuit breaker. A zero OpenDuration uses MaxBackoff.
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+// DefaultRetryConfig returns the retry settings used by NewConfig
+func DefaultRetryConfig(pollingInterval time.Duration) RetryConfig {
+	return RetryConfig{
+		Multiplier:       2,
+		MaxBackoff:       10 * pollingInterval,
+		Jitter:           0.2,
+		StartJitter:      pollingInterval,
+		FailureThreshold: 5,
+		OpenDuration:     10 * pollingInterval,
+	}
+}
+
+// retryState counts consecutive poll failures and derives the delay before the next poll.
+// It is only used by the update goroutine.
+type retryState struct {
+	config   RetryConfig
+	interval time.Duration
+	random   func() float64 // In [0, 1)
+	failures int
+}
+
+// circuitOpen reports whether enough consecutive failures occurred to stop retrying the stream
+func (s *retryState) circuitOpen() bool {
+	return s.config.FailureThreshold > 0 && s.failures >= s.config.FailureThreshold
+}
+
+// startDelay returns the random delay before the first request
+func (s *retryState) startDelay() time.Duration {
+	return time.Duration(s.random() * float64(s.config.StartJitter))
+}
+
+// nextDelay returns how long to wait before the next poll
+func (s *retryState) nextDelay() time.Duration {
+	if s.circuitOpen() {
+		return s.jitter(s.openDuration())
+	}
+
+	delay := s.interval
+	if s.config.Multiplier > 1 && s.config.MaxBackoff > s.interval {
+		backoff := float64(s.interval) * math.Pow(s.config.Multiplier, float64(s.failures))
+		delay = time.Duration(min(backoff, float64(s.config.MaxBackoff)))
+	}
+	return s.jitter(delay)
+}
+
+func (s *retryState) openDuration() time.Duration {
+	if s.config.OpenDuration > 0 {
+		return s.config.OpenDuration
+	}
+	return max(s.config.MaxBackoff, s.interval)
+}
+
+func (s *retryState) jitter(d time.Duration) time.Duration {
+	if s.config.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + s.config.Jitter*(2*s.random()-1)))
+}
+
+// record updates the failure count with the result of a poll. Only the first failure,
+// the opening of the circuit and the recovery are logged, not every failed retry.
+func (s *retryState) record(err error) {
+	if err == nil {
+		if s.failures > 0 {
+			log.Printf("ISR recovered after %d failed schema polls", s.failures)
+		}
+		s.failures = 0
+		return
+	}
+
+	s.failures++
+	switch {
+	case s.failures == 1:
+		log.Printf("Schema polling error (retrying with backoff): %v", err)
+	case s.config.FailureThreshold > 0 && s.failures == s.config.FailureThreshold:
+		log.Printf("Schema polling circuit opened after %d consecutive failures, probing ISR every %s: %v",
+			s.failures, s.openDuration(), err)
+	}
+}
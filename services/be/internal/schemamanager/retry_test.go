@@ -0,0 +1,280 @@
+package schemamanager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// fakeClock hands every wait of the update loop to the test, which advances the time and fires it
+type fakeClock struct {
+	waits chan fakeWait
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+type fakeWait struct {
+	d    time.Duration
+	fire chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		waits: make(chan fakeWait, 16),
+		now:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	fire := make(chan time.Time, 1)
+	c.waits <- fakeWait{d, fire}
+	return fire
+}
+
+// waitFor waits for the update loop to sleep and checks how long it asked for, without waking it
+func (c *fakeClock) waitFor(t *testing.T, want time.Duration) fakeWait {
+	t.Helper()
+	select {
+	case w := <-c.waits:
+		if w.d != want {
+			t.Fatalf("wait = %s, want %s", w.d, want)
+		}
+		return w
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a %s wait", want)
+	}
+	return fakeWait{}
+}
+
+// fire advances the clock by the length of w and wakes the update loop
+func (c *fakeClock) fire(w fakeWait) {
+	w.fire <- c.advance(w.d)
+}
+
+func (c *fakeClock) advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// expectWait checks the length of the next wait and fires it
+func (c *fakeClock) expectWait(t *testing.T, want time.Duration) {
+	t.Helper()
+	c.fire(c.waitFor(t, want))
+}
+
+func TestRetryState_NextDelay(t *testing.T) {
+	config := RetryConfig{
+		Multiplier:       2,
+		MaxBackoff:       8 * time.Second,
+		FailureThreshold: 6,
+		OpenDuration:     time.Minute,
+	}
+	tests := []struct {
+		name     string
+		config   RetryConfig
+		failures int
+		random   float64
+		want     time.Duration
+	}{
+		{"healthy", config, 0, 0, time.Second},
+		{"first failure", config, 1, 0, 2 * time.Second},
+		{"third failure", config, 3, 0, 8 * time.Second},
+		{"capped", config, 5, 0, 8 * time.Second},
+		{"circuit open", config, 6, 0, time.Minute},
+		{"no backoff", RetryConfig{}, 5, 0, time.Second},
+		{"jitter low", RetryConfig{Jitter: 0.2}, 0, 0, 800 * time.Millisecond},
+		{"jitter high", RetryConfig{Jitter: 0.2}, 0, 0.75, 1100 * time.Millisecond},
+		{"open duration defaults to max backoff", RetryConfig{Multiplier: 2, MaxBackoff: 8 * time.Second, FailureThreshold: 1}, 1, 0, 8 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &retryState{
+				config:   tt.config,
+				interval: time.Second,
+				random:   func() float64 { return tt.random },
+				failures: tt.failures,
+			}
+			if got := s.nextDelay(); got != tt.want {
+				t.Errorf("nextDelay() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// requestCounter counts ISR requests by procedure
+type requestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *requestCounter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.counts[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]++
+		c.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *requestCounter) get(procedure string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[procedure]
+}
+
+func TestSchemaManager_BackoffAndCircuitBreaker(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	counter := &requestCounter{counts: make(map[string]int)}
+	// WatchSchema is unimplemented, so the manager always falls back to polling
+	path, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock)
+	mux := http.NewServeMux()
+	mux.Handle(path, counter.wrap(handler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	clock := newFakeClock()
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Second,
+		Retry: RetryConfig{
+			Multiplier:       2,
+			MaxBackoff:       4 * time.Second,
+			StartJitter:      10 * time.Second,
+			FailureThreshold: 4,
+			OpenDuration:     time.Minute,
+		},
+		Clock: clock,
+	}, schemaValidator)
+	manager.retry.random = func() float64 { return 0.5 }
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	mock.mu.Lock()
+	mock.errorToReturn = errors.New("ISR is down")
+	mock.mu.Unlock()
+
+	manager.Start(ctx)
+	defer manager.Stop()
+
+	// Each stream attempt arms a poll of its own, which the failed stream abandons
+	watchAttempt := func() { clock.waitFor(t, time.Second) }
+
+	clock.expectWait(t, 5*time.Second) // Start offset
+	watchAttempt()
+	clock.expectWait(t, time.Second)
+	watchAttempt()
+	clock.expectWait(t, 2*time.Second) // After the 1st failure
+	watchAttempt()
+	clock.expectWait(t, 4*time.Second)
+	watchAttempt()
+	clock.expectWait(t, 4*time.Second) // Capped
+	clock.expectWait(t, time.Minute)   // Circuit open after the 4th failure
+	watches := counter.get("WatchSchema")
+	clock.expectWait(t, time.Minute) // The probe failed
+
+	// The stream is not retried while the circuit is open
+	if got := counter.get("WatchSchema"); got != watches {
+		t.Errorf("WatchSchema called %d times while the circuit was open, want %d", got, watches)
+	}
+
+	// A successful probe closes the circuit and resets the backoff
+	mock.mu.Lock()
+	mock.errorToReturn = nil
+	mock.version = "1.0.1"
+	mock.mu.Unlock()
+	watchAttempt()
+	clock.waitFor(t, time.Second)
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.1" {
+		t.Errorf("expected version 1.0.1, got %s", version)
+	}
+	if got := counter.get("WatchSchema"); got != watches+1 {
+		t.Errorf("WatchSchema called %d times after recovery, want %d", got, watches+1)
+	}
+}
+
+func TestSchemaManager_RejectedSchemaKeepsCircuitClosed(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	clock := newFakeClock()
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Second,
+		MaxStaleness:    2 * time.Second,
+		Retry: RetryConfig{
+			Multiplier:       2,
+			MaxBackoff:       4 * time.Second,
+			FailureThreshold: 2,
+			OpenDuration:     time.Minute,
+		},
+		Clock: clock,
+	}, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	// ISR keeps answering with a schema whose binary does not match its hash
+	mock.mu.Lock()
+	mock.version = "1.0.1"
+	mock.contentHash = "0000000000000000000000000000000000000000000000000000000000000000"
+	mock.mu.Unlock()
+
+	manager.Start(ctx)
+	defer manager.Stop()
+
+	// Polls are neither backed off nor stopped by the circuit, however many schemas are rejected
+	for range 4 {
+		clock.waitFor(t, time.Second) // Abandoned by the failed stream attempt
+		clock.expectWait(t, time.Second)
+	}
+	clock.waitFor(t, time.Second)
+
+	status := manager.Status()
+	if status.Version != "1.0.0" {
+		t.Errorf("version = %s, want 1.0.0", status.Version)
+	}
+	if status.CircuitOpen || status.ConsecutiveFailures != 0 {
+		t.Errorf("circuit open = %v, consecutive failures = %d, want closed with no failures",
+			status.CircuitOpen, status.ConsecutiveFailures)
+	}
+	if !strings.Contains(status.LastError, "content hash mismatch") {
+		t.Errorf("last error = %q, want the rejection", status.LastError)
+	}
+	if err := manager.Ready(); err != nil {
+		t.Errorf("Ready() = %v, want nil since ISR is reached", err)
+	}
+}
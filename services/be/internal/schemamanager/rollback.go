@@ -0,0 +1,75 @@
+package schemamanager
+
+import (
+	"context"
+	"log"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// Target returns the schema target the manager follows (e.g. "1.0")
+func (m *SchemaManager) Target() string {
+	return m.config.SchemaTarget
+}
+
+// CurrentVersion returns the version of the schema currently loaded into the validator
+func (m *SchemaManager) CurrentVersion() string {
+	return m.validator.GetCurrentVersion()
+}
+
+// PinnedVersion returns the version kept loaded by Pin or Rollback, or "" while following ISR
+func (m *SchemaManager) PinnedVersion() string {
+	return m.validator.PinnedVersion()
+}
+
+// History returns the previously loaded versions Rollback can switch to, newest first
+func (m *SchemaManager) History() []validator.LoadedVersion {
+	return m.validator.History()
+}
+
+// ShadowStats returns the comparison of the running canary, or false if no canary is running
+func (m *SchemaManager) ShadowStats() (validator.ShadowStats, bool) {
+	return m.validator.ShadowStats()
+}
+
+// Rollback switches back to a previously loaded version without waiting for ISR and pins it,
+// so that the next poll does not swap the newer version back in. A running canary is cancelled.
+func (m *SchemaManager) Rollback(version string) error {
+	previous := m.validator.GetCurrentVersion()
+	if err := m.validator.Rollback(version); err != nil {
+		return err
+	}
+	m.cancelCanary("schema rolled back to " + version)
+	m.setLoaded(m.GetSource())
+
+	log.Printf("Rolled back validator manually: %s -> %s (pinned until unpinned)", previous, version)
+	return nil
+}
+
+// Pin keeps the current version loaded and ignores updates from ISR until Unpin.
+// version must be the current version. A running canary is cancelled.
+func (m *SchemaManager) Pin(version string) error {
+	if err := m.validator.Pin(version); err != nil {
+		return err
+	}
+	m.cancelCanary("schema pinned to " + version)
+
+	log.Printf("Pinned schema %s: updates from ISR are ignored until unpinned", version)
+	return nil
+}
+
+// Unpin resumes following ISR and returns the version that was pinned, or "".
+// ISR is checked right away, since the watch stream only pushes the next change.
+// A failed check is logged; the schema then catches up with the next update.
+func (m *SchemaManager) Unpin(ctx context.Context) string {
+	pinned := m.validator.Unpin()
+	if pinned == "" {
+		return ""
+	}
+	log.Printf("Unpinned schema %s: following ISR again", pinned)
+
+	if err := m.checkAndUpdateSchema(ctx); err != nil {
+		log.Printf("Failed to check ISR after unpinning: %v", err)
+	}
+	return pinned
+}
@@ -0,0 +1,154 @@
+package schemamanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func TestSchemaManager_RollbackAndUnpin(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.6",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+	}, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	mock.mu.Lock()
+	mock.version = "1.0.7"
+	mock.mu.Unlock()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+
+	// Roll back without waiting for ISR; the next poll keeps 1.0.6
+	if err := manager.Rollback("1.0.6"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	status := manager.Status()
+	if status.Version != "1.0.6" || status.Pinned != "1.0.6" || len(status.History) != 1 || status.History[0].Version != "1.0.7" {
+		t.Errorf("status after rollback = %+v, want 1.0.6 pinned", status)
+	}
+
+	// Unpinning catches up with ISR right away
+	if pinned := manager.Unpin(ctx); pinned != "1.0.6" {
+		t.Errorf("Unpin() = %q, want 1.0.6", pinned)
+	}
+	if version := manager.CurrentVersion(); version != "1.0.7" {
+		t.Errorf("expected version 1.0.7 after Unpin, got %s", version)
+	}
+}
+
+func TestSchemaManager_Pin_CancelsCanary(t *testing.T) {
+	manager, schemaValidator, clock := newCanaryManager(t, CanaryConfig{Duration: time.Hour}, "")
+
+	ctx := context.Background()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	clock.waitFor(t, time.Hour)
+
+	if err := manager.Pin("1.0.0"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if status := manager.Status(); status.Canary != nil || status.Pinned != "1.0.0" {
+		t.Errorf("status after Pin = %+v, want 1.0.0 pinned without canary", status)
+	}
+
+	// No canary starts while pinned
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if _, ok := schemaValidator.ShadowStats(); ok {
+		t.Error("a canary started while the schema was pinned")
+	}
+}
+
+func TestSchemaManager_Pin_DuringCanaryDecision(t *testing.T) {
+	manager, schemaValidator, clock := newCanaryManager(t, CanaryConfig{Duration: time.Hour, MaxRejectionRateDelta: 1}, "")
+
+	ctx := context.Background()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	w := clock.waitFor(t, time.Hour)
+
+	// The validator is pinned before Pin gets to cancel the canary, so the promotion fails
+	if err := schemaValidator.Pin("1.0.0"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	clock.fire(w)
+	status := waitForCanaryEnd(t, manager)
+	if _, ok := schemaValidator.ShadowStats(); ok {
+		t.Error("the shadow schema is still installed after the failed promotion")
+	}
+	if status.Version != "1.0.0" || len(status.BlockedVersions) != 0 {
+		t.Errorf("status = %+v, want 1.0.0 loaded and nothing blocked", status)
+	}
+
+	// No canary restarts while pinned; Unpin retries it
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	if _, ok := schemaValidator.ShadowStats(); ok {
+		t.Error("a canary started while the schema was pinned")
+	}
+	manager.Unpin(ctx)
+	clock.waitFor(t, time.Hour)
+	if status := manager.Status(); status.Canary == nil || status.Canary.Version != "1.0.1" {
+		t.Errorf("canary after Unpin = %+v, want 1.0.1", status.Canary)
+	}
+}
+
+func TestSchemaManager_Rollback_DuringCanaryDecision(t *testing.T) {
+	manager, schemaValidator, clock := newCanaryManager(t, CanaryConfig{Duration: time.Hour, MaxRejectionRateDelta: 1}, "")
+	// Give Rollback an older version to return to
+	for _, version := range []string{"0.9.0", "1.0.0"} {
+		if err := schemaValidator.UpdateSchema(createTestDescriptorBytes(t), version); err != nil {
+			t.Fatalf("UpdateSchema failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	if err := manager.checkAndUpdateSchema(ctx); err != nil {
+		t.Fatalf("checkAndUpdateSchema failed: %v", err)
+	}
+	w := clock.waitFor(t, time.Hour)
+
+	// The canary decides after the validator rolled back (pinning and discarding the shadow)
+	// but before Rollback gets to cancel the canary
+	if err := schemaValidator.Rollback("0.9.0"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	clock.fire(w)
+	manager.canaryWG.Wait() // Status shows no canary as soon as the shadow is discarded
+	manager.cancelCanary("schema rolled back to 0.9.0")
+
+	status := manager.Status()
+	if status.Version != "0.9.0" || len(status.BlockedVersions) != 0 {
+		t.Errorf("status = %+v, want 0.9.0 loaded and nothing blocked", status)
+	}
+	// The candidate is tried again once unpinned
+	manager.Unpin(ctx)
+	clock.waitFor(t, time.Hour)
+	if status := manager.Status(); status.Canary == nil || status.Canary.Version != "1.0.1" {
+		t.Errorf("canary after Unpin = %+v, want 1.0.1", status.Canary)
+	}
+}
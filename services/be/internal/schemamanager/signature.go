@@ -0,0 +1,51 @@
+package schemamanager
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+)
+
+// ParseTrustedKeys parses a comma-separated list of base64-encoded ed25519 public keys
+// (the raw 32 bytes, e.g. CELO_SCHEMA_TRUSTED_KEYS). An empty string yields no keys.
+func ParseTrustedKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted key %q: %w", field, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid trusted key %q: expected %d bytes, got %d", field, ed25519.PublicKeySize, len(key))
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
+
+// verifySignature checks the detached ed25519 signature in the schema metadata against the
+// trusted keys. Without trusted keys every schema is accepted; with them, unsigned schemas and
+// schemas signed by any other key are refused, so that a compromised ISR cannot push its own rules.
+func (m *SchemaManager) verifySignature(schema *isrv1.GetLatestPatchResponse) error {
+	if len(m.config.TrustedKeys) == 0 {
+		return nil
+	}
+
+	signature := schema.Metadata.Signature
+	if len(signature) == 0 {
+		return fmt.Errorf("schema %s is not signed", schema.Metadata.Version)
+	}
+	for _, key := range m.config.TrustedKeys {
+		if ed25519.Verify(key, schema.SchemaBinary, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature of schema %s does not match any trusted key", schema.Metadata.Version)
+}
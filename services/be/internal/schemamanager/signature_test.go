@@ -0,0 +1,141 @@
+package schemamanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return public, private
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	first, _ := newKey(t)
+	second, _ := newKey(t)
+	encoded := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr string
+	}{
+		{name: "empty", input: "", want: 0},
+		{name: "one key", input: encoded(first), want: 1},
+		{name: "two keys with spaces", input: encoded(first) + ", " + encoded(second), want: 2},
+		{name: "not base64", input: "not-a-key!", wantErr: "invalid trusted key"},
+		{name: "wrong length", input: encoded(first[:16]), wantErr: "expected 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseTrustedKeys(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseTrustedKeys() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTrustedKeys() error = %v", err)
+			}
+			if len(keys) != tt.want {
+				t.Errorf("ParseTrustedKeys() returned %d keys, want %d", len(keys), tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaManager_Signature(t *testing.T) {
+	trusted, trustedPrivate := newKey(t)
+	_, otherPrivate := newKey(t)
+	descriptorData := createTestDescriptorBytes(t)
+
+	tests := []struct {
+		name        string
+		trustedKeys []ed25519.PublicKey
+		signature   []byte
+		wantVersion string
+	}{
+		{"signed by a trusted key", []ed25519.PublicKey{trusted}, ed25519.Sign(trustedPrivate, descriptorData), "1.0.0"},
+		{"signed by another key", []ed25519.PublicKey{trusted}, ed25519.Sign(otherPrivate, descriptorData), EmbeddedVersion},
+		{"unsigned", []ed25519.PublicKey{trusted}, nil, EmbeddedVersion},
+		{"unsigned without trusted keys", nil, nil, "1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serveMockISR(t, &mockISRServer{
+				version:        "1.0.0",
+				descriptorData: descriptorData,
+				signature:      tt.signature,
+			})
+
+			schemaValidator := &validator.SchemaAwareValidator{}
+			manager := NewSchemaManager(Config{
+				ISRURL:          server.URL,
+				SchemaTarget:    "1.0",
+				Major:           1,
+				Minor:           0,
+				PollingInterval: time.Minute,
+				TrustedKeys:     tt.trustedKeys,
+			}, schemaValidator)
+
+			// A refused schema is never loaded; startup falls back to the embedded descriptor set
+			if err := manager.LoadInitialSchema(context.Background()); err != nil {
+				t.Fatalf("LoadInitialSchema failed: %v", err)
+			}
+			if version := schemaValidator.GetCurrentVersion(); version != tt.wantVersion {
+				t.Errorf("expected version %s, got %s", tt.wantVersion, version)
+			}
+		})
+	}
+}
+
+func TestSchemaManager_Signature_HotSwapRefused(t *testing.T) {
+	trusted, trustedPrivate := newKey(t)
+	descriptorData := createTestDescriptorBytes(t)
+
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: descriptorData,
+		signature:      ed25519.Sign(trustedPrivate, descriptorData),
+	}
+	server := serveMockISR(t, mock)
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+		TrustedKeys:     []ed25519.PublicKey{trusted},
+	}, schemaValidator)
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	// A compromised ISR publishes a newer patch without a valid signature
+	mock.mu.Lock()
+	mock.version = "1.0.1"
+	mock.signature = nil
+	mock.mu.Unlock()
+
+	if err := manager.checkAndUpdateSchema(context.Background()); err == nil {
+		t.Error("checkAndUpdateSchema error = nil, want the unsigned schema to be refused")
+	}
+	if version := schemaValidator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("expected version 1.0.0 to stay loaded, got %s", version)
+	}
+}
@@ -0,0 +1,191 @@
+package schemamanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// Status describes the schema currently loaded by the manager and how recently ISR was reached
+type Status struct {
+	Target  string       `json:"target"`
+	Version string       `json:"version"`
+	Source  SchemaSource `json:"source"`
+	// LoadedAt is when the current schema was loaded into the validator
+	LoadedAt time.Time `json:"loaded_at,omitzero"`
+
+	// Watching is set while the WatchSchema stream is connected. ISR is still polled every
+	// PollingInterval while watching, so staleness is tracked the same way.
+	Watching bool `json:"watching"`
+	// LastSuccessAt is the last time ISR answered a fetch, a poll or a stream message
+	LastSuccessAt time.Time `json:"last_success_at,omitzero"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorAt   time.Time `json:"last_error_at,omitzero"`
+	// ConsecutiveFailures is reset by the next success
+	ConsecutiveFailures int  `json:"consecutive_failures"`
+	CircuitOpen         bool `json:"circuit_open"`
+	// StalenessSeconds is the time since LastSuccessAt (or since startup if ISR was never reached)
+	StalenessSeconds float64 `json:"staleness_seconds"`
+
+	// Yanked is set while the loaded version is yanked in ISR and ISR serves no other version of the
+	// target to roll back to. It stays loaded until a new version is published or it is rolled back.
+	Yanked       bool   `json:"yanked"`
+	YankedReason string `json:"yanked_reason,omitempty"`
+
+	// Pinned is the version kept loaded by Pin or Rollback; updates from ISR are ignored until Unpin
+	Pinned string `json:"pinned,omitempty"`
+	// History lists the previously loaded versions Rollback can switch to, newest first
+	History []validator.LoadedVersion `json:"history,omitempty"`
+
+	// Canary is the new schema currently shadowing the loaded one, if any
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// BlockedVersions failed their canary phase and are not loaded by this instance
+	BlockedVersions []BlockedVersion `json:"blocked_versions,omitempty"`
+}
+
+// health is the state behind Status, guarded by SchemaManager.mu
+type health struct {
+	loadedAt            time.Time
+	lastSuccessAt       time.Time
+	lastError           string
+	lastErrorAt         time.Time
+	consecutiveFailures int
+	circuitOpen         bool
+	watching            bool
+	// yankedVersion is the version checkYanked last found yanked with nothing to roll back to
+	yankedVersion string
+	yankedReason  string
+}
+
+// Status returns the current schema status
+func (m *SchemaManager) Status() Status {
+	// canaryMu is acquired before mu
+	canary := m.canaryStatus()
+	blocked := m.blockedVersions()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := Status{
+		Target:              m.config.SchemaTarget,
+		Version:             m.validator.GetCurrentVersion(),
+		Source:              m.source,
+		LoadedAt:            m.health.loadedAt,
+		Watching:            m.health.watching,
+		LastSuccessAt:       m.health.lastSuccessAt,
+		LastError:           m.health.lastError,
+		LastErrorAt:         m.health.lastErrorAt,
+		ConsecutiveFailures: m.health.consecutiveFailures,
+		CircuitOpen:         m.health.circuitOpen,
+		Pinned:              m.validator.PinnedVersion(),
+		History:             m.validator.History(),
+		Canary:              canary,
+		BlockedVersions:     blocked,
+	}
+	status.StalenessSeconds = m.stalenessLocked().Seconds()
+	if m.health.yankedVersion != "" && m.health.yankedVersion == status.Version {
+		status.Yanked = true
+		status.YankedReason = m.health.yankedReason
+	}
+	return status
+}
+
+// stalenessLocked returns how long ISR has not been heard from. m.mu must be held.
+func (m *SchemaManager) stalenessLocked() time.Duration {
+	since := m.health.lastSuccessAt
+	if since.IsZero() {
+		since = m.startedAt
+	}
+	return m.clock.Now().Sub(since)
+}
+
+// Ready returns an error if no schema is loaded or, when MaxStaleness is set,
+// if ISR has not been reached for longer than MaxStaleness
+func (m *SchemaManager) Ready() error {
+	if m.validator.GetCurrentVersion() == "" {
+		return fmt.Errorf("no schema loaded")
+	}
+	if m.config.MaxStaleness <= 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if staleness := m.stalenessLocked(); staleness > m.config.MaxStaleness {
+		return fmt.Errorf("schema is stale: ISR not reached for %s (max %s), last error: %s",
+			staleness.Truncate(time.Second), m.config.MaxStaleness, m.health.lastError)
+	}
+	return nil
+}
+
+// setLoaded records that a schema from source was just loaded into the validator
+func (m *SchemaManager) setLoaded(source SchemaSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.source = source
+	m.health.loadedAt = m.clock.Now()
+}
+
+// recordHealth records the result of a request to ISR (nil on success) for the status.
+// A rejected schema is reported as the last error, but ISR was reached.
+func (m *SchemaManager) recordHealth(err error, circuitOpen bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if err != nil {
+		m.health.lastError = err.Error()
+		m.health.lastErrorAt = now
+	}
+	if isrFailure(err) != nil {
+		m.health.consecutiveFailures++
+	} else {
+		m.health.lastSuccessAt = now
+		m.health.consecutiveFailures = 0
+	}
+	m.health.circuitOpen = circuitOpen
+}
+
+// setYanked records that version is yanked with nothing to roll back to.
+// It reports whether this changed the recorded state, so that the warning is logged once.
+func (m *SchemaManager) setYanked(version, reason string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.health.yankedVersion == version && m.health.yankedReason == reason {
+		return false
+	}
+	m.health.yankedVersion = version
+	m.health.yankedReason = reason
+	return true
+}
+
+func (m *SchemaManager) setWatching(watching bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health.watching = watching
+}
+
+// StatusHandler returns an HTTP handler that reports the current schema status as JSON
+func (m *SchemaManager) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// ReadinessHandler returns an HTTP handler for readiness probes:
+// 200 when Ready succeeds, 503 with the reason otherwise
+func (m *SchemaManager) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := m.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+}
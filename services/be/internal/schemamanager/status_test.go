@@ -0,0 +1,138 @@
+package schemamanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func TestSchemaManager_StatusHandler(t *testing.T) {
+	server, _ := setupMockISRServer(t, "1.0.4", false)
+	clock := newFakeClock()
+	loadedAt := clock.Now()
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 1 * time.Minute,
+		Clock:           clock,
+	}
+
+	manager := NewSchemaManager(config, &validator.SchemaAwareValidator{})
+	if err := manager.LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	clock.advance(30 * time.Second)
+
+	rec := httptest.NewRecorder()
+	manager.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var status Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+
+	want := Status{
+		Target:           "1.0",
+		Version:          "1.0.4",
+		Source:           SourceISR,
+		LoadedAt:         loadedAt,
+		LastSuccessAt:    loadedAt,
+		StalenessSeconds: 30,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("status = %+v, want %+v", status, want)
+	}
+}
+
+func TestSchemaManager_Ready(t *testing.T) {
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	server := serveMockISR(t, mock)
+
+	clock := newFakeClock()
+	manager := NewSchemaManager(Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+		MaxStaleness:    150 * time.Second,
+		Retry:           RetryConfig{FailureThreshold: 2},
+		Clock:           clock,
+	}, &validator.SchemaAwareValidator{})
+
+	if err := manager.Ready(); err == nil || !strings.Contains(err.Error(), "no schema loaded") {
+		t.Errorf("Ready() before loading error = %v, want no schema loaded", err)
+	}
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	mock.mu.Lock()
+	mock.errorToReturn = errors.New("ISR is down")
+	mock.mu.Unlock()
+
+	manager.Start(ctx)
+	defer manager.Stop()
+
+	// Two failed polls open the circuit, but the schema is not stale yet.
+	// The poll armed by each failed stream attempt is never fired.
+	clock.waitFor(t, time.Minute)
+	clock.expectWait(t, time.Minute)
+	clock.waitFor(t, time.Minute)
+	clock.expectWait(t, time.Minute)
+	w := clock.waitFor(t, time.Minute)
+	status := manager.Status()
+	if status.ConsecutiveFailures != 2 || !status.CircuitOpen || !strings.Contains(status.LastError, "ISR is down") {
+		t.Errorf("status = %+v, want 2 failures with the circuit open", status)
+	}
+	if status.StalenessSeconds != 120 {
+		t.Errorf("StalenessSeconds = %v, want 120", status.StalenessSeconds)
+	}
+	if err := manager.Ready(); err != nil {
+		t.Errorf("Ready() error = %v, want nil", err)
+	}
+
+	// Past MaxStaleness the readiness check fails
+	clock.fire(w)
+	w = clock.waitFor(t, time.Minute)
+	if err := manager.Ready(); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Ready() error = %v, want stale", err)
+	}
+	rec := httptest.NewRecorder()
+	manager.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("readiness status code = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Reaching ISR again makes the BE ready
+	mock.mu.Lock()
+	mock.errorToReturn = nil
+	mock.mu.Unlock()
+	clock.fire(w)
+	clock.waitFor(t, time.Minute)
+	if err := manager.Ready(); err != nil {
+		t.Errorf("Ready() after recovery error = %v, want nil", err)
+	}
+	status = manager.Status()
+	if status.ConsecutiveFailures != 0 || status.CircuitOpen || !status.LastSuccessAt.Equal(clock.Now()) {
+		t.Errorf("status after recovery = %+v", status)
+	}
+}
@@ -0,0 +1,159 @@
+package schemamanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+func TestSchemaManager_ClientCertificate(t *testing.T) {
+	ca, caKey := newCertificate(t, "test-ca", nil, nil)
+	cert, key := newCertificate(t, "be", ca, caKey)
+
+	mock := &mockISRServer{
+		version:        "1.0.0",
+		descriptorData: createTestDescriptorBytes(t),
+	}
+	_, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock)
+	var identities []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identities = append(identities, r.TLS.PeerCertificates[0].Subject.CommonName)
+		handler.ServeHTTP(w, r)
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certFile := writePEM(t, dir, "be.crt", "CERTIFICATE", cert.Raw)
+	keyFile := writePEM(t, dir, "be.key", "EC PRIVATE KEY", keyDER)
+	caFile := writePEM(t, dir, "isr-ca.crt", "CERTIFICATE", server.Certificate().Raw)
+
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: time.Minute,
+	}
+
+	// Without a client certificate the handshake is refused and the embedded schema is loaded
+	config.ISRTLS, err = LoadISRTLSConfig("", "", caFile)
+	if err != nil {
+		t.Fatalf("LoadISRTLSConfig failed: %v", err)
+	}
+	schemaValidator := &validator.SchemaAwareValidator{}
+	if err := NewSchemaManager(config, schemaValidator).LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if schemaValidator.GetCurrentVersion() == "1.0.0" {
+		t.Fatal("schema loaded from ISR without a client certificate")
+	}
+
+	config.ISRTLS, err = LoadISRTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("LoadISRTLSConfig failed: %v", err)
+	}
+	schemaValidator = &validator.SchemaAwareValidator{}
+	if err := NewSchemaManager(config, schemaValidator).LoadInitialSchema(context.Background()); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+	if schemaValidator.GetCurrentVersion() != "1.0.0" {
+		t.Errorf("version = %q, want 1.0.0", schemaValidator.GetCurrentVersion())
+	}
+	if len(identities) != 1 || identities[0] != "be" {
+		t.Errorf("client identities = %q, want [\"be\"]", identities)
+	}
+}
+
+func TestLoadISRTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca, _ := newCertificate(t, "test-ca", nil, nil)
+	caFile := writePEM(t, dir, "ca.crt", "CERTIFICATE", ca.Raw)
+	emptyFile := filepath.Join(dir, "empty.crt")
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	config, err := LoadISRTLSConfig("", "", "")
+	if err != nil || config != nil {
+		t.Errorf("LoadISRTLSConfig() = %v, %v, want nil", config, err)
+	}
+	config, err = LoadISRTLSConfig("", "", caFile)
+	if err != nil || config.RootCAs == nil || len(config.Certificates) != 0 {
+		t.Errorf("LoadISRTLSConfig(CA only) = %+v, %v, want root CAs without a certificate", config, err)
+	}
+
+	for name, files := range map[string][3]string{
+		"certificate without key": {caFile, "", ""},
+		"key without certificate": {"", caFile, ""},
+		"missing CA file":         {"", "", filepath.Join(dir, "missing.crt")},
+		"CA file without PEM":     {"", "", emptyFile},
+	} {
+		if _, err := LoadISRTLSConfig(files[0], files[1], files[2]); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// newCertificate issues a client certificate signed by parent, or a self-signed CA when parent is nil
+func newCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
@@ -0,0 +1,111 @@
+package schemamanager
+
+import (
+	"strconv"
+	"strings"
+)
+
+// schemaVersion is a SemVer 2.0 version as served by ISR, without build metadata
+type schemaVersion struct {
+	major, minor, patch uint64
+	preRelease          string // Dot-separated identifiers after "-", empty for a release
+}
+
+// parseSchemaVersion parses "Major.Minor.Patch[-pre.release][+build]". ISR validates versions
+// on upload, so only the structure needed for ordering is checked here.
+func parseSchemaVersion(s string) (schemaVersion, bool) {
+	s, _, _ = strings.Cut(s, "+")
+	core, preRelease, hasPreRelease := strings.Cut(s, "-")
+	if hasPreRelease && preRelease == "" {
+		return schemaVersion{}, false
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return schemaVersion{}, false
+	}
+	var numbers [3]uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return schemaVersion{}, false
+		}
+		numbers[i] = n
+	}
+	return schemaVersion{major: numbers[0], minor: numbers[1], patch: numbers[2], preRelease: preRelease}, true
+}
+
+// compareSchemaVersions returns -1, 0 or 1 depending on whether a has lower, equal or higher
+// precedence than b, following the same SemVer 2.0 rules as ISR
+func compareSchemaVersions(a, b schemaVersion) int {
+	for _, c := range []int{compareUint(a.major, b.major), compareUint(a.minor, b.minor), compareUint(a.patch, b.patch)} {
+		if c != 0 {
+			return c
+		}
+	}
+	return comparePreRelease(a.preRelease, b.preRelease)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePreRelease orders pre-releases per SemVer 2.0 §11. A release ("") is higher than any pre-release.
+func comparePreRelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifier(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(aIDs)), uint64(len(bIDs)))
+}
+
+// compareIdentifier compares numeric identifiers numerically and others in ASCII order.
+// Numeric identifiers are lower than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		// No leading zeros, so a longer number is larger
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func isNumeric(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package schemamanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
+)
+
+// watchingISRServer pushes versions sent on updates over WatchSchema.
+// Closing the stream is simulated by sending an empty version.
+type watchingISRServer struct {
+	isrv1connect.UnimplementedSchemaRegistryServiceHandler
+	descriptorData []byte
+	updates        chan string
+
+	mu            sync.Mutex
+	latestVersion string
+}
+
+func (s *watchingISRServer) GetLatestPatch(
+	ctx context.Context,
+	req *connect.Request[isrv1.GetLatestPatchRequest],
+) (*connect.Response[isrv1.GetLatestPatchResponse], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return connect.NewResponse(&isrv1.GetLatestPatchResponse{
+		Metadata:     &isrv1.SchemaMetadata{Version: s.latestVersion},
+		SchemaBinary: s.descriptorData,
+	}), nil
+}
+
+func (s *watchingISRServer) WatchSchema(
+	ctx context.Context,
+	req *connect.Request[isrv1.WatchSchemaRequest],
+	stream *connect.ServerStream[isrv1.WatchSchemaResponse],
+) error {
+	for {
+		select {
+		case version := <-s.updates:
+			if version == "" {
+				return connect.NewError(connect.CodeUnavailable, nil)
+			}
+			s.setLatestVersion(version)
+			err := stream.Send(&isrv1.WatchSchemaResponse{
+				Latest: &isrv1.GetLatestPatchResponse{
+					Metadata:     &isrv1.SchemaMetadata{Version: version},
+					SchemaBinary: s.descriptorData,
+				},
+			})
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *watchingISRServer) setLatestVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latestVersion = version
+}
+
+// waitForVersion polls the validator until it reports the expected version
+func waitForVersion(t *testing.T, v *validator.SchemaAwareValidator, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if v.GetCurrentVersion() == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for version %s, current version %s", want, v.GetCurrentVersion())
+}
+
+// startWatchingManager starts a manager following 1.0 on mock, polling every 50ms
+func startWatchingManager(t *testing.T, mock *watchingISRServer) *validator.SchemaAwareValidator {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	path, handler := isrv1connect.NewSchemaRegistryServiceHandler(mock)
+	mux.Handle(path, handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	config := Config{
+		ISRURL:          server.URL,
+		SchemaTarget:    "1.0",
+		Major:           1,
+		Minor:           0,
+		PollingInterval: 50 * time.Millisecond,
+	}
+
+	schemaValidator := &validator.SchemaAwareValidator{}
+	manager := NewSchemaManager(config, schemaValidator)
+
+	ctx := context.Background()
+	if err := manager.LoadInitialSchema(ctx); err != nil {
+		t.Fatalf("LoadInitialSchema failed: %v", err)
+	}
+
+	manager.Start(ctx)
+	t.Cleanup(manager.Stop)
+	return schemaValidator
+}
+
+func TestSchemaManager_WatchSchema_FallsBackToPolling(t *testing.T) {
+	mock := &watchingISRServer{
+		descriptorData: createTestDescriptorBytes(t),
+		updates:        make(chan string),
+		latestVersion:  "1.0.0",
+	}
+	schemaValidator := startWatchingManager(t, mock)
+
+	// Pushed over the stream
+	mock.updates <- "1.0.1"
+	waitForVersion(t, schemaValidator, "1.0.1")
+
+	// Break the stream; the manager picks up 1.0.2 by polling
+	mock.setLatestVersion("1.0.2")
+	mock.updates <- ""
+	waitForVersion(t, schemaValidator, "1.0.2")
+
+	// The stream is re-established after polling
+	mock.updates <- "1.0.3"
+	waitForVersion(t, schemaValidator, "1.0.3")
+}
+
+func TestSchemaManager_WatchSchema_PollsWhileWatching(t *testing.T) {
+	mock := &watchingISRServer{
+		descriptorData: createTestDescriptorBytes(t),
+		updates:        make(chan string),
+		latestVersion:  "1.0.0",
+	}
+	schemaValidator := startWatchingManager(t, mock)
+
+	mock.updates <- "1.0.1"
+	waitForVersion(t, schemaValidator, "1.0.1")
+
+	// 1.0.2 is committed through another ISR replica and never pushed over this stream
+	mock.setLatestVersion("1.0.2")
+	waitForVersion(t, schemaValidator, "1.0.2")
+
+	// The stale stream is dropped and re-established
+	mock.updates <- "1.0.3"
+	waitForVersion(t, schemaValidator, "1.0.3")
+}
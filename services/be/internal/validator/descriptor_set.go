@@ -1,7 +1,7 @@
 package validator
 
 import (
-	"testing"
+	"fmt"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
@@ -10,21 +10,12 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
-// CreateTestDescriptorBytes creates a test FileDescriptorSet from the user.v1 package
-// This function is exported for use in other test packages
-func CreateTestDescriptorBytes(t *testing.T) []byte {
-	t.Helper()
-
-	// Get file descriptor for user.v1 package
-	fileDesc, err := protoregistry.GlobalFiles.FindFileByPath("user/v1/user.proto")
-	if err != nil {
-		t.Fatalf("failed to find user.proto: %v", err)
-	}
-
-	// Create FileDescriptorSet
+// DescriptorSetFromFiles builds a serialized FileDescriptorSet from the files compiled
+// into this binary (protoregistry.GlobalFiles), including all transitive imports.
+// Dependencies are emitted before the files that import them.
+func DescriptorSetFromFiles(paths ...string) ([]byte, error) {
 	fds := &descriptorpb.FileDescriptorSet{}
 
-	// Add all dependencies recursively
 	visited := make(map[string]bool)
 	var addFile func(fd protoreflect.FileDescriptor)
 	addFile = func(fd protoreflect.FileDescriptor) {
@@ -39,17 +30,20 @@ func CreateTestDescriptorBytes(t *testing.T) []byte {
 			addFile(fd.Imports().Get(i).FileDescriptor)
 		}
 
-		// Add this file
 		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
 	}
 
-	addFile(fileDesc)
+	for _, path := range paths {
+		fileDesc, err := protoregistry.GlobalFiles.FindFileByPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s: %w", path, err)
+		}
+		addFile(fileDesc)
+	}
 
-	// Marshal to bytes
 	data, err := proto.Marshal(fds)
 	if err != nil {
-		t.Fatalf("failed to marshal descriptor set: %v", err)
+		return nil, fmt.Errorf("failed to marshal descriptor set: %w", err)
 	}
-
-	return data
+	return data, nil
 }
@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// historySize bounds how many previously loaded validators are kept for Rollback
+const historySize = 5
+
+var (
+	// ErrPinned is returned when the schema cannot change because a version is pinned
+	ErrPinned = errors.New("schema is pinned")
+	// ErrUnknownVersion is returned when a version is not in the history
+	ErrUnknownVersion = errors.New("version not in history")
+	// ErrNotCurrent is returned when pinning a version other than the current one
+	ErrNotCurrent = errors.New("version is not the current schema")
+)
+
+// LoadedVersion is a schema version that was loaded into the validator
+type LoadedVersion struct {
+	Version  string    `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// swapLocked makes vwv the current validator and keeps the previous one in the history. s.mu must be held.
+func (s *SchemaAwareValidator) swapLocked(vwv *validatorWithVersion) {
+	if v := s.v.Load(); v != nil {
+		if previous := v.(*validatorWithVersion); previous.version != vwv.version {
+			s.history = append(s.removeFromHistoryLocked(previous.version), previous)
+		}
+	}
+	// The current version is never also in the history
+	s.history = s.removeFromHistoryLocked(vwv.version)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	s.v.Store(vwv)
+}
+
+func (s *SchemaAwareValidator) removeFromHistoryLocked(version string) []*validatorWithVersion {
+	history := s.history[:0:0]
+	for _, vwv := range s.history {
+		if vwv.version != version {
+			history = append(history, vwv)
+		}
+	}
+	return history
+}
+
+// History returns the previously loaded versions Rollback can switch to, newest first
+func (s *SchemaAwareValidator) History() []LoadedVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make([]LoadedVersion, 0, len(s.history))
+	for i := len(s.history) - 1; i >= 0; i-- {
+		versions = append(versions, LoadedVersion{Version: s.history[i].version, LoadedAt: s.history[i].loadedAt})
+	}
+	return versions
+}
+
+// Rollback switches back to a previously loaded version and pins it, so that the next update
+// does not undo the rollback. Unpin resumes updates. Any shadow schema is discarded.
+func (s *SchemaAwareValidator) Rollback(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, vwv := range s.history {
+		if vwv.version == version {
+			s.swapLocked(vwv)
+			s.pinned = version
+			s.shadow.Store(nil)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot roll back to %s: %w", version, ErrUnknownVersion)
+}
+
+// Pin keeps the current version loaded: UpdateSchema and PromoteShadow fail with ErrPinned until Unpin.
+// version must be the current version, so that a schema swapped in concurrently is not pinned by mistake.
+func (s *SchemaAwareValidator) Pin(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current := s.GetCurrentVersion(); current != version {
+		return fmt.Errorf("cannot pin %s, the current schema is %s: %w", version, current, ErrNotCurrent)
+	}
+	s.pinned = version
+	return nil
+}
+
+// Unpin allows the schema to change again and returns the version that was pinned, or ""
+func (s *SchemaAwareValidator) Unpin() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pinned := s.pinned
+	s.pinned = ""
+	return pinned
+}
+
+// PinnedVersion returns the pinned version, or "" if the schema follows updates
+func (s *SchemaAwareValidator) PinnedVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pinned
+}
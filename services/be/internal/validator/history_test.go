@@ -0,0 +1,111 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func historyVersions(v *SchemaAwareValidator) []string {
+	var versions []string
+	for _, loaded := range v.History() {
+		versions = append(versions, loaded.Version)
+	}
+	return versions
+}
+
+func TestSchemaAwareValidator_History(t *testing.T) {
+	descriptorBytes := createTestDescriptorBytes(t)
+	validator := &SchemaAwareValidator{}
+	for i := range historySize + 2 {
+		if err := validator.UpdateSchema(descriptorBytes, fmt.Sprintf("1.0.%d", i)); err != nil {
+			t.Fatalf("UpdateSchema failed: %v", err)
+		}
+	}
+
+	// The ring keeps the newest previous versions, not the current one
+	got := fmt.Sprint(historyVersions(validator))
+	if want := "[1.0.5 1.0.4 1.0.3 1.0.2 1.0.1]"; got != want {
+		t.Errorf("History() = %s, want %s", got, want)
+	}
+
+	// Loading a version again moves it out of the history
+	if err := validator.UpdateSchema(descriptorBytes, "1.0.3"); err != nil {
+		t.Fatalf("UpdateSchema failed: %v", err)
+	}
+	got = fmt.Sprint(historyVersions(validator))
+	if want := "[1.0.6 1.0.5 1.0.4 1.0.2 1.0.1]"; got != want {
+		t.Errorf("History() = %s, want %s", got, want)
+	}
+}
+
+func TestSchemaAwareValidator_Rollback(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.6")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+	if err := validator.UpdateSchema(createDescriptorBytesWithNameMinLen(t, 5), "1.0.7"); err != nil {
+		t.Fatalf("UpdateSchema failed: %v", err)
+	}
+	if _, err := validator.StartShadow(createTestDescriptorBytes(t), "1.0.8", 0); err != nil {
+		t.Fatalf("StartShadow failed: %v", err)
+	}
+
+	if err := validator.Rollback("1.0.5"); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Rollback() to an unknown version error = %v, want ErrUnknownVersion", err)
+	}
+	if err := validator.Rollback("1.0.6"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	// The rules of 1.0.6 apply again and the rollback is pinned
+	if version := validator.GetCurrentVersion(); version != "1.0.6" {
+		t.Errorf("GetCurrentVersion() = %s, want 1.0.6", version)
+	}
+	if err := validator.Validate(newCreateUserRequest("Bob")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if pinned := validator.PinnedVersion(); pinned != "1.0.6" {
+		t.Errorf("PinnedVersion() = %q, want 1.0.6", pinned)
+	}
+	if _, ok := validator.ShadowStats(); ok {
+		t.Error("ShadowStats() still reports a shadow after Rollback")
+	}
+	if got := fmt.Sprint(historyVersions(validator)); got != "[1.0.7]" {
+		t.Errorf("History() = %s, want [1.0.7]", got)
+	}
+
+	if err := validator.UpdateSchema(createTestDescriptorBytes(t), "1.0.8"); !errors.Is(err, ErrPinned) {
+		t.Errorf("UpdateSchema() while pinned error = %v, want ErrPinned", err)
+	}
+	if pinned := validator.Unpin(); pinned != "1.0.6" {
+		t.Errorf("Unpin() = %q, want 1.0.6", pinned)
+	}
+	if err := validator.UpdateSchema(createTestDescriptorBytes(t), "1.0.8"); err != nil {
+		t.Errorf("UpdateSchema() after Unpin error = %v", err)
+	}
+}
+
+func TestSchemaAwareValidator_Pin(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.0")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	if err := validator.Pin("1.0.1"); !errors.Is(err, ErrNotCurrent) {
+		t.Errorf("Pin() of another version error = %v, want ErrNotCurrent", err)
+	}
+	if err := validator.Pin("1.0.0"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	if _, err := validator.StartShadow(createTestDescriptorBytes(t), "1.0.1", 0); err != nil {
+		t.Fatalf("StartShadow failed: %v", err)
+	}
+	if err := validator.PromoteShadow("1.0.1"); !errors.Is(err, ErrPinned) {
+		t.Errorf("PromoteShadow() while pinned error = %v, want ErrPinned", err)
+	}
+	if version := validator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("GetCurrentVersion() = %s, want 1.0.0", version)
+	}
+}
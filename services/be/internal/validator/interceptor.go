@@ -0,0 +1,14 @@
+package validator
+
+import (
+	"connectrpc.com/connect"
+	"connectrpc.com/validate"
+)
+
+// NewInterceptor returns a connect interceptor that validates incoming requests
+// against the schema currently loaded into v.
+// It replaces validate.NewInterceptor(), which always validates with the rules
+// compiled into the generated types, and picks up schema hot-swaps immediately.
+func NewInterceptor(v *SchemaAwareValidator) connect.Interceptor {
+	return validate.NewInterceptor(validate.WithValidator(v))
+}
@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	commonv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/common/v1"
+	userv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1/userv1connect"
+)
+
+// stubUserService accepts every request that reaches the handler
+type stubUserService struct {
+	userv1connect.UnimplementedUserServiceHandler
+}
+
+func (s *stubUserService) CreateUser(
+	ctx context.Context,
+	req *connect.Request[userv1.CreateUserRequest],
+) (*connect.Response[userv1.CreateUserResponse], error) {
+	return connect.NewResponse(&userv1.CreateUserResponse{}), nil
+}
+
+func TestNewInterceptor_UsesHotSwappedSchema(t *testing.T) {
+	schemaValidator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.5")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	path, handler := userv1connect.NewUserServiceHandler(
+		&stubUserService{},
+		connect.WithInterceptors(NewInterceptor(schemaValidator)),
+	)
+	mux.Handle(path, handler)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := userv1connect.NewUserServiceClient(http.DefaultClient, server.URL)
+	newRequest := func() *connect.Request[userv1.CreateUserRequest] {
+		return connect.NewRequest(&userv1.CreateUserRequest{
+			Name:  "Bob",
+			Email: "bob@example.com",
+			Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+		})
+	}
+
+	if _, err := client.CreateUser(context.Background(), newRequest()); err != nil {
+		t.Fatalf("CreateUser() with schema 1.0.5 error = %v, want nil", err)
+	}
+
+	if err := schemaValidator.UpdateSchema(createDescriptorBytesWithNameMinLen(t, 5), "1.0.6"); err != nil {
+		t.Fatalf("UpdateSchema failed: %v", err)
+	}
+
+	_, err = client.CreateUser(context.Background(), newRequest())
+	if err == nil {
+		t.Fatal("CreateUser() with schema 1.0.6 error = nil, want error")
+	}
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		t.Fatalf("error type = %T, want *connect.Error", err)
+	}
+	if connectErr.Code() != connect.CodeInvalidArgument {
+		t.Errorf("error code = %v, want %v (InvalidArgument)", connectErr.Code(), connect.CodeInvalidArgument)
+	}
+}
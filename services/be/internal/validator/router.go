@@ -0,0 +1,167 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+// SchemaVersionHeader selects the schema target of a request ("2", "2.1" or "2.1.3"; the patch is ignored).
+// Responses carry the version the request was validated with.
+const SchemaVersionHeader = "X-Schema-Version"
+
+// Route is a schema target the routing interceptor can select
+type Route struct {
+	Validator *SchemaAwareValidator
+	// Target is the configured schema target (e.g. "2.0"). It identifies the route while the loaded
+	// version has no Major.Minor (e.g. "embedded").
+	Target string
+}
+
+// majorMinor returns the Major.Minor of the loaded version, or of Target if the version has none
+func (r Route) majorMinor() (int, int, bool) {
+	if major, minor, ok := parseMajorMinor(r.Validator.GetCurrentVersion()); ok && minor >= 0 {
+		return major, minor, true
+	}
+	if major, minor, ok := parseMajorMinor(r.Target); ok && minor >= 0 {
+		return major, minor, true
+	}
+	return 0, 0, false
+}
+
+// parseMajorMinor parses "Major", "Major.Minor" or a full version. minor is -1 if absent.
+func parseMajorMinor(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(version, ".", 3)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 0 {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return major, -1, true
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// packageVersion matches the version component of a proto package (e.g. "v2" or "v2beta1")
+var packageVersion = regexp.MustCompile(`^v(\d+)(?:(?:alpha|beta)\d*)?$`)
+
+// packageMajor returns the major version of the package of a procedure (e.g. 2 for "/user.v2.UserService/CreateUser")
+func packageMajor(procedure string) (int, bool) {
+	service, _, _ := strings.Cut(strings.TrimPrefix(procedure, "/"), "/")
+	parts := strings.Split(service, ".")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if m := packageVersion.FindStringSubmatch(parts[i]); m != nil {
+			major, err := strconv.Atoi(m[1])
+			return major, err == nil
+		}
+	}
+	return 0, false
+}
+
+// NewRoutingInterceptor returns a connect interceptor that validates each request with one of several
+// schema targets, chosen in this order:
+//  1. the X-Schema-Version header; a version no route serves is rejected (InvalidArgument)
+//  2. the major version of the RPC package (e.g. "user.v2" selects a 2.x route)
+//  3. the first route
+//
+// Routes are matched in order against the Major.Minor of their loaded schema, so a route follows its hot-swaps.
+func NewRoutingInterceptor(routes ...Route) connect.Interceptor {
+	interceptors := make([]connect.Interceptor, len(routes))
+	for i, route := range routes {
+		interceptors[i] = NewInterceptor(route.Validator)
+	}
+	return &routingInterceptor{routes: routes, interceptors: interceptors}
+}
+
+type routingInterceptor struct {
+	routes       []Route
+	interceptors []connect.Interceptor
+}
+
+// selectRoute returns the index of the route that validates a request
+func (i *routingInterceptor) selectRoute(header http.Header, procedure string) (int, error) {
+	if requested := header.Get(SchemaVersionHeader); requested != "" {
+		major, minor, ok := parseMajorMinor(requested)
+		if !ok {
+			return 0, connect.NewError(connect.CodeInvalidArgument,
+				fmt.Errorf("invalid %s %q: expected Major, Major.Minor or a full version", SchemaVersionHeader, requested))
+		}
+		if n, ok := i.match(major, minor); ok {
+			return n, nil
+		}
+		return 0, connect.NewError(connect.CodeInvalidArgument,
+			fmt.Errorf("schema version %s is not served by this instance", requested))
+	}
+
+	if major, ok := packageMajor(procedure); ok {
+		if n, ok := i.match(major, -1); ok {
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// match returns the first route serving major (and minor, unless it is -1)
+func (i *routingInterceptor) match(major, minor int) (int, bool) {
+	for n, route := range i.routes {
+		routeMajor, routeMinor, ok := route.majorMinor()
+		if ok && routeMajor == major && (minor < 0 || routeMinor == minor) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func (i *routingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	wrapped := make([]connect.UnaryFunc, len(i.interceptors))
+	for n, interceptor := range i.interceptors {
+		wrapped[n] = interceptor.WrapUnary(next)
+	}
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		n, err := i.selectRoute(req.Header(), req.Spec().Procedure)
+		if err != nil {
+			return nil, err
+		}
+		version := i.routes[n].Validator.GetCurrentVersion()
+
+		resp, err := wrapped[n](ctx, req)
+		if err != nil {
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				connectErr.Meta().Set(SchemaVersionHeader, version)
+			}
+			return nil, err
+		}
+		resp.Header().Set(SchemaVersionHeader, version)
+		return resp, nil
+	}
+}
+
+func (i *routingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *routingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	wrapped := make([]connect.StreamingHandlerFunc, len(i.interceptors))
+	for n, interceptor := range i.interceptors {
+		wrapped[n] = interceptor.WrapStreamingHandler(next)
+	}
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		n, err := i.selectRoute(conn.RequestHeader(), conn.Spec().Procedure)
+		if err != nil {
+			return err
+		}
+		conn.ResponseHeader().Set(SchemaVersionHeader, i.routes[n].Validator.GetCurrentVersion())
+		return wrapped[n](ctx, conn)
+	}
+}
@@ -0,0 +1,147 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1/userv1connect"
+)
+
+func TestPackageMajor(t *testing.T) {
+	tests := []struct {
+		procedure string
+		want      int
+		wantOK    bool
+	}{
+		{"/user.v1.UserService/CreateUser", 1, true},
+		{"/user.v2.UserService/CreateUser", 2, true},
+		{"/acme.user.v3beta1.UserService/CreateUser", 3, true},
+		{"/grpc.health.Health/Check", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.procedure, func(t *testing.T) {
+			got, ok := packageMajor(tt.procedure)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("packageMajor() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// newRoutedUserClient serves UserService behind a routing interceptor over the given routes
+func newRoutedUserClient(t *testing.T, routes ...Route) userv1connect.UserServiceClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(userv1connect.NewUserServiceHandler(
+		&stubUserService{},
+		connect.WithInterceptors(NewRoutingInterceptor(routes...)),
+	))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return userv1connect.NewUserServiceClient(http.DefaultClient, server.URL)
+}
+
+func TestNewRoutingInterceptor(t *testing.T) {
+	v1, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.5")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+	// 2.x requires names of at least 5 characters
+	v2, err := NewSchemaAwareValidator(createDescriptorBytesWithNameMinLen(t, 5), "2.1.0")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+	embedded, err := NewSchemaAwareValidator(createDescriptorBytesWithNameMinLen(t, 5), "embedded")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		routes      []Route
+		header      string
+		wantVersion string
+		wantCode    connect.Code // 0 when the request is accepted
+		wantError   string
+	}{
+		{
+			name:        "default route",
+			routes:      []Route{{Validator: v1}, {Validator: v2}},
+			wantVersion: "1.0.5",
+		},
+		{
+			name:        "RPC package version",
+			routes:      []Route{{Validator: v2}, {Validator: v1}},
+			wantVersion: "1.0.5",
+		},
+		{
+			name:        "header selects the major",
+			routes:      []Route{{Validator: v1}, {Validator: v2}},
+			header:      "2",
+			wantVersion: "2.1.0",
+			wantCode:    connect.CodeInvalidArgument,
+		},
+		{
+			name:        "header with a full version",
+			routes:      []Route{{Validator: v1}, {Validator: v2}},
+			header:      "2.1.7",
+			wantVersion: "2.1.0",
+			wantCode:    connect.CodeInvalidArgument,
+		},
+		{
+			name:        "target identifies an embedded schema",
+			routes:      []Route{{Validator: v1}, {Validator: embedded, Target: "2.1"}},
+			header:      "2.1",
+			wantVersion: "embedded",
+			wantCode:    connect.CodeInvalidArgument,
+		},
+		{
+			name:      "version not served",
+			routes:    []Route{{Validator: v1}, {Validator: v2}},
+			header:    "2.0",
+			wantCode:  connect.CodeInvalidArgument,
+			wantError: "not served",
+		},
+		{
+			name:      "invalid header",
+			routes:    []Route{{Validator: v1}},
+			header:    "latest",
+			wantCode:  connect.CodeInvalidArgument,
+			wantError: "invalid X-Schema-Version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRoutedUserClient(t, tt.routes...)
+			req := connect.NewRequest(newCreateUserRequest("Bob"))
+			if tt.header != "" {
+				req.Header().Set(SchemaVersionHeader, tt.header)
+			}
+
+			resp, err := client.CreateUser(context.Background(), req)
+			if connect.CodeOf(err) != tt.wantCode && !(err == nil && tt.wantCode == 0) {
+				t.Fatalf("CreateUser() error = %v, want code %v", err, tt.wantCode)
+			}
+			if tt.wantError != "" && !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("CreateUser() error = %v, want %q", err, tt.wantError)
+			}
+
+			// The version is reported in the response headers, or in the error metadata
+			var got string
+			var connectErr *connect.Error
+			if resp != nil {
+				got = resp.Header().Get(SchemaVersionHeader)
+			} else if errors.As(err, &connectErr) {
+				got = connectErr.Meta().Get(SchemaVersionHeader)
+			}
+			if got != tt.wantVersion {
+				t.Errorf("%s = %q, want %q", SchemaVersionHeader, got, tt.wantVersion)
+			}
+		})
+	}
+}
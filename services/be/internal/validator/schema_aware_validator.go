@@ -1,9 +1,12 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"buf.build/go/protovalidate"
 	"google.golang.org/protobuf/proto"
@@ -17,12 +20,27 @@ import (
 // validatorWithVersion wraps a validator with its schema version
 type validatorWithVersion struct {
 	validator protovalidate.Validator
+	files     *protoregistry.Files
+	types     *dynamicpb.Types
 	version   string
+	loadedAt  time.Time
 }
 
-// SchemaAwareValidator provides thread-safe schema hot-swapping for protovalidate
+// SchemaAwareValidator provides thread-safe schema hot-swapping for protovalidate.
+//
+// Messages are validated against the descriptors of the currently loaded schema,
+// not the descriptors compiled into the generated Go types, so rule changes
+// published to ISR take effect without rebuilding the service.
 type SchemaAwareValidator struct {
 	v atomic.Value // *validatorWithVersion
+
+	// mu serializes changes of v so that the history and the pin stay consistent with it
+	mu      sync.Mutex
+	history []*validatorWithVersion // Previously loaded validators, oldest first (see History)
+	pinned  string
+
+	// shadow is the candidate schema evaluated alongside v, if any (see StartShadow)
+	shadow atomic.Pointer[shadowValidator]
 }
 
 // NewSchemaAwareValidator creates a new schema-aware validator with the given descriptor bytes and version
@@ -40,22 +58,87 @@ func (s *SchemaAwareValidator) Validate(msg proto.Message, options ...protovalid
 	if v == nil {
 		return fmt.Errorf("validator not initialized: call UpdateSchema first")
 	}
-	vwv := v.(*validatorWithVersion)
-	return vwv.validator.Validate(msg, options...)
+	err := v.(*validatorWithVersion).validate(msg, options...)
+
+	if shadow := s.shadow.Load(); shadow != nil {
+		shadow.observe(msg, err, options)
+	}
+	return err
+}
+
+func (vwv *validatorWithVersion) validate(msg proto.Message, options ...protovalidate.ValidationOption) error {
+	dynamicMsg, err := vwv.toDynamic(msg)
+	if err != nil {
+		return err
+	}
+	return vwv.validator.Validate(dynamicMsg, options...)
+}
+
+// toDynamic re-encodes msg as a dynamicpb.Message built from the schema descriptor
+// with the same full name. protovalidate evaluates the rules of
+// msg.ProtoReflect().Descriptor(), so validating a generated type directly would
+// always apply the rules embedded at compile time.
+// Messages that are not part of the schema are returned unchanged.
+func (vwv *validatorWithVersion) toDynamic(msg proto.Message) (proto.Message, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	fullName := msg.ProtoReflect().Descriptor().FullName()
+
+	desc, err := vwv.files.FindDescriptorByName(fullName)
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return msg, nil
+		}
+		return nil, fmt.Errorf("failed to find descriptor for %s: %w", fullName, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("schema descriptor %s is not a message", fullName)
+	}
+	if msg.ProtoReflect().Descriptor() == md {
+		return msg, nil
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", fullName, err)
+	}
+	dynamicMsg := dynamicpb.NewMessage(md)
+	if err := (proto.UnmarshalOptions{Resolver: vwv.types}).Unmarshal(data, dynamicMsg); err != nil {
+		return nil, fmt.Errorf("failed to decode %s with schema descriptor: %w", fullName, err)
+	}
+	return dynamicMsg, nil
 }
 
 // UpdateSchema atomically updates the validator with a new schema
 func (s *SchemaAwareValidator) UpdateSchema(descriptorBytes []byte, version string) error {
+	vwv, err := newValidatorWithVersion(descriptorBytes, version)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pinned != "" {
+		return fmt.Errorf("%w to %s", ErrPinned, s.pinned)
+	}
+	s.swapLocked(vwv)
+	return nil
+}
+
+// newValidatorWithVersion builds a validator for the given FileDescriptorSet
+func newValidatorWithVersion(descriptorBytes []byte, version string) (*validatorWithVersion, error) {
 	// 1. Unmarshal FileDescriptorSet
 	fds := &descriptorpb.FileDescriptorSet{}
 	if err := proto.Unmarshal(descriptorBytes, fds); err != nil {
-		return fmt.Errorf("failed to unmarshal descriptor set: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %w", err)
 	}
 
 	// 2. Create Files registry
 	files, err := protodesc.NewFiles(fds)
 	if err != nil {
-		return fmt.Errorf("failed to create files registry: %w", err)
+		return nil, fmt.Errorf("failed to create files registry: %w", err)
 	}
 
 	// 3. Create extension type registry and register all extensions
@@ -85,7 +168,7 @@ func (s *SchemaAwareValidator) UpdateSchema(descriptorBytes []byte, version stri
 	})
 
 	if len(descriptors) == 0 {
-		return fmt.Errorf("no message descriptors found in schema")
+		return nil, fmt.Errorf("no message descriptors found in schema")
 	}
 
 	// 5. Create protovalidate.Validator with extension resolver
@@ -94,16 +177,16 @@ func (s *SchemaAwareValidator) UpdateSchema(descriptorBytes []byte, version stri
 		protovalidate.WithExtensionTypeResolver(extensionRegistry),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create validator: %w", err)
+		return nil, fmt.Errorf("failed to create validator: %w", err)
 	}
 
-	// 6. Store in atomic.Value
-	s.v.Store(&validatorWithVersion{
+	return &validatorWithVersion{
 		validator: validator,
+		files:     files,
+		types:     dynamicpb.NewTypes(files),
 		version:   version,
-	})
-
-	return nil
+		loadedAt:  time.Now(),
+	}, nil
 }
 
 // GetCurrentVersion returns the current schema version
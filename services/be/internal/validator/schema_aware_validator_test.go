@@ -5,13 +5,62 @@ import (
 	"sync"
 	"testing"
 
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	commonv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/common/v1"
 	userv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// createTestDescriptorBytes creates a test FileDescriptorSet from the user.v1 package
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := DescriptorSetFromFiles("user/v1/user.proto")
+	if err != nil {
+		t.Fatalf("failed to create descriptor set: %v", err)
+	}
+	return data
+}
+
+// createDescriptorBytesWithNameMinLen returns the user.v1 descriptor set with the
+// min_len rule of CreateUserRequest.name replaced, simulating a patch published to ISR.
+func createDescriptorBytesWithNameMinLen(t *testing.T, minLen uint64) []byte {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(createTestDescriptorBytes(t), fds); err != nil {
+		t.Fatalf("failed to unmarshal descriptor set: %v", err)
+	}
+
+	for _, file := range fds.File {
+		if file.GetName() != "user/v1/user.proto" {
+			continue
+		}
+		for _, msg := range file.MessageType {
+			if msg.GetName() != "CreateUserRequest" {
+				continue
+			}
+			for _, field := range msg.Field {
+				if field.GetName() != "name" {
+					continue
+				}
+				rules := proto.GetExtension(field.GetOptions(), validate.E_Field).(*validate.FieldRules)
+				rules.GetString().MinLen = proto.Uint64(minLen)
+			}
+		}
+	}
+
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
 func TestNewSchemaAwareValidator_Success(t *testing.T) {
-	descriptorBytes := CreateTestDescriptorBytes(t)
+	descriptorBytes := createTestDescriptorBytes(t)
 
 	validator, err := NewSchemaAwareValidator(descriptorBytes, "1.0.0")
 	if err != nil {
@@ -38,7 +87,7 @@ func TestNewSchemaAwareValidator_InvalidDescriptor(t *testing.T) {
 }
 
 func TestSchemaAwareValidator_Validate(t *testing.T) {
-	descriptorBytes := CreateTestDescriptorBytes(t)
+	descriptorBytes := createTestDescriptorBytes(t)
 
 	validator, err := NewSchemaAwareValidator(descriptorBytes, "1.0.0")
 	if err != nil {
@@ -135,7 +184,7 @@ func TestSchemaAwareValidator_ValidateBeforeInit(t *testing.T) {
 }
 
 func TestSchemaAwareValidator_UpdateSchema(t *testing.T) {
-	descriptorBytes := CreateTestDescriptorBytes(t)
+	descriptorBytes := createTestDescriptorBytes(t)
 
 	validator, err := NewSchemaAwareValidator(descriptorBytes, "1.0.0")
 	if err != nil {
@@ -169,8 +218,51 @@ func TestSchemaAwareValidator_UpdateSchema(t *testing.T) {
 	}
 }
 
+func TestSchemaAwareValidator_UpdateSchema_AppliesNewRules(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.5")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	msg := &userv1.CreateUserRequest{
+		Name:  "Bob",
+		Email: "bob@example.com",
+		Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+	}
+
+	if err := validator.Validate(msg); err != nil {
+		t.Fatalf("Validate() with min_len 1 error = %v, want nil", err)
+	}
+
+	// Tighten name min_len from 1 to 5 without touching the generated types
+	if err := validator.UpdateSchema(createDescriptorBytesWithNameMinLen(t, 5), "1.0.6"); err != nil {
+		t.Fatalf("UpdateSchema failed: %v", err)
+	}
+
+	if err := validator.Validate(msg); err == nil {
+		t.Fatal("Validate() with min_len 5 error = nil, want validation error")
+	}
+
+	msg.Name = "Alice"
+	if err := validator.Validate(msg); err != nil {
+		t.Errorf("Validate() with 5-character name error = %v, want nil", err)
+	}
+}
+
+func TestSchemaAwareValidator_Validate_MessageNotInSchema(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.0")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	// wrapperspb types are not part of the user.v1 schema and are validated as-is
+	if err := validator.Validate(wrapperspb.String("test")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
 func TestSchemaAwareValidator_ConcurrentAccess(t *testing.T) {
-	descriptorBytes := CreateTestDescriptorBytes(t)
+	descriptorBytes := createTestDescriptorBytes(t)
 
 	validator, err := NewSchemaAwareValidator(descriptorBytes, "1.0.0")
 	if err != nil {
@@ -226,7 +318,7 @@ func TestSchemaAwareValidator_ConcurrentAccess(t *testing.T) {
 }
 
 func TestSchemaAwareValidator_GetCurrentVersion(t *testing.T) {
-	descriptorBytes := CreateTestDescriptorBytes(t)
+	descriptorBytes := createTestDescriptorBytes(t)
 
 	tests := []struct {
 		name    string
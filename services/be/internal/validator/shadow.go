@@ -0,0 +1,169 @@
+package validator
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxDisagreementExamples bounds how many disagreements are kept for inspection
+const maxDisagreementExamples = 20
+
+// ShadowStats summarizes how a shadow schema judged live requests compared to the current one.
+// A request counts as rejected when Validate returned any error.
+type ShadowStats struct {
+	Version           string `json:"version"`
+	Samples           int64  `json:"samples"`
+	CurrentRejections int64  `json:"current_rejections"`
+	ShadowRejections  int64  `json:"shadow_rejections"`
+	Disagreements     int64  `json:"disagreements"`
+	// Examples quote validation errors, which may contain request data. They are left out of JSON
+	// so that they never reach the public status; the admin service serves them.
+	Examples []Disagreement `json:"-"`
+}
+
+// Disagreement is a request that only one of the current and shadow schemas rejected
+type Disagreement struct {
+	Message string `json:"message"`
+	// Current and Shadow are the validation errors, empty when the request was accepted
+	Current string `json:"current,omitempty"`
+	Shadow  string `json:"shadow,omitempty"`
+}
+
+// RejectionRateDelta returns the shadow rejection rate minus the current rejection rate,
+// between -1 and 1. It is 0 before any sample was taken.
+func (s ShadowStats) RejectionRateDelta() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.ShadowRejections-s.CurrentRejections) / float64(s.Samples)
+}
+
+// shadowValidator validates live requests with a candidate schema without affecting their result
+type shadowValidator struct {
+	candidate *validatorWithVersion
+	limit     int64
+	taken     atomic.Int64 // Samples reserved so far, to stop validating once limit is reached
+	done      chan struct{}
+	doneOnce  sync.Once
+
+	mu    sync.Mutex
+	stats ShadowStats
+}
+
+// observe validates msg with the candidate schema and compares the verdict with current
+func (sv *shadowValidator) observe(msg proto.Message, current error, options []protovalidate.ValidationOption) {
+	n := sv.taken.Add(1)
+	if sv.limit > 0 && n > sv.limit {
+		return
+	}
+	shadow := sv.candidate.validate(msg, options...)
+
+	sv.mu.Lock()
+	sv.stats.Samples++
+	if current != nil {
+		sv.stats.CurrentRejections++
+	}
+	if shadow != nil {
+		sv.stats.ShadowRejections++
+	}
+	if (current == nil) != (shadow == nil) {
+		sv.stats.Disagreements++
+		if len(sv.stats.Examples) < maxDisagreementExamples {
+			sv.stats.Examples = append(sv.stats.Examples, Disagreement{
+				Message: messageName(msg),
+				Current: errorString(current),
+				Shadow:  errorString(shadow),
+			})
+		}
+	}
+	sv.mu.Unlock()
+
+	if sv.limit > 0 && n == sv.limit {
+		sv.doneOnce.Do(func() { close(sv.done) })
+	}
+}
+
+func (sv *shadowValidator) snapshot() ShadowStats {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	stats := sv.stats
+	stats.Examples = append([]Disagreement(nil), sv.stats.Examples...)
+	return stats
+}
+
+func messageName(msg proto.Message) string {
+	if msg == nil {
+		return ""
+	}
+	return string(msg.ProtoReflect().Descriptor().FullName())
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// StartShadow builds a validator for a candidate schema and runs it alongside the current one:
+// every Validate call is also checked against the candidate, but only the current verdict is returned.
+// The returned channel is closed once sampleLimit requests were compared (never if sampleLimit is 0).
+// Starting a shadow replaces the previous one.
+func (s *SchemaAwareValidator) StartShadow(descriptorBytes []byte, version string, sampleLimit int64) (<-chan struct{}, error) {
+	candidate, err := newValidatorWithVersion(descriptorBytes, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build shadow validator: %w", err)
+	}
+
+	shadow := &shadowValidator{
+		candidate: candidate,
+		limit:     sampleLimit,
+		done:      make(chan struct{}),
+		stats:     ShadowStats{Version: version},
+	}
+	s.shadow.Store(shadow)
+	return shadow.done, nil
+}
+
+// ShadowStats returns the comparison so far, or false if no shadow is running
+func (s *SchemaAwareValidator) ShadowStats() (ShadowStats, bool) {
+	shadow := s.shadow.Load()
+	if shadow == nil {
+		return ShadowStats{}, false
+	}
+	return shadow.snapshot(), true
+}
+
+// PromoteShadow makes the shadow schema the current one and stops shadowing.
+// version must match the running shadow so that a replaced shadow is never promoted by mistake.
+func (s *SchemaAwareValidator) PromoteShadow(version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checked first: Rollback pins and discards the shadow at once
+	if s.pinned != "" {
+		return fmt.Errorf("%w to %s", ErrPinned, s.pinned)
+	}
+	shadow := s.shadow.Load()
+	if shadow == nil {
+		return fmt.Errorf("no shadow schema to promote")
+	}
+	if shadow.candidate.version != version {
+		return fmt.Errorf("shadow schema is %s, not %s", shadow.candidate.version, version)
+	}
+
+	shadow.candidate.loadedAt = time.Now()
+	s.swapLocked(shadow.candidate)
+	s.shadow.CompareAndSwap(shadow, nil)
+	return nil
+}
+
+// StopShadow discards the shadow schema, if any
+func (s *SchemaAwareValidator) StopShadow() {
+	s.shadow.Store(nil)
+}
@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"testing"
+
+	commonv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/common/v1"
+	userv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1"
+)
+
+func newCreateUserRequest(name string) *userv1.CreateUserRequest {
+	return &userv1.CreateUserRequest{
+		Name:  name,
+		Email: name + "@example.com",
+		Plan:  commonv1.UserPlan_USER_PLAN_FREE,
+	}
+}
+
+func TestSchemaAwareValidator_Shadow(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.0")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	if _, ok := validator.ShadowStats(); ok {
+		t.Error("ShadowStats() reported a shadow before StartShadow")
+	}
+
+	done, err := validator.StartShadow(createDescriptorBytesWithNameMinLen(t, 5), "1.0.1", 3)
+	if err != nil {
+		t.Fatalf("StartShadow failed: %v", err)
+	}
+
+	// Only the current schema decides the result while shadowing
+	if err := validator.Validate(newCreateUserRequest("Bob")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if err := validator.Validate(newCreateUserRequest("Alice")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	select {
+	case <-done:
+		t.Fatal("done closed before the sample limit was reached")
+	default:
+	}
+	if err := validator.Validate(newCreateUserRequest("")); err == nil {
+		t.Error("Validate() error = nil, want the empty name rejected")
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatal("done not closed after the sample limit was reached")
+	}
+
+	// Requests past the sample limit are not compared
+	validator.Validate(newCreateUserRequest("Bob"))
+
+	stats, ok := validator.ShadowStats()
+	if !ok {
+		t.Fatal("ShadowStats() reported no shadow")
+	}
+	if stats.Version != "1.0.1" || stats.Samples != 3 || stats.CurrentRejections != 1 ||
+		stats.ShadowRejections != 2 || stats.Disagreements != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if len(stats.Examples) != 1 || stats.Examples[0].Message != "user.v1.CreateUserRequest" ||
+		stats.Examples[0].Current != "" || stats.Examples[0].Shadow == "" {
+		t.Errorf("examples = %+v", stats.Examples)
+	}
+	if delta := stats.RejectionRateDelta(); delta < 0.33 || delta > 0.34 {
+		t.Errorf("RejectionRateDelta() = %v, want 1/3", delta)
+	}
+
+	if err := validator.PromoteShadow("1.0.2"); err == nil {
+		t.Error("PromoteShadow() with another version error = nil, want error")
+	}
+	if err := validator.PromoteShadow("1.0.1"); err != nil {
+		t.Fatalf("PromoteShadow() error = %v", err)
+	}
+	if version := validator.GetCurrentVersion(); version != "1.0.1" {
+		t.Errorf("GetCurrentVersion() = %s, want 1.0.1", version)
+	}
+	if _, ok := validator.ShadowStats(); ok {
+		t.Error("ShadowStats() still reports a shadow after PromoteShadow")
+	}
+	if err := validator.Validate(newCreateUserRequest("Bob")); err == nil {
+		t.Error("Validate() error = nil, want the promoted min_len applied")
+	}
+}
+
+func TestSchemaAwareValidator_StopShadow(t *testing.T) {
+	validator, err := NewSchemaAwareValidator(createTestDescriptorBytes(t), "1.0.0")
+	if err != nil {
+		t.Fatalf("NewSchemaAwareValidator failed: %v", err)
+	}
+
+	if _, err := validator.StartShadow([]byte("invalid"), "1.0.1", 0); err == nil {
+		t.Error("StartShadow() with an invalid descriptor error = nil, want error")
+	}
+	if _, err := validator.StartShadow(createDescriptorBytesWithNameMinLen(t, 5), "1.0.1", 0); err != nil {
+		t.Fatalf("StartShadow failed: %v", err)
+	}
+	validator.StopShadow()
+
+	if err := validator.PromoteShadow("1.0.1"); err == nil {
+		t.Error("PromoteShadow() after StopShadow error = nil, want error")
+	}
+	if version := validator.GetCurrentVersion(); version != "1.0.0" {
+		t.Errorf("GetCurrentVersion() = %s, want 1.0.0", version)
+	}
+}
@@ -8,15 +8,19 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
 	"connectrpc.com/connect"
 	"connectrpc.com/validate"
+	adminv1connect "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/admin/v1/adminv1connect"
 	postv1connect "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/post/v1/postv1connect"
 	userv1connect "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/user/v1/userv1connect"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/handler"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/repository"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/schemamanager"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/be/internal/validator"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
@@ -28,17 +32,133 @@ func main() {
 }
 
 func run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Get configuration from environment
 	port := os.Getenv("CELO_PORT")
 	if port == "" {
 		port = "50052"
 	}
 
+	// Schema configuration
+	isrURL := os.Getenv("CELO_ISR_URL")
+	if isrURL == "" {
+		isrURL = "http://localhost:50051"
+	}
+
+	schemaTarget := os.Getenv("CELO_SCHEMA_TARGET")
+	if schemaTarget == "" {
+		schemaTarget = "1.0"
+	}
+
+	pollingInterval := 1 * time.Minute
+	if v := os.Getenv("CELO_SCHEMA_POLLING_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CELO_SCHEMA_POLLING_INTERVAL: %w", err)
+		}
+		pollingInterval = d
+	}
+
 	// YAML file paths for data
 	dataDir := os.Getenv("CELO_DATA_DIR")
 	if dataDir == "" {
 		dataDir = "./data"
 	}
+
+	// Several targets (e.g. "1.0;2.0") are followed at once while clients migrate; the first is the default
+	schemaTargets := schemamanager.ParseSchemaTargets(schemaTarget)
+	if len(schemaTargets) == 0 {
+		return fmt.Errorf("invalid CELO_SCHEMA_TARGET: %q", schemaTarget)
+	}
+	schemaConfig, err := schemamanager.NewConfig(isrURL, schemaTargets[0], pollingInterval)
+	if err != nil {
+		return fmt.Errorf("invalid schema configuration: %w", err)
+	}
+
+	// Cache of the last schema fetched from ISR, used when ISR is down at startup
+	schemaConfig.CacheDir = os.Getenv("CELO_SCHEMA_CACHE_DIR")
+	if schemaConfig.CacheDir == "" {
+		schemaConfig.CacheDir = filepath.Join(dataDir, "schema-cache")
+	}
+	// Backoff while ISR is failing; the circuit opens after the threshold of consecutive failures
+	// and probes ISR once per open duration
+	if v := os.Getenv("CELO_SCHEMA_MAX_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CELO_SCHEMA_MAX_BACKOFF: %w", err)
+		}
+		schemaConfig.Retry.MaxBackoff = d
+	}
+	if v := os.Getenv("CELO_SCHEMA_CIRCUIT_OPEN_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CELO_SCHEMA_CIRCUIT_OPEN_DURATION: %w", err)
+		}
+		schemaConfig.Retry.OpenDuration = d
+	}
+	if v := os.Getenv("CELO_SCHEMA_CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid CELO_SCHEMA_CIRCUIT_BREAKER_THRESHOLD: %q", v)
+		}
+		schemaConfig.Retry.FailureThreshold = n
+	}
+	// Readiness fails once ISR has not been reached for this long
+	if v := os.Getenv("CELO_SCHEMA_MAX_STALENESS"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CELO_SCHEMA_MAX_STALENESS: %w", err)
+		}
+		schemaConfig.MaxStaleness = d
+	}
+	// New schemas shadow the current one on live requests before the swap
+	if v := os.Getenv("CELO_SCHEMA_CANARY_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CELO_SCHEMA_CANARY_DURATION: %w", err)
+		}
+		schemaConfig.Canary.Duration = d
+	}
+	if v := os.Getenv("CELO_SCHEMA_CANARY_SAMPLES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid CELO_SCHEMA_CANARY_SAMPLES: %q", v)
+		}
+		schemaConfig.Canary.Samples = n
+	}
+	if v := os.Getenv("CELO_SCHEMA_CANARY_MAX_REJECTION_DELTA"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			return fmt.Errorf("invalid CELO_SCHEMA_CANARY_MAX_REJECTION_DELTA: %q", v)
+		}
+		schemaConfig.Canary.MaxRejectionRateDelta = f
+	}
+	// Credentials for an ISR that requires authentication
+	schemaConfig.ISRToken = os.Getenv("CELO_ISR_TOKEN")
+	schemaConfig.ISRTLS, err = schemamanager.LoadISRTLSConfig(
+		os.Getenv("CELO_ISR_TLS_CERT_FILE"), os.Getenv("CELO_ISR_TLS_KEY_FILE"), os.Getenv("CELO_ISR_TLS_CA_FILE"))
+	if err != nil {
+		return fmt.Errorf("invalid ISR TLS configuration: %w", err)
+	}
+	// Only schemas signed by one of these keys are loaded
+	schemaConfig.TrustedKeys, err = schemamanager.ParseTrustedKeys(os.Getenv("CELO_SCHEMA_TRUSTED_KEYS"))
+	if err != nil {
+		return fmt.Errorf("invalid CELO_SCHEMA_TRUSTED_KEYS: %w", err)
+	}
+	if len(schemaConfig.TrustedKeys) == 0 {
+		log.Println("CELO_SCHEMA_TRUSTED_KEYS is not set; schemas from ISR are loaded without signature verification")
+	}
+	// Every target shares the settings above
+	schemaConfigs := []schemamanager.Config{schemaConfig}
+	for _, target := range schemaTargets[1:] {
+		config, err := schemaConfig.ForTarget(target)
+		if err != nil {
+			return fmt.Errorf("invalid schema configuration: %w", err)
+		}
+		schemaConfigs = append(schemaConfigs, config)
+	}
 	userYAMLPath := filepath.Join(dataDir, "user.yaml")
 	postYAMLPath := filepath.Join(dataDir, "post.yaml")
 
@@ -59,11 +179,25 @@ func run() error {
 	userHandler := handler.NewUserHandler(userRepo)
 	postHandler := handler.NewPostHandler(postRepo, userRepo)
 
+	// Initialize a schema-aware validator per target and load the initial schemas from ISR
+	schemaGroup, err := schemamanager.NewGroup(schemaConfigs...)
+	if err != nil {
+		return fmt.Errorf("invalid schema configuration: %w", err)
+	}
+	if err := schemaGroup.LoadInitialSchema(ctx); err != nil {
+		return fmt.Errorf("failed to load initial schema: %w", err)
+	}
+
+	// Start polling ISR for schema updates
+	schemaGroup.Start(ctx)
+	defer schemaGroup.Stop()
+
 	// Create HTTP server with Connect
 	mux := http.NewServeMux()
-	// Use standard protovalidate interceptor
+	// Validate against the loaded schema (dynamicpb) instead of the generated types,
+	// picking the target by X-Schema-Version or the RPC package version
 	interceptors := connect.WithInterceptors(
-		validate.NewInterceptor(),
+		validator.NewRoutingInterceptor(schemaGroup.Routes()...),
 	)
 
 	// Register User Service
@@ -74,12 +208,36 @@ func run() error {
 	postPath, postConnectHandler := postv1connect.NewPostServiceHandler(postHandler, interceptors)
 	mux.Handle(postPath, postConnectHandler)
 
+	// Register the schema admin service (rollback / pin) only when a token protects it.
+	// Its requests are validated with the compiled rules; they are not part of the ISR schema.
+	if adminToken := os.Getenv("CELO_ADMIN_TOKEN"); adminToken != "" {
+		adminPath, adminConnectHandler := adminv1connect.NewSchemaAdminServiceHandler(
+			handler.NewSchemaAdminHandler(func(target string) (handler.SchemaController, bool) {
+				m, ok := schemaGroup.Manager(target)
+				if !ok {
+					return nil, false
+				}
+				return m, true
+			}),
+			connect.WithInterceptors(handler.NewAdminTokenInterceptor(adminToken), validate.NewInterceptor()),
+		)
+		mux.Handle(adminPath, adminConnectHandler)
+	} else {
+		log.Println("CELO_ADMIN_TOKEN is not set; the schema admin service is disabled")
+	}
+
 	// Add health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	// Report the loaded schema version, where it came from and how recently ISR was reached
+	// (?target=2.0 selects another target)
+	mux.Handle("/schema/status", schemaGroup.StatusHandler())
+	// Readiness probe: fails without a schema or when the schema is stale
+	mux.Handle("/ready", schemaGroup.ReadinessHandler())
+
 	addr := fmt.Sprintf(":%s", port)
 	srv := &http.Server{
 		Addr:              addr,
@@ -94,6 +252,7 @@ func run() error {
 		<-sigCh
 
 		log.Println("Shutting down server...")
+		schemaGroup.Stop()
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
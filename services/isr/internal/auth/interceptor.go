@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+)
+
+// TagLookup returns the version a tag points to, or storage.ErrNotFound.
+// storage.SchemaRepository implements it.
+type TagLookup interface {
+	GetByTag(ctx context.Context, tag string) (*model.Schema, error)
+}
+
+// Authorizer authenticates requests and checks them against a Policy
+type Authorizer struct {
+	policy     *Policy
+	tags       TagLookup
+	byToken    map[string]*Principal // By hex SHA-256 of the token
+	byIdentity map[string]*Principal // By TLS identity
+}
+
+// NewAuthorizer returns an Authorizer for a validated policy. tags is used to authorize SetTag
+// against the major the tag is moved away from as well as the one it is moved to.
+func NewAuthorizer(policy *Policy, tags TagLookup) *Authorizer {
+	a := &Authorizer{
+		policy:     policy,
+		tags:       tags,
+		byToken:    make(map[string]*Principal),
+		byIdentity: make(map[string]*Principal),
+	}
+	for i := range policy.Principals {
+		principal := &policy.Principals[i]
+		if principal.TokenSHA256 != "" {
+			a.byToken[strings.ToLower(principal.TokenSHA256)] = principal
+		}
+		for _, id := range principal.TLSIdentities {
+			a.byIdentity[id] = principal
+		}
+	}
+	return a
+}
+
+type clientCertKey struct{}
+
+// WithClientCertificate makes the verified client certificate of an mTLS connection available to the
+// interceptor. It must wrap the handlers the interceptor is installed on.
+func WithClientCertificate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// VerifiedChains is only set for certificates signed by the configured client CAs
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), clientCertKey{}, r.TLS.VerifiedChains[0][0]))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Interceptor returns a connect interceptor that rejects unauthenticated (Unauthenticated) and
+// unauthorized (PermissionDenied) requests before they reach validation or the handler
+func (a *Authorizer) Interceptor() connect.Interceptor {
+	return &interceptor{a}
+}
+
+type interceptor struct {
+	a *Authorizer
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		principal, err := i.a.authenticate(ctx, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		if err := i.a.authorize(ctx, principal, req.Spec().Procedure, req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler authenticates when the stream opens and authorizes each received message,
+// since the major of a WatchSchema request is only known once it is received
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		principal, err := i.a.authenticate(ctx, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, &authorizedConn{
+			StreamingHandlerConn: conn,
+			authorize: func(msg any) error {
+				return i.a.authorize(ctx, principal, conn.Spec().Procedure, msg)
+			},
+		})
+	}
+}
+
+type authorizedConn struct {
+	connect.StreamingHandlerConn
+	authorize func(msg any) error
+}
+
+func (c *authorizedConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return c.authorize(msg)
+}
+
+// authenticate returns the principal presenting a bearer token or a client certificate,
+// or nil for an anonymous request. Credentials that match no principal are rejected
+// rather than treated as anonymous.
+func (a *Authorizer) authenticate(ctx context.Context, header http.Header) (*Principal, error) {
+	if authorization := header.Get("Authorization"); authorization != "" {
+		token, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok || token == "" {
+			return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("authorization header must be \"Bearer <token>\""))
+		}
+		sum := sha256.Sum256([]byte(token))
+		principal, ok := a.byToken[hex.EncodeToString(sum[:])]
+		if !ok {
+			return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("invalid bearer token"))
+		}
+		return principal, nil
+	}
+
+	if cert, ok := ctx.Value(clientCertKey{}).(*x509.Certificate); ok {
+		for _, id := range certIdentities(cert) {
+			if principal, ok := a.byIdentity[id]; ok {
+				return principal, nil
+			}
+		}
+		return nil, connect.NewError(connect.CodeUnauthenticated,
+			fmt.Errorf("client certificate %q matches no principal", cert.Subject.CommonName))
+	}
+	return nil, nil
+}
+
+// authorize checks the grants of principal (nil for anonymous) for a request to procedure
+func (a *Authorizer) authorize(ctx context.Context, principal *Principal, procedure string, msg any) error {
+	access := procedureAccess(procedure)
+	majors := requestMajors(msg)
+	if req, ok := msg.(*isrv1.SetTagRequest); ok && majors != nil {
+		current, err := a.tags.GetByTag(ctx, req.Tag)
+		switch {
+		case err == nil:
+			if current.Major != majors[0] {
+				majors = append(majors, current.Major)
+			}
+		case !errors.Is(err, storage.ErrNotFound):
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get tag: %w", err))
+		}
+	}
+
+	grants, name := a.policy.Anonymous, "anonymous"
+	if principal != nil {
+		grants, name = principal.Grants, principal.Name
+	}
+	if allows(grants, access, majors) {
+		return nil
+	}
+
+	target := "every major"
+	if majors != nil {
+		target = fmt.Sprintf("major %v", majors)
+	}
+	if principal == nil {
+		return connect.NewError(connect.CodeUnauthenticated,
+			fmt.Errorf("%s requires credentials with %s access to %s", procedure, access, target))
+	}
+	return connect.NewError(connect.CodePermissionDenied,
+		fmt.Errorf("%s has no %s access to %s", name, access, target))
+}
+
+// certIdentities returns the names a client certificate can be matched by
+func certIdentities(cert *x509.Certificate) []string {
+	var ids []string
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+	return ids
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/handler"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/memory"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// createTestDescriptorSet builds a FileDescriptorSet from the isr.v1 package
+// and its transitive imports, dependencies first
+func createTestDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if visited[fd.Path()] {
+			return
+		}
+		visited[fd.Path()] = true
+
+		for i := 0; i < fd.Imports().Len(); i++ {
+			addFile(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	addFile(isrv1.File_isr_v1_isr_proto)
+
+	return fds
+}
+
+// createTestDescriptorBytes returns the serialized form of createTestDescriptorSet
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := proto.Marshal(createTestDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// testPolicy lets "ci" write major 1, "reader" and "be.internal" read everything, and anonymous read major 1
+func testPolicy() *Policy {
+	return &Policy{
+		Principals: []Principal{
+			{Name: "ci", TokenSHA256: tokenHash("ci-token"), Grants: []Grant{{Access: AccessWrite, Majors: []int32{1}}}},
+			{Name: "reader", TokenSHA256: tokenHash("reader-token"), Grants: []Grant{{Access: AccessRead}}},
+			{Name: "be", TLSIdentities: []string{"be.internal"}, Grants: []Grant{{Access: AccessRead}}},
+		},
+		Anonymous: []Grant{{Access: AccessRead, Majors: []int32{1}}},
+	}
+}
+
+// newTestServer serves an in-memory ISR behind the policy
+func newTestServer(t *testing.T, policy *Policy) *httptest.Server {
+	t.Helper()
+	repo := memory.New()
+	path, h := isrv1connect.NewSchemaRegistryServiceHandler(handler.NewSchemaHandler(repo),
+		connect.WithInterceptors(NewAuthorizer(policy, repo).Interceptor()))
+	mux := http.NewServeMux()
+	mux.Handle(path, h)
+	server := httptest.NewUnstartedServer(WithClientCertificate(mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// withToken adds a bearer token to every request
+func withToken(token string) connect.ClientOption {
+	return connect.WithInterceptors(connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			req.Header().Set("Authorization", "Bearer "+token)
+			return next(ctx, req)
+		}
+	}))
+}
+
+func TestInterceptor_BearerToken(t *testing.T) {
+	server := newTestServer(t, testPolicy())
+	server.Start()
+	ctx := context.Background()
+	schemaBinary := createTestDescriptorBytes(t)
+
+	client := func(opts ...connect.ClientOption) isrv1connect.SchemaRegistryServiceClient {
+		return isrv1connect.NewSchemaRegistryServiceClient(server.Client(), server.URL, opts...)
+	}
+	upload := func(c isrv1connect.SchemaRegistryServiceClient, v string) error {
+		_, err := c.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{Version: v, SchemaBinary: schemaBinary}))
+		return err
+	}
+	latest := func(c isrv1connect.SchemaRegistryServiceClient, major int32) error {
+		_, err := c.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{Major: major}))
+		return err
+	}
+	resolve := func(c isrv1connect.SchemaRegistryServiceClient) error {
+		_, err := c.ResolveVersion(ctx, connect.NewRequest(&isrv1.ResolveVersionRequest{Constraint: "^1"}))
+		return err
+	}
+
+	ci, reader, anonymous := client(withToken("ci-token")), client(withToken("reader-token")), client()
+	tests := []struct {
+		name string
+		call func() error
+		want connect.Code // 0 for success
+	}{
+		{"ci uploads major 1", func() error { return upload(ci, "1.0.0") }, 0},
+		{"ci uploads major 2", func() error { return upload(ci, "2.0.0") }, connect.CodePermissionDenied},
+		{"ci reads major 1", func() error { return latest(ci, 1) }, 0},
+		{"ci resolves a range", func() error { return resolve(ci) }, connect.CodePermissionDenied},
+		{"reader uploads", func() error { return upload(reader, "1.0.1") }, connect.CodePermissionDenied},
+		{"reader reads major 1", func() error { return latest(reader, 1) }, 0},
+		{"reader resolves a range", func() error { return resolve(reader) }, 0},
+		{"anonymous reads major 1", func() error { return latest(anonymous, 1) }, 0},
+		{"anonymous reads major 2", func() error { return latest(anonymous, 2) }, connect.CodeUnauthenticated},
+		{"anonymous uploads", func() error { return upload(anonymous, "1.0.1") }, connect.CodeUnauthenticated},
+		{"invalid token", func() error { return latest(client(withToken("guess")), 1) }, connect.CodeUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.want == 0 {
+				if err != nil {
+					t.Errorf("error = %v, want nil", err)
+				}
+				return
+			}
+			if connect.CodeOf(err) != tt.want {
+				t.Errorf("error = %v, want code %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterceptor_SetTagAcrossMajors(t *testing.T) {
+	policy := testPolicy()
+	policy.Principals = append(policy.Principals,
+		Principal{Name: "admin", TokenSHA256: tokenHash("admin-token"), Grants: []Grant{{Access: AccessWrite}}})
+	server := newTestServer(t, policy)
+	server.Start()
+	ctx := context.Background()
+	schemaBinary := createTestDescriptorBytes(t)
+
+	admin := isrv1connect.NewSchemaRegistryServiceClient(server.Client(), server.URL, withToken("admin-token"))
+	ci := isrv1connect.NewSchemaRegistryServiceClient(server.Client(), server.URL, withToken("ci-token"))
+	setTag := func(c isrv1connect.SchemaRegistryServiceClient, tag, v string) error {
+		_, err := c.SetTag(ctx, connect.NewRequest(&isrv1.SetTagRequest{Tag: tag, Version: v}))
+		return err
+	}
+	for _, v := range []string{"1.0.0", "1.0.1", "2.0.0"} {
+		if _, err := admin.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{Version: v, SchemaBinary: schemaBinary})); err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", v, err)
+		}
+	}
+	if err := setTag(admin, "stable", "2.0.0"); err != nil {
+		t.Fatalf("SetTag() error = %v", err)
+	}
+
+	// ci may write major 1 only, so it cannot take stable away from major 2
+	if err := setTag(ci, "stable", "1.0.0"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("ci moving stable from 2.0.0 error = %v, want code %v", err, connect.CodePermissionDenied)
+	}
+	if err := setTag(ci, "canary", "1.0.0"); err != nil {
+		t.Errorf("ci creating canary error = %v, want nil", err)
+	}
+	if err := setTag(ci, "canary", "1.0.1"); err != nil {
+		t.Errorf("ci moving canary within major 1 error = %v, want nil", err)
+	}
+	if err := setTag(ci, "canary", "2.0.0"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("ci moving canary to 2.0.0 error = %v, want code %v", err, connect.CodePermissionDenied)
+	}
+}
+
+func TestInterceptor_WatchSchema(t *testing.T) {
+	server := newTestServer(t, testPolicy())
+	server.Start()
+	client := isrv1connect.NewSchemaRegistryServiceClient(server.Client(), server.URL)
+
+	// The major is only known once the stream's request is received
+	stream, err := client.WatchSchema(context.Background(), connect.NewRequest(&isrv1.WatchSchemaRequest{Major: 2}))
+	if err != nil {
+		t.Fatalf("WatchSchema() error = %v", err)
+	}
+	defer stream.Close()
+	if stream.Receive() {
+		t.Fatal("Receive() = true, want the stream to be rejected")
+	}
+	if connect.CodeOf(stream.Err()) != connect.CodeUnauthenticated {
+		t.Errorf("stream error = %v, want code %v", stream.Err(), connect.CodeUnauthenticated)
+	}
+}
+
+func TestInterceptor_ClientCertificate(t *testing.T) {
+	ca, caKey := newCertificate(t, "test-ca", nil, nil)
+	server := newTestServer(t, testPolicy())
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: certPool(ca)}
+	server.StartTLS()
+
+	clientWithCert := func(cn string) isrv1connect.SchemaRegistryServiceClient {
+		cert, key := newCertificate(t, cn, ca, caKey)
+		// A transport per client, so that connections are not shared across certificates
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = []tls.Certificate{
+			{Certificate: [][]byte{cert.Raw}, PrivateKey: key},
+		}
+		return isrv1connect.NewSchemaRegistryServiceClient(&http.Client{Transport: transport}, server.URL)
+	}
+	resolve := func(c isrv1connect.SchemaRegistryServiceClient) error {
+		_, err := c.ResolveVersion(context.Background(), connect.NewRequest(&isrv1.ResolveVersionRequest{Constraint: "^1"}))
+		return err
+	}
+
+	// Reading every major passes authorization and finds nothing
+	if err := resolve(clientWithCert("be.internal")); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("be.internal error = %v, want code %v", err, connect.CodeNotFound)
+	}
+	if err := resolve(clientWithCert("unknown.internal")); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("unknown.internal error = %v, want code %v", err, connect.CodeUnauthenticated)
+	}
+}
+
+// newCertificate issues a certificate for cn, self-signed when parent is nil
+func newCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func certPool(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
@@ -0,0 +1,148 @@
+// Package auth authenticates ISR callers with static bearer tokens or mTLS client certificates
+// and authorizes them against a per-major read/write policy.
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Access is the kind of permission a procedure requires
+type Access string
+
+const (
+	AccessRead  Access = "read"  // Fetching schemas and metadata
+	AccessWrite Access = "write" // Uploading, tagging, yanking and deprecating; implies read
+)
+
+// Grant allows an access on some majors
+type Grant struct {
+	Access Access  `json:"access"`
+	Majors []int32 `json:"majors,omitempty"` // Empty for every major
+}
+
+// Principal is a caller identified by a bearer token or a client certificate
+type Principal struct {
+	Name string `json:"name"`
+	// Hex-encoded SHA-256 of the bearer token, so that the policy file holds no secret
+	TokenSHA256 string `json:"token_sha256,omitempty"`
+	// Client certificate identities: the subject common name or a DNS, URI or email SAN
+	TLSIdentities []string `json:"tls_identities,omitempty"`
+	Grants        []Grant  `json:"grants"`
+}
+
+// Policy is the JSON document loaded from CELO_AUTH_CONFIG, e.g.
+//
+//	{
+//	  "principals": [
+//	    {"name": "ci", "token_sha256": "9f86d0...", "grants": [{"access": "write", "majors": [1]}]},
+//	    {"name": "be", "tls_identities": ["be.internal"], "grants": [{"access": "read"}]}
+//	  ],
+//	  "anonymous": [{"access": "read", "majors": [1]}]
+//	}
+type Policy struct {
+	Principals []Principal `json:"principals"`
+	// Anonymous are the grants of requests without credentials. Empty rejects them.
+	Anonymous []Grant `json:"anonymous,omitempty"`
+}
+
+// LoadPolicy reads and validates a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth policy: %w", err)
+	}
+	// A misspelled key would silently drop grants
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var policy Policy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to decode auth policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// Validate checks that every principal can be identified and every grant is well-formed
+func (p *Policy) Validate() error {
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	identities := make(map[string]bool)
+	for _, principal := range p.Principals {
+		if principal.Name == "" {
+			return fmt.Errorf("principal without a name")
+		}
+		if names[principal.Name] {
+			return fmt.Errorf("principal %q is defined twice", principal.Name)
+		}
+		names[principal.Name] = true
+
+		if principal.TokenSHA256 == "" && len(principal.TLSIdentities) == 0 {
+			return fmt.Errorf("principal %q has neither token_sha256 nor tls_identities", principal.Name)
+		}
+		if principal.TokenSHA256 != "" {
+			if b, err := hex.DecodeString(principal.TokenSHA256); err != nil || len(b) != 32 {
+				return fmt.Errorf("principal %q: token_sha256 must be 64 hex characters", principal.Name)
+			}
+			if tokens[principal.TokenSHA256] {
+				return fmt.Errorf("principal %q: token is shared with another principal", principal.Name)
+			}
+			tokens[principal.TokenSHA256] = true
+		}
+		for _, id := range principal.TLSIdentities {
+			if identities[id] {
+				return fmt.Errorf("principal %q: TLS identity %q is shared with another principal", principal.Name, id)
+			}
+			identities[id] = true
+		}
+		if err := validateGrants(principal.Grants); err != nil {
+			return fmt.Errorf("principal %q: %w", principal.Name, err)
+		}
+	}
+	if err := validateGrants(p.Anonymous); err != nil {
+		return fmt.Errorf("anonymous: %w", err)
+	}
+	return nil
+}
+
+func validateGrants(grants []Grant) error {
+	for _, g := range grants {
+		if g.Access != AccessRead && g.Access != AccessWrite {
+			return fmt.Errorf("invalid access %q (want read or write)", g.Access)
+		}
+		for _, major := range g.Majors {
+			if major < 0 {
+				return fmt.Errorf("invalid major %d", major)
+			}
+		}
+	}
+	return nil
+}
+
+// covers reports whether g permits access to major. A nil major needs a grant on every major.
+func (g Grant) covers(access Access, major *int32) bool {
+	if access == AccessWrite && g.Access != AccessWrite {
+		return false
+	}
+	return len(g.Majors) == 0 || (major != nil && slices.Contains(g.Majors, *major))
+}
+
+// allows reports whether grants permit access to every one of majors.
+// A nil majors means the request does not name a major; only grants on every major apply then.
+func allows(grants []Grant, access Access, majors []int32) bool {
+	if majors == nil {
+		return slices.ContainsFunc(grants, func(g Grant) bool { return g.covers(access, nil) })
+	}
+	for _, major := range majors {
+		if !slices.ContainsFunc(grants, func(g Grant) bool { return g.covers(access, &major) }) {
+			return false
+		}
+	}
+	return true
+}
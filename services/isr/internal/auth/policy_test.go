@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			json: `{"principals": [{"name": "ci", "token_sha256": "` + hash + `", "grants": [{"access": "write", "majors": [1]}]},
+				{"name": "be", "tls_identities": ["be.internal"], "grants": [{"access": "read"}]}],
+				"anonymous": [{"access": "read"}]}`,
+		},
+		{
+			name:    "unknown field",
+			json:    `{"principals": [], "anonymus": []}`,
+			wantErr: "unknown field",
+		},
+		{
+			name:    "no credentials",
+			json:    `{"principals": [{"name": "ci", "grants": []}]}`,
+			wantErr: "neither token_sha256 nor tls_identities",
+		},
+		{
+			name:    "plain token",
+			json:    `{"principals": [{"name": "ci", "token_sha256": "secret", "grants": []}]}`,
+			wantErr: "64 hex characters",
+		},
+		{
+			name: "shared token",
+			json: `{"principals": [{"name": "a", "token_sha256": "` + hash + `"},
+				{"name": "b", "token_sha256": "` + hash + `"}]}`,
+			wantErr: "shared with another principal",
+		},
+		{
+			name:    "invalid access",
+			json:    `{"anonymous": [{"access": "admin"}]}`,
+			wantErr: "invalid access",
+		},
+		{
+			name:    "negative major",
+			json:    `{"anonymous": [{"access": "read", "majors": [-1]}]}`,
+			wantErr: "invalid major",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "auth.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+				t.Fatalf("failed to write policy: %v", err)
+			}
+			_, err := LoadPolicy(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadPolicy() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadPolicy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	grants := []Grant{
+		{Access: AccessWrite, Majors: []int32{1}},
+		{Access: AccessRead, Majors: []int32{2}},
+	}
+	tests := []struct {
+		access Access
+		majors []int32
+		want   bool
+	}{
+		{AccessWrite, []int32{1}, true},
+		{AccessRead, []int32{1}, true}, // Write implies read
+		{AccessRead, []int32{2}, true},
+		{AccessWrite, []int32{2}, false},
+		{AccessRead, []int32{1, 2}, true},
+		{AccessRead, []int32{1, 3}, false},
+		{AccessRead, nil, false}, // No grant on every major
+	}
+	for _, tt := range tests {
+		if got := allows(grants, tt.access, tt.majors); got != tt.want {
+			t.Errorf("allows(%s, %v) = %v, want %v", tt.access, tt.majors, got, tt.want)
+		}
+	}
+
+	if !allows([]Grant{{Access: AccessRead}}, AccessRead, nil) {
+		t.Error("a grant on every major should allow a request without a major")
+	}
+}
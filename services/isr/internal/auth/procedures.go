@@ -0,0 +1,69 @@
+package auth
+
+import (
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+)
+
+// writeProcedures change what BEs validate against; every other procedure only reads
+var writeProcedures = map[string]bool{
+	isrv1connect.SchemaRegistryServiceUploadSchemaProcedure:    true,
+	isrv1connect.SchemaRegistryServiceSetTagProcedure:          true,
+	isrv1connect.SchemaRegistryServiceYankSchemaProcedure:      true,
+	isrv1connect.SchemaRegistryServiceDeprecateSchemaProcedure: true,
+}
+
+func procedureAccess(procedure string) Access {
+	if writeProcedures[procedure] {
+		return AccessWrite
+	}
+	return AccessRead
+}
+
+// requestMajors returns the majors a request touches, or nil if it does not name one
+// (e.g. ResolveVersion, GetSchemaByTag, GetSchemaByHash or an unfiltered listing)
+func requestMajors(msg any) []int32 {
+	switch m := msg.(type) {
+	case *isrv1.GetLatestPatchRequest:
+		return []int32{m.Major}
+	case *isrv1.WatchSchemaRequest:
+		return []int32{m.Major}
+	case *isrv1.ListSchemasRequest:
+		if m.Major != nil {
+			return []int32{*m.Major}
+		}
+	case *isrv1.ListVersionsRequest:
+		if m.Major != nil {
+			return []int32{*m.Major}
+		}
+	case *isrv1.GetSchemaByVersionRequest:
+		return versionMajors(m.Version)
+	case *isrv1.UploadSchemaRequest:
+		return versionMajors(m.Version)
+	case *isrv1.SetTagRequest:
+		// authorize adds the major of the version the tag currently points to
+		return versionMajors(m.Version)
+	case *isrv1.YankSchemaRequest:
+		return versionMajors(m.Version)
+	case *isrv1.DeprecateSchemaRequest:
+		return versionMajors(m.Version)
+	case *isrv1.DiffSchemasRequest:
+		return versionMajors(m.FromVersion, m.ToVersion)
+	}
+	return nil
+}
+
+// versionMajors returns the majors of versions. An invalid version, which validation rejects
+// afterwards, yields nil so that only a grant on every major lets the request through.
+func versionMajors(versions ...string) []int32 {
+	majors := make([]int32, 0, len(versions))
+	for _, v := range versions {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			return nil
+		}
+		majors = append(majors, parsed.Major)
+	}
+	return majors
+}
@@ -0,0 +1,177 @@
+package compat
+
+import (
+	"fmt"
+	"sort"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Violation is a breaking change found between two schema versions
+type Violation struct {
+	Rule        Rule
+	Level       Level
+	Element     string // Fully-qualified name of the affected message, field, enum or enum value
+	Description string
+}
+
+// Check compares next against previous and returns the violations that are
+// not turned off for bump, sorted by element
+func Check(cfg Config, bump Bump, previous, next *protoregistry.Files) []Violation {
+	var violations []Violation
+	for _, v := range Compare(previous, next) {
+		v.Level = cfg.Level(bump, v.Rule)
+		if v.Level != LevelOff {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// HasErrors reports whether any violation is at LevelError
+func HasErrors(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare returns every breaking change from previous to next, sorted by element.
+// Level is left empty; use Check to apply a Config.
+func Compare(previous, next *protoregistry.Files) []Violation {
+	c := &comparer{next: next}
+
+	previous.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		c.compareMessages(fd.Messages())
+		c.compareEnums(fd.Enums())
+		return true
+	})
+
+	sort.SliceStable(c.violations, func(i, j int) bool {
+		if c.violations[i].Element != c.violations[j].Element {
+			return c.violations[i].Element < c.violations[j].Element
+		}
+		return c.violations[i].Rule < c.violations[j].Rule
+	})
+	return c.violations
+}
+
+type comparer struct {
+	next       *protoregistry.Files
+	violations []Violation
+}
+
+func (c *comparer) add(rule Rule, element protoreflect.FullName, format string, args ...any) {
+	c.violations = append(c.violations, Violation{
+		Rule:        rule,
+		Element:     string(element),
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *comparer) compareMessages(msgs protoreflect.MessageDescriptors) {
+	for i := 0; i < msgs.Len(); i++ {
+		prev := msgs.Get(i)
+		if prev.IsMapEntry() {
+			continue // Compared through the map field itself
+		}
+
+		desc, err := c.next.FindDescriptorByName(prev.FullName())
+		next, ok := desc.(protoreflect.MessageDescriptor)
+		if err != nil || !ok {
+			c.add(RuleMessageRemoved, prev.FullName(), "message %s was removed", prev.FullName())
+			continue
+		}
+
+		c.compareFields(prev, next)
+		c.compareMessages(prev.Messages())
+		c.compareEnums(prev.Enums())
+	}
+}
+
+func (c *comparer) compareFields(prev, next protoreflect.MessageDescriptor) {
+	fields := prev.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		pf := fields.Get(i)
+
+		nf := next.Fields().ByNumber(pf.Number())
+		if nf == nil {
+			c.add(RuleFieldRemoved, pf.FullName(), "field %s (%d) was removed", pf.Name(), pf.Number())
+			continue
+		}
+		if nf.Name() != pf.Name() {
+			c.add(RuleFieldNumberReused, pf.FullName(), "field number %d was %s, now used by %s", pf.Number(), pf.Name(), nf.Name())
+			continue
+		}
+		if prevType, nextType := fieldType(pf), fieldType(nf); prevType != nextType {
+			c.add(RuleFieldTypeChanged, pf.FullName(), "field %s changed type from %s to %s", pf.Name(), prevType, nextType)
+			continue
+		}
+		if !isRequired(pf) && isRequired(nf) {
+			c.add(RuleRequiredTightened, pf.FullName(), "field %s became required", pf.Name())
+		}
+	}
+
+	// Existing producers never set a new field, so adding it as required breaks them
+	added := next.Fields()
+	for i := 0; i < added.Len(); i++ {
+		nf := added.Get(i)
+		if fields.ByNumber(nf.Number()) == nil && isRequired(nf) {
+			c.add(RuleRequiredTightened, nf.FullName(), "required field %s (%d) was added", nf.Name(), nf.Number())
+		}
+	}
+}
+
+func (c *comparer) compareEnums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		prev := enums.Get(i)
+
+		desc, err := c.next.FindDescriptorByName(prev.FullName())
+		next, ok := desc.(protoreflect.EnumDescriptor)
+		if err != nil || !ok {
+			c.add(RuleEnumRemoved, prev.FullName(), "enum %s was removed", prev.FullName())
+			continue
+		}
+
+		values := prev.Values()
+		for j := 0; j < values.Len(); j++ {
+			pv := values.Get(j)
+			if next.Values().ByNumber(pv.Number()) == nil {
+				c.add(RuleEnumValueRemoved, pv.FullName(), "enum value %s (%d) was removed", pv.Name(), pv.Number())
+			}
+		}
+	}
+}
+
+// fieldType describes the wire-relevant type of a field, e.g. "repeated string"
+// or "map<string, common.v1.User>"
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(fd.MapKey()), fieldType(fd.MapValue()))
+	}
+
+	name := fd.Kind().String()
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		name = string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		name = string(fd.Enum().FullName())
+	}
+	if fd.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
+
+// isRequired reports whether a field is required by proto2 syntax or buf.validate
+func isRequired(fd protoreflect.FieldDescriptor) bool {
+	if fd.Cardinality() == protoreflect.Required {
+		return true
+	}
+	rules, err := protovalidate.ResolveFieldRules(fd)
+	return err == nil && rules.GetRequired()
+}
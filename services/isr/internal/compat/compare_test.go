@@ -0,0 +1,264 @@
+package compat
+
+import (
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+		JsonName: proto.String(name),
+	}
+}
+
+// baseFile returns a small schema used as the previous version in tests:
+//
+//	message User { string name = 1; int32 age = 2; repeated string tags = 3; Plan plan = 4; }
+//	enum Plan { PLAN_UNSPECIFIED = 0; PLAN_FREE = 1; PLAN_PRO = 2; }
+func baseFile() *descriptorpb.FileDescriptorProto {
+	plan := field("plan", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+	plan.TypeName = proto.String(".test.v1.Plan")
+	tags := field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/test.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("age", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				tags,
+				plan,
+			},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Plan"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("PLAN_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("PLAN_FREE"), Number: proto.Int32(1)},
+				{Name: proto.String("PLAN_PRO"), Number: proto.Int32(2)},
+			},
+		}},
+	}
+}
+
+func buildFiles(t *testing.T, fdp *descriptorpb.FileDescriptorProto) *protoregistry.Files {
+	t.Helper()
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build %s: %v", fdp.GetName(), err)
+	}
+	files := &protoregistry.Files{}
+	if err := files.RegisterFile(fd); err != nil {
+		t.Fatalf("failed to register %s: %v", fdp.GetName(), err)
+	}
+	return files
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(f *descriptorpb.FileDescriptorProto)
+		wantRule    Rule
+		wantElement string
+	}{
+		{
+			name: "field removed",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType[0].Field = f.MessageType[0].Field[1:]
+			},
+			wantRule:    RuleFieldRemoved,
+			wantElement: "test.v1.User.name",
+		},
+		{
+			name: "field number reused",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType[0].Field[1].Name = proto.String("years")
+				f.MessageType[0].Field[1].JsonName = proto.String("years")
+			},
+			wantRule:    RuleFieldNumberReused,
+			wantElement: "test.v1.User.age",
+		},
+		{
+			name: "scalar type changed",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType[0].Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+			},
+			wantRule:    RuleFieldTypeChanged,
+			wantElement: "test.v1.User.age",
+		},
+		{
+			name: "repeated became singular",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType[0].Field[2].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+			},
+			wantRule:    RuleFieldTypeChanged,
+			wantElement: "test.v1.User.tags",
+		},
+		{
+			name: "message removed",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType = nil
+			},
+			wantRule:    RuleMessageRemoved,
+			wantElement: "test.v1.User",
+		},
+		{
+			name: "enum value removed",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.EnumType[0].Value = f.EnumType[0].Value[:2]
+			},
+			wantRule:    RuleEnumValueRemoved,
+			wantElement: "test.v1.PLAN_PRO",
+		},
+		{
+			name: "required added",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+				proto.SetExtension(f.MessageType[0].Field[0].Options, validate.E_Field, &validate.FieldRules{
+					Required: proto.Bool(true),
+				})
+			},
+			wantRule:    RuleRequiredTightened,
+			wantElement: "test.v1.User.name",
+		},
+		{
+			name: "required field added",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				email := field("email", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+				email.Options = &descriptorpb.FieldOptions{}
+				proto.SetExtension(email.Options, validate.E_Field, &validate.FieldRules{
+					Required: proto.Bool(true),
+				})
+				f.MessageType[0].Field = append(f.MessageType[0].Field, email)
+			},
+			wantRule:    RuleRequiredTightened,
+			wantElement: "test.v1.User.email",
+		},
+		{
+			name: "proto2 required field added",
+			modify: func(f *descriptorpb.FileDescriptorProto) {
+				f.Syntax = proto.String("proto2")
+				email := field("email", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+				email.Label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+				f.MessageType[0].Field = append(f.MessageType[0].Field, email)
+			},
+			wantRule:    RuleRequiredTightened,
+			wantElement: "test.v1.User.email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := baseFile()
+			tt.modify(next)
+
+			got := Compare(buildFiles(t, baseFile()), buildFiles(t, next))
+			if len(got) != 1 {
+				t.Fatalf("Compare() returned %d violations, want 1: %v", len(got), got)
+			}
+			if got[0].Rule != tt.wantRule {
+				t.Errorf("Rule = %s, want %s", got[0].Rule, tt.wantRule)
+			}
+			if got[0].Element != tt.wantElement {
+				t.Errorf("Element = %s, want %s", got[0].Element, tt.wantElement)
+			}
+			if got[0].Description == "" {
+				t.Error("Description is empty")
+			}
+		})
+	}
+}
+
+func TestCompare_NonBreakingChanges(t *testing.T) {
+	next := baseFile()
+	// Adding fields and enum values is allowed
+	next.MessageType[0].Field = append(next.MessageType[0].Field, field("email", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	next.EnumType[0].Value = append(next.EnumType[0].Value, &descriptorpb.EnumValueDescriptorProto{
+		Name:   proto.String("PLAN_ENTERPRISE"),
+		Number: proto.Int32(3),
+	})
+	// Changing validation rules other than required is allowed
+	next.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(next.MessageType[0].Field[0].Options, validate.E_Field, &validate.FieldRules{
+		Type: &validate.FieldRules_String_{String_: &validate.StringRules{MinLen: proto.Uint64(5)}},
+	})
+
+	if got := Compare(buildFiles(t, baseFile()), buildFiles(t, next)); len(got) != 0 {
+		t.Errorf("Compare() = %v, want no violations", got)
+	}
+}
+
+func TestCheck_Levels(t *testing.T) {
+	next := baseFile()
+	next.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(next.MessageType[0].Field[0].Options, validate.E_Field, &validate.FieldRules{
+		Required: proto.Bool(true),
+	})
+	next.EnumType[0].Value = next.EnumType[0].Value[:2]
+
+	previousFiles, nextFiles := buildFiles(t, baseFile()), buildFiles(t, next)
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		bump       Bump
+		wantLevels map[Rule]Level
+		wantErrors bool
+	}{
+		{
+			name:       "patch bump rejects everything by default",
+			cfg:        DefaultConfig(),
+			bump:       BumpPatch,
+			wantLevels: map[Rule]Level{RuleRequiredTightened: LevelError, RuleEnumValueRemoved: LevelError},
+			wantErrors: true,
+		},
+		{
+			name:       "minor bump warns on required by default",
+			cfg:        DefaultConfig(),
+			bump:       BumpMinor,
+			wantLevels: map[Rule]Level{RuleRequiredTightened: LevelWarn, RuleEnumValueRemoved: LevelError},
+			wantErrors: true,
+		},
+		{
+			name: "rules can be turned off",
+			cfg: Config{
+				Minor: map[Rule]Level{RuleRequiredTightened: LevelWarn, RuleEnumValueRemoved: LevelOff},
+			},
+			bump:       BumpMinor,
+			wantLevels: map[Rule]Level{RuleRequiredTightened: LevelWarn},
+			wantErrors: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.cfg, tt.bump, previousFiles, nextFiles)
+			if len(got) != len(tt.wantLevels) {
+				t.Fatalf("Check() returned %d violations, want %d: %v", len(got), len(tt.wantLevels), got)
+			}
+			for _, v := range got {
+				if v.Level != tt.wantLevels[v.Rule] {
+					t.Errorf("%s level = %s, want %s", v.Rule, v.Level, tt.wantLevels[v.Rule])
+				}
+			}
+			if HasErrors(got) != tt.wantErrors {
+				t.Errorf("HasErrors() = %v, want %v", HasErrors(got), tt.wantErrors)
+			}
+		})
+	}
+}
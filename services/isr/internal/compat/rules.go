@@ -0,0 +1,121 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule identifies a kind of breaking change
+type Rule string
+
+const (
+	RuleMessageRemoved    Rule = "MESSAGE_REMOVED"
+	RuleFieldRemoved      Rule = "FIELD_REMOVED"
+	RuleFieldNumberReused Rule = "FIELD_NUMBER_REUSED"
+	RuleFieldTypeChanged  Rule = "FIELD_TYPE_CHANGED"
+	RuleEnumRemoved       Rule = "ENUM_REMOVED"
+	RuleEnumValueRemoved  Rule = "ENUM_VALUE_REMOVED"
+	RuleRequiredTightened Rule = "REQUIRED_TIGHTENED"
+)
+
+// AllRules lists every rule checked by Compare
+var AllRules = []Rule{
+	RuleMessageRemoved,
+	RuleFieldRemoved,
+	RuleFieldNumberReused,
+	RuleFieldTypeChanged,
+	RuleEnumRemoved,
+	RuleEnumValueRemoved,
+	RuleRequiredTightened,
+}
+
+// Level controls what happens when a rule is violated
+type Level string
+
+const (
+	LevelOff   Level = "off"   // Not reported
+	LevelWarn  Level = "warn"  // Reported, upload accepted
+	LevelError Level = "error" // Upload rejected
+)
+
+// Bump is the kind of version increment being checked
+type Bump string
+
+const (
+	BumpPatch Bump = "patch" // Same major.minor as the previous version
+	BumpMinor Bump = "minor" // Higher minor in the same major
+)
+
+// Config holds the rule levels for each kind of bump.
+// Rules missing from a map are treated as LevelError.
+type Config struct {
+	Patch map[Rule]Level
+	Minor map[Rule]Level
+}
+
+// DefaultConfig rejects every breaking change, except that a minor bump
+// may make fields required since clients opt in to a new minor explicitly
+func DefaultConfig() Config {
+	cfg := Config{
+		Patch: make(map[Rule]Level, len(AllRules)),
+		Minor: make(map[Rule]Level, len(AllRules)),
+	}
+	for _, rule := range AllRules {
+		cfg.Patch[rule] = LevelError
+		cfg.Minor[rule] = LevelError
+	}
+	cfg.Minor[RuleRequiredTightened] = LevelWarn
+	return cfg
+}
+
+// Level returns the configured level of rule for bump
+func (c Config) Level(bump Bump, rule Rule) Level {
+	levels := c.Patch
+	if bump == BumpMinor {
+		levels = c.Minor
+	}
+	if level, ok := levels[rule]; ok {
+		return level
+	}
+	return LevelError
+}
+
+// ParseLevels parses a comma-separated list of RULE=level pairs
+// (e.g. "REQUIRED_TIGHTENED=warn,FIELD_REMOVED=error") into levels, overriding existing entries
+func ParseLevels(spec string, levels map[Rule]Level) error {
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid rule level %q: expected RULE=level", pair)
+		}
+
+		rule := Rule(strings.ToUpper(strings.TrimSpace(name)))
+		if !isKnownRule(rule) {
+			return fmt.Errorf("unknown compatibility rule %q", name)
+		}
+
+		level := Level(strings.ToLower(strings.TrimSpace(value)))
+		switch level {
+		case LevelOff, LevelWarn, LevelError:
+		default:
+			return fmt.Errorf("invalid level %q for rule %s: expected off, warn or error", value, rule)
+		}
+
+		levels[rule] = level
+	}
+	return nil
+}
+
+func isKnownRule(rule Rule) bool {
+	for _, r := range AllRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
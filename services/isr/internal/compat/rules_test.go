@@ -0,0 +1,56 @@
+package compat
+
+import "testing"
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[Rule]Level
+		wantErr bool
+	}{
+		{
+			name: "empty spec keeps defaults",
+			spec: "",
+			want: map[Rule]Level{RuleFieldRemoved: LevelError},
+		},
+		{
+			name: "overrides are case-insensitive",
+			spec: "field_removed=WARN, REQUIRED_TIGHTENED=off",
+			want: map[Rule]Level{RuleFieldRemoved: LevelWarn, RuleRequiredTightened: LevelOff},
+		},
+		{
+			name:    "unknown rule",
+			spec:    "FIELD_RENAMED=warn",
+			wantErr: true,
+		},
+		{
+			name:    "unknown level",
+			spec:    "FIELD_REMOVED=fatal",
+			wantErr: true,
+		},
+		{
+			name:    "missing level",
+			spec:    "FIELD_REMOVED",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := map[Rule]Level{RuleFieldRemoved: LevelError}
+			err := ParseLevels(tt.spec, levels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for rule, want := range tt.want {
+				if levels[rule] != want {
+					t.Errorf("levels[%s] = %s, want %s", rule, levels[rule], want)
+				}
+			}
+		})
+	}
+}
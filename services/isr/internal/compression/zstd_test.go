@@ -0,0 +1,114 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/handler"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/memory"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// encodingRecorder records the Content-Encoding of every response
+type encodingRecorder struct {
+	mu        sync.Mutex
+	encodings []string
+}
+
+func (r *encodingRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		r.mu.Lock()
+		r.encodings = append(r.encodings, resp.Header.Get("Content-Encoding"))
+		r.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (r *encodingRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.encodings[len(r.encodings)-1]
+}
+
+// largeDescriptorSet returns descriptor.proto as a descriptor set, which is well above MinBytes
+func largeDescriptorSet(t *testing.T) []byte {
+	t.Helper()
+	fds := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		},
+	}
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+func TestWithZstd(t *testing.T) {
+	path, h := isrv1connect.NewSchemaRegistryServiceHandler(handler.NewSchemaHandler(memory.New()), WithZstd())
+	mux := http.NewServeMux()
+	mux.Handle(path, h)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	recorder := &encodingRecorder{}
+	httpClient := &http.Client{Transport: recorder}
+	ctx := context.Background()
+	schemaBinary := largeDescriptorSet(t)
+
+	// A zstd client uploads compressed and receives zstd responses
+	client := isrv1connect.NewSchemaRegistryServiceClient(httpClient, server.URL,
+		connect.WithAcceptCompression(Zstd, newDecompressor, newCompressor),
+		connect.WithSendCompression(Zstd),
+	)
+	if _, err := client.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.0.0",
+		SchemaBinary: schemaBinary,
+	})); err != nil {
+		t.Fatalf("UploadSchema() error = %v", err)
+	}
+	resp, err := client.GetSchemaByVersion(ctx, connect.NewRequest(&isrv1.GetSchemaByVersionRequest{Version: "1.0.0"}))
+	if err != nil {
+		t.Fatalf("GetSchemaByVersion() error = %v", err)
+	}
+	if !bytes.Equal(resp.Msg.SchemaBinary, schemaBinary) {
+		t.Error("GetSchemaByVersion() returned a different binary")
+	}
+	if got := recorder.last(); got != Zstd {
+		t.Errorf("Content-Encoding = %q, want %q", got, Zstd)
+	}
+	if stored := resp.Msg.Metadata.StoredSizeBytes; stored <= 0 || stored >= resp.Msg.Metadata.SizeBytes {
+		t.Errorf("StoredSizeBytes = %d, want between 0 and SizeBytes (%d)", stored, resp.Msg.Metadata.SizeBytes)
+	}
+
+	// Clients without zstd still get gzip
+	plain := isrv1connect.NewSchemaRegistryServiceClient(httpClient, server.URL)
+	if _, err := plain.GetSchemaByVersion(ctx, connect.NewRequest(&isrv1.GetSchemaByVersionRequest{Version: "1.0.0"})); err != nil {
+		t.Fatalf("GetSchemaByVersion() error = %v", err)
+	}
+	if got := recorder.last(); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+
+	// Small responses are not compressed
+	if _, err := client.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{
+		Major:        1,
+		KnownVersion: "1.0.0",
+	})); err != nil {
+		t.Fatalf("GetLatestPatch() error = %v", err)
+	}
+	if got := recorder.last(); got != "" {
+		t.Errorf("Content-Encoding of a not-modified response = %q, want none", got)
+	}
+}
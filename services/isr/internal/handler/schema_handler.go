@@ -2,33 +2,60 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/compat"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/rulediff"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/schemacheck"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// SchemaRepositoryInterface defines the repository methods used by the handler
-type SchemaRepositoryInterface interface {
-	Create(ctx context.Context, schema *model.Schema) error
-	GetByVersion(ctx context.Context, version string) (*model.Schema, error)
-	GetLatestPatch(ctx context.Context, major, minor int32) (*model.Schema, error)
-	VersionExists(ctx context.Context, version string) (bool, error)
-}
+// defaultPageSize is used by ListSchemas when the request leaves page_size unset
+const defaultPageSize = 50
+
+// resolvePageSize is the number of versions ResolveVersion reads per List call
+const resolvePageSize = 100
 
 type SchemaHandler struct {
-	repo SchemaRepositoryInterface
+	repo    storage.SchemaRepository
+	watches *watchHub
+	compat  compat.Config
 }
 
-func NewSchemaHandler(repo SchemaRepositoryInterface) *SchemaHandler {
-	return &SchemaHandler{repo: repo}
+// Option configures a SchemaHandler
+type Option func(*SchemaHandler)
+
+// WithCompatibilityConfig sets the rule levels used to check uploads against
+// the previous version. Defaults to compat.DefaultConfig().
+func WithCompatibilityConfig(cfg compat.Config) Option {
+	return func(h *SchemaHandler) {
+		h.compat = cfg
+	}
+}
+
+func NewSchemaHandler(repo storage.SchemaRepository, opts ...Option) *SchemaHandler {
+	h := &SchemaHandler{
+		repo:    repo,
+		watches: newWatchHub(),
+		compat:  compat.DefaultConfig(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *SchemaHandler) UploadSchema(
@@ -36,18 +63,31 @@ func (h *SchemaHandler) UploadSchema(
 	req *connect.Request[isrv1.UploadSchemaRequest],
 ) (*connect.Response[isrv1.UploadSchemaResponse], error) {
 	// Parse semantic version
-	major, minor, patch, err := version.ParseSemVer(req.Msg.Version)
+	semver, err := version.Parse(req.Msg.Version)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid version format: %w", err))
 	}
 
-	// Check if version already exists
-	exists, err := h.repo.VersionExists(ctx, req.Msg.Version)
+	// Reject descriptor sets that BE would fail to load
+	files, err := schemacheck.Check(req.Msg.SchemaBinary)
+	if err != nil {
+		return nil, invalidSchemaError(err)
+	}
+
+	// Check if version already exists. Build metadata does not make a new version.
+	// This only fails fast before the compatibility check; Create detects concurrent uploads.
+	exists, err := h.repo.VersionExists(ctx, semver.WithoutBuild())
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to check version: %w", err))
 	}
 	if exists {
-		return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("version %s already exists", req.Msg.Version))
+		return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("version %s already exists", semver.WithoutBuild()))
+	}
+
+	// Reject breaking changes against the previous version
+	warnings, err := h.checkCompatibility(ctx, semver.Major, semver.Minor, files)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate UUID v7
@@ -57,52 +97,114 @@ func (h *SchemaHandler) UploadSchema(
 	}
 
 	now := time.Now()
+	hash := sha256.Sum256(req.Msg.SchemaBinary)
 	schema := &model.Schema{
-		ID:           id.String(),
-		Version:      req.Msg.Version,
-		Major:        major,
-		Minor:        minor,
-		Patch:        patch,
-		SchemaBinary: req.Msg.SchemaBinary,
-		SizeBytes:    int32(len(req.Msg.SchemaBinary)),
-		CreatedAt:    now,
+		ID:            id.String(),
+		Version:       semver.WithoutBuild(),
+		Major:         semver.Major,
+		Minor:         semver.Minor,
+		Patch:         semver.Patch,
+		PreRelease:    semver.PreRelease,
+		BuildMetadata: semver.Build,
+		SchemaBinary:  req.Msg.SchemaBinary,
+		SizeBytes:     int32(len(req.Msg.SchemaBinary)),
+		ContentHash:   hex.EncodeToString(hash[:]),
+		Signature:     req.Msg.Signature, // Verified by BEs against their trusted keys, not by ISR
+		Status:        model.StatusActive,
+		CreatedAt:     now,
 	}
 
-	if err := h.repo.Create(ctx, schema); err != nil {
+	if err := h.repo.Create(ctx, schema, req.Msg.AllowOutOfOrder); err != nil {
+		var outOfOrder *storage.OutOfOrderError
+		switch {
+		case errors.Is(err, storage.ErrAlreadyExists):
+			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("version %s already exists", schema.Version))
+		case errors.As(err, &outOfOrder):
+			return nil, connect.NewError(connect.CodeFailedPrecondition,
+				fmt.Errorf("%w; set allow_out_of_order to upload it anyway", outOfOrder))
+		}
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to store schema: %w", err))
 	}
 
+	// Wake up WatchSchema streams for this major.minor
+	h.watches.notify(schema.Major, schema.Minor)
+
 	resp := &isrv1.UploadSchemaResponse{
-		Metadata: &isrv1.SchemaMetadata{
-			Id:        schema.ID,
-			Version:   schema.Version,
-			CreatedAt: timestamppb.New(schema.CreatedAt),
-			SizeBytes: schema.SizeBytes,
-		},
+		Metadata: toSchemaMetadata(schema),
+		Warnings: warnings,
 	}
 
 	return connect.NewResponse(resp), nil
 }
 
+// checkCompatibility compares files with the latest patch of the same major.minor,
+// or with the latest version of an earlier minor when this is the first patch of a new minor.
+// Pre-releases are never used as the base.
+// Violations at LevelError are returned as FailedPrecondition; the remaining ones are returned as warnings.
+func (h *SchemaHandler) checkCompatibility(
+	ctx context.Context,
+	major, minor int32,
+	files *protoregistry.Files,
+) ([]*isrv1.CompatibilityViolation, error) {
+	bump := compat.BumpPatch
+	base, err := h.repo.GetLatestPatch(ctx, major, minor, false)
+	if errors.Is(err, storage.ErrNotFound) {
+		bump = compat.BumpMinor
+		base, err = h.repo.GetLatestBeforeMinor(ctx, major, minor)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil // First version of this major; nothing to compare against
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get previous schema: %w", err))
+	}
+
+	baseFiles, err := schemacheck.Parse(base.SchemaBinary)
+	if err != nil {
+		// Stored before uploads were verified; don't block new versions on it
+		log.Printf("Skipping compatibility check against %s: %v", base.Version, err)
+		return nil, nil
+	}
+
+	violations := compat.Check(h.compat, bump, baseFiles, files)
+	if compat.HasErrors(violations) {
+		connectErr := connect.NewError(connect.CodeFailedPrecondition,
+			fmt.Errorf("breaking changes against %s are not allowed in a %s bump", base.Version, bump))
+		details := &isrv1.IncompatibleSchemaDetails{
+			BaseVersion: base.Version,
+			Violations:  toCompatibilityViolations(violations),
+		}
+		if detail, err := connect.NewErrorDetail(details); err == nil {
+			connectErr.AddDetail(detail)
+		}
+		return nil, connectErr
+	}
+
+	return toCompatibilityViolations(violations), nil
+}
+
 func (h *SchemaHandler) GetLatestPatch(
 	ctx context.Context,
 	req *connect.Request[isrv1.GetLatestPatchRequest],
 ) (*connect.Response[isrv1.GetLatestPatchResponse], error) {
-	schema, err := h.repo.GetLatestPatch(ctx, req.Msg.Major, req.Msg.Minor)
+	schema, err := h.repo.GetLatestPatch(ctx, req.Msg.Major, req.Msg.Minor, req.Msg.IncludePrerelease)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, storage.ErrNotFound) {
 			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema not found for version %d.%d", req.Msg.Major, req.Msg.Minor))
 		}
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
 	}
 
+	// The caller already has the latest patch; skip the binary
+	if req.Msg.KnownVersion != "" && version.StripBuild(req.Msg.KnownVersion) == schema.Version {
+		return connect.NewResponse(&isrv1.GetLatestPatchResponse{
+			Metadata:    toSchemaMetadata(schema),
+			NotModified: true,
+		}), nil
+	}
+
 	resp := &isrv1.GetLatestPatchResponse{
-		Metadata: &isrv1.SchemaMetadata{
-			Id:        schema.ID,
-			Version:   schema.Version,
-			CreatedAt: timestamppb.New(schema.CreatedAt),
-			SizeBytes: schema.SizeBytes,
-		},
+		Metadata:     toSchemaMetadata(schema),
 		SchemaBinary: schema.SchemaBinary,
 	}
 
@@ -113,23 +215,549 @@ func (h *SchemaHandler) GetSchemaByVersion(
 	ctx context.Context,
 	req *connect.Request[isrv1.GetSchemaByVersionRequest],
 ) (*connect.Response[isrv1.GetSchemaByVersionResponse], error) {
-	schema, err := h.repo.GetByVersion(ctx, req.Msg.Version)
+	schema, err := h.repo.GetByVersion(ctx, version.StripBuild(req.Msg.Version))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, storage.ErrNotFound) {
 			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema version %s not found", req.Msg.Version))
 		}
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
 	}
 
 	resp := &isrv1.GetSchemaByVersionResponse{
-		Metadata: &isrv1.SchemaMetadata{
-			Id:        schema.ID,
-			Version:   schema.Version,
-			CreatedAt: timestamppb.New(schema.CreatedAt),
-			SizeBytes: schema.SizeBytes,
+		Metadata:     toSchemaMetadata(schema),
+		SchemaBinary: schema.SchemaBinary,
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// GetSchemaByHash returns the schema binary with the given content hash, regardless of version or status
+func (h *SchemaHandler) GetSchemaByHash(
+	ctx context.Context,
+	req *connect.Request[isrv1.GetSchemaByHashRequest],
+) (*connect.Response[isrv1.GetSchemaByHashResponse], error) {
+	data, err := h.repo.GetBlob(ctx, req.Msg.ContentHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema with hash %s not found", req.Msg.ContentHash))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+
+	resp := &isrv1.GetSchemaByHashResponse{
+		ContentHash:  req.Msg.ContentHash,
+		SizeBytes:    int32(len(data)),
+		SchemaBinary: data,
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// ResolveVersion returns the highest non-yanked version matching a range such as "^1" or ">=1.2.3 <2"
+func (h *SchemaHandler) ResolveVersion(
+	ctx context.Context,
+	req *connect.Request[isrv1.ResolveVersionRequest],
+) (*connect.Response[isrv1.ResolveVersionResponse], error) {
+	constraint, err := version.ParseConstraint(req.Msg.Constraint)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	match, err := h.resolve(ctx, constraint, req.Msg.IncludePrerelease)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to resolve version: %w", err))
+	}
+	if match == nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no schema matches %q", constraint))
+	}
+
+	// The caller already has the highest match; skip the binary
+	if req.Msg.KnownVersion != "" && version.StripBuild(req.Msg.KnownVersion) == match.Version {
+		return connect.NewResponse(&isrv1.ResolveVersionResponse{
+			Latest: &isrv1.GetLatestPatchResponse{
+				Metadata:    toSchemaMetadata(match),
+				NotModified: true,
+			},
+		}), nil
+	}
+
+	// List does not load binaries
+	schema, err := h.repo.GetByVersion(ctx, match.Version)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+
+	return connect.NewResponse(&isrv1.ResolveVersionResponse{
+		Latest: &isrv1.GetLatestPatchResponse{
+			Metadata:     toSchemaMetadata(schema),
+			SchemaBinary: schema.SchemaBinary,
 		},
+	}), nil
+}
+
+// resolve walks the versions newest first and returns the first non-yanked one matching
+// constraint, or nil if there is none
+func (h *SchemaHandler) resolve(
+	ctx context.Context,
+	constraint version.Constraint,
+	includePreRelease bool,
+) (*model.Schema, error) {
+	filter := model.SchemaFilter{Limit: resolvePageSize}
+	for {
+		schemas, err := h.repo.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range schemas {
+			if s.Status == model.StatusYanked {
+				continue
+			}
+			v := version.SemVer{Major: s.Major, Minor: s.Minor, Patch: s.Patch, PreRelease: s.PreRelease}
+			if constraint.Matches(v, includePreRelease) {
+				return s, nil
+			}
+		}
+		if len(schemas) < resolvePageSize {
+			return nil, nil
+		}
+		last := schemas[len(schemas)-1]
+		filter.Before = &model.SchemaCursor{Major: last.Major, Minor: last.Minor, Patch: last.Patch, PreRelease: last.PreRelease}
+	}
+}
+
+// SetTag points a tag at an existing version, so environments following the tag
+// pick it up without a new upload. Yanked versions cannot be tagged.
+func (h *SchemaHandler) SetTag(
+	ctx context.Context,
+	req *connect.Request[isrv1.SetTagRequest],
+) (*connect.Response[isrv1.SetTagResponse], error) {
+	schema, err := h.repo.GetByVersion(ctx, version.StripBuild(req.Msg.Version))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema version %s not found", req.Msg.Version))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+	if schema.Status == model.StatusYanked {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("schema version %s is yanked", req.Msg.Version))
+	}
+
+	resp := &isrv1.SetTagResponse{
+		Metadata: toSchemaMetadata(schema),
+	}
+	previous, err := h.repo.GetByTag(ctx, req.Msg.Tag)
+	switch {
+	case err == nil:
+		resp.PreviousVersion = toSchemaMetadata(previous).Version
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get tag: %w", err))
+	}
+
+	tag := &model.SchemaTag{
+		Name:      req.Msg.Tag,
+		SchemaID:  schema.ID,
+		UpdatedAt: time.Now(),
+	}
+	if err := h.repo.SetTag(ctx, tag); err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to set tag: %w", err))
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// GetSchemaByTag returns the version a tag points to. A tagged version that was
+// yanked afterwards is still returned, with its status set.
+func (h *SchemaHandler) GetSchemaByTag(
+	ctx context.Context,
+	req *connect.Request[isrv1.GetSchemaByTagRequest],
+) (*connect.Response[isrv1.GetSchemaByTagResponse], error) {
+	schema, err := h.repo.GetByTag(ctx, req.Msg.Tag)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("tag %s not found", req.Msg.Tag))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+
+	latest := &isrv1.GetLatestPatchResponse{
+		Metadata:     toSchemaMetadata(schema),
 		SchemaBinary: schema.SchemaBinary,
 	}
+	// The caller already has the tagged version; skip the binary
+	if req.Msg.KnownVersion != "" && version.StripBuild(req.Msg.KnownVersion) == schema.Version {
+		latest.SchemaBinary = nil
+		latest.NotModified = true
+	}
+
+	return connect.NewResponse(&isrv1.GetSchemaByTagResponse{Latest: latest}), nil
+}
+
+// YankSchema withdraws a version. GetLatestPatch and WatchSchema fall back to the
+// previous patch, while GetSchemaByVersion still returns it for audit.
+func (h *SchemaHandler) YankSchema(
+	ctx context.Context,
+	req *connect.Request[isrv1.YankSchemaRequest],
+) (*connect.Response[isrv1.YankSchemaResponse], error) {
+	schema, err := h.setStatus(ctx, version.StripBuild(req.Msg.Version), model.StatusYanked, req.Msg.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Push the new latest patch to WatchSchema streams
+	h.watches.notify(schema.Major, schema.Minor)
+
+	return connect.NewResponse(&isrv1.YankSchemaResponse{
+		Metadata: toSchemaMetadata(schema),
+	}), nil
+}
+
+// DeprecateSchema marks a version as deprecated. It is still served as usual.
+func (h *SchemaHandler) DeprecateSchema(
+	ctx context.Context,
+	req *connect.Request[isrv1.DeprecateSchemaRequest],
+) (*connect.Response[isrv1.DeprecateSchemaResponse], error) {
+	schema, err := h.setStatus(ctx, version.StripBuild(req.Msg.Version), model.StatusDeprecated, req.Msg.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&isrv1.DeprecateSchemaResponse{
+		Metadata: toSchemaMetadata(schema),
+	}), nil
+}
+
+// setStatus changes the status of a version and returns the updated schema.
+// Yanking is final: a yanked version cannot be deprecated.
+func (h *SchemaHandler) setStatus(
+	ctx context.Context,
+	version string,
+	status model.SchemaStatus,
+	reason string,
+) (*model.Schema, error) {
+	schema, err := h.repo.GetByVersion(ctx, version)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema version %s not found", version))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+	if schema.Status == model.StatusYanked && status != model.StatusYanked {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("schema version %s is yanked", version))
+	}
+
+	if err := h.repo.UpdateStatus(ctx, version, status, reason); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema version %s not found", version))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update schema status: %w", err))
+	}
+
+	schema.Status = status
+	schema.StatusReason = reason
+	return schema, nil
+}
+
+// ListSchemas lists schema metadata, newest version first
+func (h *SchemaHandler) ListSchemas(
+	ctx context.Context,
+	req *connect.Request[isrv1.ListSchemasRequest],
+) (*connect.Response[isrv1.ListSchemasResponse], error) {
+	pageSize := int(req.Msg.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	filter := model.SchemaFilter{
+		Major: req.Msg.Major,
+		Minor: req.Msg.Minor,
+		Limit: pageSize + 1,
+	}
+	if req.Msg.CreatedAfter != nil {
+		t := req.Msg.CreatedAfter.AsTime()
+		filter.CreatedAfter = &t
+	}
+	if req.Msg.CreatedBefore != nil {
+		t := req.Msg.CreatedBefore.AsTime()
+		filter.CreatedBefore = &t
+	}
+	if req.Msg.PageToken != "" {
+		cursor, err := decodePageToken(req.Msg.PageToken)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		filter.Before = cursor
+	}
+
+	schemas, err := h.repo.List(ctx, filter)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list schemas: %w", err))
+	}
+
+	resp := &isrv1.ListSchemasResponse{}
+	if len(schemas) > pageSize {
+		schemas = schemas[:pageSize]
+		resp.NextPageToken = encodePageToken(schemas[pageSize-1])
+	}
+	for _, schema := range schemas {
+		resp.Schemas = append(resp.Schemas, toSchemaMetadata(schema))
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// ListVersions lists every published major.minor with its latest patch, newest first
+func (h *SchemaHandler) ListVersions(
+	ctx context.Context,
+	req *connect.Request[isrv1.ListVersionsRequest],
+) (*connect.Response[isrv1.ListVersionsResponse], error) {
+	series, err := h.repo.ListSeries(ctx, req.Msg.Major)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list versions: %w", err))
+	}
+
+	resp := &isrv1.ListVersionsResponse{}
+	for _, s := range series {
+		resp.Series = append(resp.Series, &isrv1.SchemaSeries{
+			Major:         s.Major,
+			Minor:         s.Minor,
+			LatestVersion: fmt.Sprintf("%d.%d.%d", s.Major, s.Minor, s.LatestPatch),
+			PatchCount:    s.PatchCount,
+		})
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+// DiffSchemas reports the protovalidate constraints that differ between two versions
+func (h *SchemaHandler) DiffSchemas(
+	ctx context.Context,
+	req *connect.Request[isrv1.DiffSchemasRequest],
+) (*connect.Response[isrv1.DiffSchemasResponse], error) {
+	from, err := h.loadFiles(ctx, version.StripBuild(req.Msg.FromVersion))
+	if err != nil {
+		return nil, err
+	}
+	to, err := h.loadFiles(ctx, version.StripBuild(req.Msg.ToVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := rulediff.Diff(from, to)
+	resp := &isrv1.DiffSchemasResponse{
+		Summary: rulediff.Summary(changes),
+	}
+	for _, c := range changes {
+		resp.Changes = append(resp.Changes, &isrv1.RuleChange{
+			Element:   c.Element,
+			Rule:      c.Rule,
+			Type:      toRuleChangeType(c.Type),
+			FromValue: c.From,
+			ToValue:   c.To,
+		})
+	}
 
 	return connect.NewResponse(resp), nil
 }
+
+// loadFiles fetches a stored version and builds its descriptors
+func (h *SchemaHandler) loadFiles(ctx context.Context, version string) (*protoregistry.Files, error) {
+	schema, err := h.repo.GetByVersion(ctx, version)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("schema version %s not found", version))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+	}
+
+	files, err := schemacheck.Parse(schema.SchemaBinary)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to parse schema %s: %w", version, err))
+	}
+	return files, nil
+}
+
+// WatchSchema streams the latest patch for the requested major.minor.
+// The current latest patch is sent as soon as the stream opens (if one exists),
+// followed by every change of the latest patch: a newer patch committed by
+// UploadSchema, or the previous patch when the latest one is yanked.
+func (h *SchemaHandler) WatchSchema(
+	ctx context.Context,
+	req *connect.Request[isrv1.WatchSchemaRequest],
+	stream *connect.ServerStream[isrv1.WatchSchemaResponse],
+) error {
+	// Subscribe before reading the current latest patch so no upload is missed
+	notifyCh, unsubscribe := h.watches.subscribe(req.Msg.Major, req.Msg.Minor)
+	defer unsubscribe()
+
+	// Send response headers right away; clients block until they arrive,
+	// and the first schema may not be uploaded for a long time
+	if err := stream.Send(nil); err != nil {
+		return err
+	}
+
+	var sentID string
+	sendLatest := func() error {
+		schema, err := h.repo.GetLatestPatch(ctx, req.Msg.Major, req.Msg.Minor, req.Msg.IncludePrerelease)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil // Nothing uploaded yet; wait for the first upload
+			}
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("failed to get schema: %w", err))
+		}
+		if schema.ID == sentID {
+			return nil // This stream already has it
+		}
+
+		resp := &isrv1.WatchSchemaResponse{
+			Latest: &isrv1.GetLatestPatchResponse{
+				Metadata:     toSchemaMetadata(schema),
+				SchemaBinary: schema.SchemaBinary,
+			},
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		sentID = schema.ID
+		return nil
+	}
+
+	if err := sendLatest(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-notifyCh:
+			if err := sendLatest(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// followRetryDelay is how long FollowChanges waits before subscribing again after a failure
+const followRetryDelay = 5 * time.Second
+
+// FollowChanges wakes WatchSchema streams for changes committed through any handler or ISR replica
+// sharing the repository; without it, streams only see changes made by this handler.
+// It blocks until ctx is done and resubscribes after failures. Changes may be missed while
+// unsubscribed, so every stream re-reads its latest patch once the subscription is active.
+func (h *SchemaHandler) FollowChanges(ctx context.Context, notifier storage.ChangeNotifier) {
+	for {
+		err := notifier.Listen(ctx, h.watches.notifyAll, h.watches.notify)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Lost subscription to schema changes, retrying in %s: %v", followRetryDelay, err)
+
+		select {
+		case <-time.After(followRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// toSchemaMetadata converts a stored schema to its API metadata
+func toSchemaMetadata(schema *model.Schema) *isrv1.SchemaMetadata {
+	fullVersion := schema.Version
+	if schema.BuildMetadata != "" {
+		fullVersion += "+" + schema.BuildMetadata
+	}
+	return &isrv1.SchemaMetadata{
+		Id:              schema.ID,
+		Version:         fullVersion,
+		CreatedAt:       timestamppb.New(schema.CreatedAt),
+		SizeBytes:       schema.SizeBytes,
+		StoredSizeBytes: schema.StoredSizeBytes,
+		ContentHash:     schema.ContentHash,
+		Signature:       schema.Signature,
+		Status:          toSchemaStatus(schema.Status),
+		StatusReason:    schema.StatusReason,
+	}
+}
+
+// encodePageToken returns an opaque token that continues a listing after schema
+func encodePageToken(schema *model.Schema) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(schema.Version))
+}
+
+// decodePageToken parses a token produced by encodePageToken
+func decodePageToken(token string) (*model.SchemaCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	v, err := version.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	return &model.SchemaCursor{Major: v.Major, Minor: v.Minor, Patch: v.Patch, PreRelease: v.PreRelease}, nil
+}
+
+// toCompatibilityViolations converts compat violations to their API representation
+func toCompatibilityViolations(violations []compat.Violation) []*isrv1.CompatibilityViolation {
+	var out []*isrv1.CompatibilityViolation
+	for _, v := range violations {
+		out = append(out, &isrv1.CompatibilityViolation{
+			Rule:        string(v.Rule),
+			Level:       string(v.Level),
+			Element:     v.Element,
+			Description: v.Description,
+		})
+	}
+	return out
+}
+
+func toSchemaStatus(status model.SchemaStatus) isrv1.SchemaStatus {
+	switch status {
+	case model.StatusActive:
+		return isrv1.SchemaStatus_SCHEMA_STATUS_ACTIVE
+	case model.StatusDeprecated:
+		return isrv1.SchemaStatus_SCHEMA_STATUS_DEPRECATED
+	case model.StatusYanked:
+		return isrv1.SchemaStatus_SCHEMA_STATUS_YANKED
+	default:
+		return isrv1.SchemaStatus_SCHEMA_STATUS_UNSPECIFIED
+	}
+}
+
+func toRuleChangeType(t rulediff.ChangeType) isrv1.RuleChangeType {
+	switch t {
+	case rulediff.ChangeAdded:
+		return isrv1.RuleChangeType_RULE_CHANGE_TYPE_ADDED
+	case rulediff.ChangeRemoved:
+		return isrv1.RuleChangeType_RULE_CHANGE_TYPE_REMOVED
+	case rulediff.ChangeChanged:
+		return isrv1.RuleChangeType_RULE_CHANGE_TYPE_CHANGED
+	default:
+		return isrv1.RuleChangeType_RULE_CHANGE_TYPE_UNSPECIFIED
+	}
+}
+
+// invalidSchemaError converts a schemacheck error into InvalidArgument,
+// attaching each problem as InvalidSchemaDetails
+func invalidSchemaError(err error) *connect.Error {
+	connectErr := connect.NewError(connect.CodeInvalidArgument, err)
+
+	var checkErr *schemacheck.Error
+	if !errors.As(err, &checkErr) {
+		return connectErr
+	}
+
+	details := &isrv1.InvalidSchemaDetails{}
+	for _, p := range checkErr.Problems {
+		details.Errors = append(details.Errors, &isrv1.SchemaError{
+			File:        p.File,
+			Element:     p.Element,
+			Description: p.Description,
+		})
+	}
+	if detail, err := connect.NewErrorDetail(details); err == nil {
+		connectErr.AddDetail(detail)
+	}
+	return connectErr
+}
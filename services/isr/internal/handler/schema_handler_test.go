@@ -1,28 +1,83 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"connectrpc.com/connect"
-	"github.com/jackc/pgx/v5"
 	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/compat"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/memory"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// mockSchemaRepository is a mock implementation of SchemaRepositoryInterface
+// createTestDescriptorSet builds a FileDescriptorSet from the isr.v1 package
+// and its transitive imports, dependencies first
+func createTestDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if visited[fd.Path()] {
+			return
+		}
+		visited[fd.Path()] = true
+
+		for i := 0; i < fd.Imports().Len(); i++ {
+			addFile(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	addFile(isrv1.File_isr_v1_isr_proto)
+
+	return fds
+}
+
+// createTestDescriptorBytes returns the serialized form of createTestDescriptorSet
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := proto.Marshal(createTestDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+// mockSchemaRepository is a mock implementation of storage.SchemaRepository
 type mockSchemaRepository struct {
-	createFunc         func(ctx context.Context, schema *model.Schema) error
-	getByVersionFunc   func(ctx context.Context, version string) (*model.Schema, error)
-	getLatestPatchFunc func(ctx context.Context, major, minor int32) (*model.Schema, error)
-	versionExistsFunc  func(ctx context.Context, version string) (bool, error)
+	createFunc          func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error
+	getByVersionFunc    func(ctx context.Context, version string) (*model.Schema, error)
+	getLatestPatchFunc  func(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error)
+	getLatestBeforeFunc func(ctx context.Context, major, minor int32) (*model.Schema, error)
+	versionExistsFunc   func(ctx context.Context, version string) (bool, error)
+	listFunc            func(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error)
+	listSeriesFunc      func(ctx context.Context, major *int32) ([]*model.SchemaSeries, error)
+	updateStatusFunc    func(ctx context.Context, version string, status model.SchemaStatus, reason string) error
+	setTagFunc          func(ctx context.Context, tag *model.SchemaTag) error
+	getByTagFunc        func(ctx context.Context, tag string) (*model.Schema, error)
+	getBlobFunc         func(ctx context.Context, hash string) ([]byte, error)
 }
 
-func (m *mockSchemaRepository) Create(ctx context.Context, schema *model.Schema) error {
+func (m *mockSchemaRepository) Create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
 	if m.createFunc != nil {
-		return m.createFunc(ctx, schema)
+		return m.createFunc(ctx, schema, allowOutOfOrder)
 	}
 	return nil
 }
@@ -34,11 +89,18 @@ func (m *mockSchemaRepository) GetByVersion(ctx context.Context, version string)
 	return nil, nil
 }
 
-func (m *mockSchemaRepository) GetLatestPatch(ctx context.Context, major, minor int32) (*model.Schema, error) {
+func (m *mockSchemaRepository) GetLatestPatch(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
 	if m.getLatestPatchFunc != nil {
-		return m.getLatestPatchFunc(ctx, major, minor)
+		return m.getLatestPatchFunc(ctx, major, minor, includePreRelease)
 	}
-	return nil, nil
+	return nil, storage.ErrNotFound
+}
+
+func (m *mockSchemaRepository) GetLatestBeforeMinor(ctx context.Context, major, minor int32) (*model.Schema, error) {
+	if m.getLatestBeforeFunc != nil {
+		return m.getLatestBeforeFunc(ctx, major, minor)
+	}
+	return nil, storage.ErrNotFound
 }
 
 func (m *mockSchemaRepository) VersionExists(ctx context.Context, version string) (bool, error) {
@@ -48,21 +110,64 @@ func (m *mockSchemaRepository) VersionExists(ctx context.Context, version string
 	return false, nil
 }
 
+func (m *mockSchemaRepository) List(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, filter)
+	}
+	return nil, nil
+}
+
+func (m *mockSchemaRepository) ListSeries(ctx context.Context, major *int32) ([]*model.SchemaSeries, error) {
+	if m.listSeriesFunc != nil {
+		return m.listSeriesFunc(ctx, major)
+	}
+	return nil, nil
+}
+
+func (m *mockSchemaRepository) UpdateStatus(ctx context.Context, version string, status model.SchemaStatus, reason string) error {
+	if m.updateStatusFunc != nil {
+		return m.updateStatusFunc(ctx, version, status, reason)
+	}
+	return nil
+}
+
+func (m *mockSchemaRepository) SetTag(ctx context.Context, tag *model.SchemaTag) error {
+	if m.setTagFunc != nil {
+		return m.setTagFunc(ctx, tag)
+	}
+	return nil
+}
+
+func (m *mockSchemaRepository) GetByTag(ctx context.Context, tag string) (*model.Schema, error) {
+	if m.getByTagFunc != nil {
+		return m.getByTagFunc(ctx, tag)
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (m *mockSchemaRepository) GetBlob(ctx context.Context, hash string) ([]byte, error) {
+	if m.getBlobFunc != nil {
+		return m.getBlobFunc(ctx, hash)
+	}
+	return nil, storage.ErrNotFound
+}
+
 func TestSchemaHandler_UploadSchema_Success(t *testing.T) {
 	mockRepo := &mockSchemaRepository{
 		versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
 			return false, nil
 		},
-		createFunc: func(ctx context.Context, schema *model.Schema) error {
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
 			return nil
 		},
 	}
 
 	handler := NewSchemaHandler(mockRepo)
+	schemaBinary := createTestDescriptorBytes(t)
 
 	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
 		Version:      "1.2.3",
-		SchemaBinary: []byte("test schema data"),
+		SchemaBinary: schemaBinary,
 	})
 
 	resp, err := handler.UploadSchema(context.Background(), req)
@@ -73,8 +178,8 @@ func TestSchemaHandler_UploadSchema_Success(t *testing.T) {
 	if resp.Msg.Metadata.Version != "1.2.3" {
 		t.Errorf("Version = %v, want 1.2.3", resp.Msg.Metadata.Version)
 	}
-	if resp.Msg.Metadata.SizeBytes != int32(len("test schema data")) {
-		t.Errorf("SizeBytes = %v, want %v", resp.Msg.Metadata.SizeBytes, len("test schema data"))
+	if resp.Msg.Metadata.SizeBytes != int32(len(schemaBinary)) {
+		t.Errorf("SizeBytes = %v, want %v", resp.Msg.Metadata.SizeBytes, len(schemaBinary))
 	}
 	if resp.Msg.Metadata.Id == "" {
 		t.Errorf("Id is empty, want non-empty value")
@@ -121,7 +226,7 @@ func TestSchemaHandler_UploadSchema_AlreadyExists(t *testing.T) {
 
 	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
 		Version:      "1.2.3",
-		SchemaBinary: []byte("test"),
+		SchemaBinary: createTestDescriptorBytes(t),
 	})
 
 	_, err := handler.UploadSchema(context.Background(), req)
@@ -150,7 +255,7 @@ func TestSchemaHandler_UploadSchema_RepositoryError(t *testing.T) {
 
 	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
 		Version:      "1.2.3",
-		SchemaBinary: []byte("test"),
+		SchemaBinary: createTestDescriptorBytes(t),
 	})
 
 	_, err := handler.UploadSchema(context.Background(), req)
@@ -173,7 +278,7 @@ func TestSchemaHandler_UploadSchema_CreateError(t *testing.T) {
 		versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
 			return false, nil
 		},
-		createFunc: func(ctx context.Context, schema *model.Schema) error {
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
 			return errors.New("database error")
 		},
 	}
@@ -182,7 +287,7 @@ func TestSchemaHandler_UploadSchema_CreateError(t *testing.T) {
 
 	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
 		Version:      "1.2.3",
-		SchemaBinary: []byte("test"),
+		SchemaBinary: createTestDescriptorBytes(t),
 	})
 
 	_, err := handler.UploadSchema(context.Background(), req)
@@ -200,6 +305,65 @@ func TestSchemaHandler_UploadSchema_CreateError(t *testing.T) {
 	}
 }
 
+func TestSchemaHandler_UploadSchema_CreateConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    bool
+		err      error
+		wantCode connect.Code
+	}{
+		{
+			// Another upload of the same version won the race after VersionExists
+			name:     "concurrent upload",
+			err:      fmt.Errorf("failed to insert schema: %w", storage.ErrAlreadyExists),
+			wantCode: connect.CodeAlreadyExists,
+		},
+		{
+			name:     "out of order",
+			err:      fmt.Errorf("failed to insert schema: %w", &storage.OutOfOrderError{Version: "1.2.3", Latest: "1.2.5"}),
+			wantCode: connect.CodeFailedPrecondition,
+		},
+		{
+			name:  "out of order allowed",
+			allow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAllow bool
+			mockRepo := &mockSchemaRepository{
+				versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
+					return false, nil
+				},
+				createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+					gotAllow = allowOutOfOrder
+					return tt.err
+				},
+			}
+			handler := NewSchemaHandler(mockRepo)
+
+			_, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+				Version:         "1.2.3",
+				SchemaBinary:    createTestDescriptorBytes(t),
+				AllowOutOfOrder: tt.allow,
+			}))
+			if gotAllow != tt.allow {
+				t.Errorf("Create allowOutOfOrder = %v, want %v", gotAllow, tt.allow)
+			}
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("UploadSchema() error = %v, want nil", err)
+				}
+				return
+			}
+			if connect.CodeOf(err) != tt.wantCode {
+				t.Errorf("error code = %v, want %v (%v)", connect.CodeOf(err), tt.wantCode, err)
+			}
+		})
+	}
+}
+
 func TestSchemaHandler_GetLatestPatch_Success(t *testing.T) {
 	expectedSchema := &model.Schema{
 		ID:           "test-id",
@@ -213,11 +377,11 @@ func TestSchemaHandler_GetLatestPatch_Success(t *testing.T) {
 	}
 
 	mockRepo := &mockSchemaRepository{
-		getLatestPatchFunc: func(ctx context.Context, major, minor int32) (*model.Schema, error) {
+		getLatestPatchFunc: func(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
 			if major == 1 && minor == 2 {
 				return expectedSchema, nil
 			}
-			return nil, pgx.ErrNoRows
+			return nil, storage.ErrNotFound
 		},
 	}
 
@@ -252,8 +416,8 @@ func TestSchemaHandler_GetLatestPatch_Success(t *testing.T) {
 
 func TestSchemaHandler_GetLatestPatch_NotFound(t *testing.T) {
 	mockRepo := &mockSchemaRepository{
-		getLatestPatchFunc: func(ctx context.Context, major, minor int32) (*model.Schema, error) {
-			return nil, pgx.ErrNoRows
+		getLatestPatchFunc: func(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
+			return nil, storage.ErrNotFound
 		},
 	}
 
@@ -281,7 +445,7 @@ func TestSchemaHandler_GetLatestPatch_NotFound(t *testing.T) {
 
 func TestSchemaHandler_GetLatestPatch_InternalError(t *testing.T) {
 	mockRepo := &mockSchemaRepository{
-		getLatestPatchFunc: func(ctx context.Context, major, minor int32) (*model.Schema, error) {
+		getLatestPatchFunc: func(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
 			return nil, errors.New("database connection failed")
 		},
 	}
@@ -325,7 +489,7 @@ func TestSchemaHandler_GetSchemaByVersion_Success(t *testing.T) {
 			if version == "2.0.1" {
 				return expectedSchema, nil
 			}
-			return nil, pgx.ErrNoRows
+			return nil, storage.ErrNotFound
 		},
 	}
 
@@ -360,7 +524,7 @@ func TestSchemaHandler_GetSchemaByVersion_Success(t *testing.T) {
 func TestSchemaHandler_GetSchemaByVersion_NotFound(t *testing.T) {
 	mockRepo := &mockSchemaRepository{
 		getByVersionFunc: func(ctx context.Context, version string) (*model.Schema, error) {
-			return nil, pgx.ErrNoRows
+			return nil, storage.ErrNotFound
 		},
 	}
 
@@ -412,3 +576,923 @@ func TestSchemaHandler_GetSchemaByVersion_InternalError(t *testing.T) {
 		t.Errorf("error code = %v, want %v", connectErr.Code(), connect.CodeInternal)
 	}
 }
+
+func TestSchemaHandler_UploadSchema_ContentHash(t *testing.T) {
+	var stored *model.Schema
+	mockRepo := &mockSchemaRepository{
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+			stored = schema
+			return nil
+		},
+	}
+
+	handler := NewSchemaHandler(mockRepo)
+	schemaBinary := createTestDescriptorBytes(t)
+
+	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.2.3",
+		SchemaBinary: schemaBinary,
+	})
+
+	resp, err := handler.UploadSchema(context.Background(), req)
+	if err != nil {
+		t.Fatalf("UploadSchema() error = %v, want nil", err)
+	}
+
+	sum := sha256.Sum256(schemaBinary)
+	wantHash := hex.EncodeToString(sum[:])
+	if resp.Msg.Metadata.ContentHash != wantHash {
+		t.Errorf("ContentHash = %v, want %v", resp.Msg.Metadata.ContentHash, wantHash)
+	}
+	if stored == nil || stored.ContentHash != wantHash {
+		t.Errorf("stored schema ContentHash does not match %v", wantHash)
+	}
+}
+
+func TestSchemaHandler_UploadSchema_Signature(t *testing.T) {
+	var stored *model.Schema
+	mockRepo := &mockSchemaRepository{
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+			stored = schema
+			return nil
+		},
+	}
+
+	handler := NewSchemaHandler(mockRepo)
+	signature := bytes.Repeat([]byte{0x5a}, 64)
+
+	resp, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.2.3",
+		SchemaBinary: createTestDescriptorBytes(t),
+		Signature:    signature,
+	}))
+	if err != nil {
+		t.Fatalf("UploadSchema() error = %v, want nil", err)
+	}
+
+	// ISR does not hold the signing keys; the signature is stored and served as is
+	if stored == nil || !bytes.Equal(stored.Signature, signature) {
+		t.Errorf("stored Signature = %x, want %x", stored.Signature, signature)
+	}
+	if !bytes.Equal(resp.Msg.Metadata.Signature, signature) {
+		t.Errorf("Metadata.Signature = %x, want %x", resp.Msg.Metadata.Signature, signature)
+	}
+}
+
+func TestSchemaHandler_GetSchemaByHash(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+	schemaBinary := createTestDescriptorBytes(t)
+
+	// Identical binaries uploaded under two versions share one hash
+	var hashes []string
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		resp, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      v,
+			SchemaBinary: schemaBinary,
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", v, err)
+		}
+		hashes = append(hashes, resp.Msg.Metadata.ContentHash)
+	}
+	if hashes[0] != hashes[1] {
+		t.Fatalf("ContentHash = %v and %v, want identical", hashes[0], hashes[1])
+	}
+
+	resp, err := handler.GetSchemaByHash(ctx, connect.NewRequest(&isrv1.GetSchemaByHashRequest{
+		ContentHash: hashes[0],
+	}))
+	if err != nil {
+		t.Fatalf("GetSchemaByHash() error = %v, want nil", err)
+	}
+	if !bytes.Equal(resp.Msg.SchemaBinary, schemaBinary) {
+		t.Errorf("SchemaBinary does not match the uploaded binary")
+	}
+	if resp.Msg.ContentHash != hashes[0] || resp.Msg.SizeBytes != int32(len(schemaBinary)) {
+		t.Errorf("GetSchemaByHash() = (%v, %d), want (%v, %d)",
+			resp.Msg.ContentHash, resp.Msg.SizeBytes, hashes[0], len(schemaBinary))
+	}
+
+	_, err = handler.GetSchemaByHash(ctx, connect.NewRequest(&isrv1.GetSchemaByHashRequest{
+		ContentHash: strings.Repeat("0", 64),
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("GetSchemaByHash(unknown) code = %v, want %v", connect.CodeOf(err), connect.CodeNotFound)
+	}
+}
+
+func TestSchemaHandler_GetLatestPatch_NotModified(t *testing.T) {
+	mockRepo := &mockSchemaRepository{
+		getLatestPatchFunc: func(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
+			return &model.Schema{
+				ID:           "test-id",
+				Version:      "1.2.5",
+				Major:        1,
+				Minor:        2,
+				Patch:        5,
+				SchemaBinary: []byte("test schema"),
+				SizeBytes:    11,
+				ContentHash:  "hash-1.2.5",
+				CreatedAt:    time.Now(),
+			}, nil
+		},
+	}
+
+	handler := NewSchemaHandler(mockRepo)
+
+	tests := []struct {
+		name            string
+		knownVersion    string
+		wantNotModified bool
+	}{
+		{name: "no known version", knownVersion: "", wantNotModified: false},
+		{name: "outdated known version", knownVersion: "1.2.4", wantNotModified: false},
+		{name: "latest known version", knownVersion: "1.2.5", wantNotModified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := connect.NewRequest(&isrv1.GetLatestPatchRequest{
+				Major:        1,
+				Minor:        2,
+				KnownVersion: tt.knownVersion,
+			})
+
+			resp, err := handler.GetLatestPatch(context.Background(), req)
+			if err != nil {
+				t.Fatalf("GetLatestPatch() error = %v, want nil", err)
+			}
+
+			if resp.Msg.NotModified != tt.wantNotModified {
+				t.Errorf("NotModified = %v, want %v", resp.Msg.NotModified, tt.wantNotModified)
+			}
+			if tt.wantNotModified && len(resp.Msg.SchemaBinary) != 0 {
+				t.Errorf("SchemaBinary length = %d, want 0", len(resp.Msg.SchemaBinary))
+			}
+			if !tt.wantNotModified && string(resp.Msg.SchemaBinary) != "test schema" {
+				t.Errorf("SchemaBinary = %v, want 'test schema'", string(resp.Msg.SchemaBinary))
+			}
+			if resp.Msg.Metadata.Version != "1.2.5" {
+				t.Errorf("Version = %v, want 1.2.5", resp.Msg.Metadata.Version)
+			}
+			if resp.Msg.Metadata.ContentHash != "hash-1.2.5" {
+				t.Errorf("ContentHash = %v, want hash-1.2.5", resp.Msg.Metadata.ContentHash)
+			}
+		})
+	}
+}
+
+// modifiedDescriptorBytes returns the test descriptor set with isr/v1/isr.proto changed by modify
+func modifiedDescriptorBytes(t *testing.T, modify func(file *descriptorpb.FileDescriptorProto)) []byte {
+	t.Helper()
+
+	fds := createTestDescriptorSet(t)
+	modify(fds.File[len(fds.File)-1])
+
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+// findMessage returns the top-level message with the given name
+func findMessage(t *testing.T, file *descriptorpb.FileDescriptorProto, name string) *descriptorpb.DescriptorProto {
+	t.Helper()
+
+	for _, msg := range file.MessageType {
+		if msg.GetName() == name {
+			return msg
+		}
+	}
+	t.Fatalf("message %s not found in %s", name, file.GetName())
+	return nil
+}
+
+// removeField deletes the named field from msg
+func removeField(msg *descriptorpb.DescriptorProto, name string) {
+	for i, field := range msg.Field {
+		if field.GetName() == name {
+			msg.Field = append(msg.Field[:i], msg.Field[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestSchemaHandler_UploadSchema_Compatibility(t *testing.T) {
+	removeField := modifiedDescriptorBytes(t, func(file *descriptorpb.FileDescriptorProto) {
+		removeField(findMessage(t, file, "SchemaMetadata"), "content_hash")
+	})
+	requireField := modifiedDescriptorBytes(t, func(file *descriptorpb.FileDescriptorProto) {
+		field := findMessage(t, file, "GetLatestPatchRequest").Field[2] // known_version
+		field.Options = &descriptorpb.FieldOptions{}
+		proto.SetExtension(field.Options, validate.E_Field, &validate.FieldRules{Required: proto.Bool(true)})
+	})
+
+	tests := []struct {
+		name           string
+		version        string
+		binary         []byte
+		wantCode       connect.Code // 0 means success
+		wantBase       string
+		wantViolations []string // rule:element
+	}{
+		{
+			name:     "unchanged patch",
+			version:  "1.0.1",
+			binary:   createTestDescriptorBytes(t),
+			wantBase: "1.0.0",
+		},
+		{
+			name:           "field removed in patch",
+			version:        "1.0.1",
+			binary:         removeField,
+			wantCode:       connect.CodeFailedPrecondition,
+			wantBase:       "1.0.0",
+			wantViolations: []string{"FIELD_REMOVED:isr.v1.SchemaMetadata.content_hash"},
+		},
+		{
+			name:           "required added in patch",
+			version:        "1.0.1",
+			binary:         requireField,
+			wantCode:       connect.CodeFailedPrecondition,
+			wantBase:       "1.0.0",
+			wantViolations: []string{"REQUIRED_TIGHTENED:isr.v1.GetLatestPatchRequest.known_version"},
+		},
+		{
+			name:           "required added in minor is a warning",
+			version:        "1.1.0",
+			binary:         requireField,
+			wantBase:       "1.0.0",
+			wantViolations: []string{"REQUIRED_TIGHTENED:isr.v1.GetLatestPatchRequest.known_version"},
+		},
+		{
+			name:    "new major is not compared",
+			version: "2.0.0",
+			binary:  removeField,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.New()
+			handler := NewSchemaHandler(repo)
+
+			_, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+				Version:      "1.0.0",
+				SchemaBinary: createTestDescriptorBytes(t),
+			}))
+			if err != nil {
+				t.Fatalf("UploadSchema(1.0.0) error = %v", err)
+			}
+
+			resp, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+				Version:      tt.version,
+				SchemaBinary: tt.binary,
+			}))
+
+			var got []*isrv1.CompatibilityViolation
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("UploadSchema(%s) error = %v, want nil", tt.version, err)
+				}
+				got = resp.Msg.Warnings
+			} else {
+				var connectErr *connect.Error
+				if !errors.As(err, &connectErr) {
+					t.Fatalf("UploadSchema(%s) error = %v, want *connect.Error", tt.version, err)
+				}
+				if connectErr.Code() != tt.wantCode {
+					t.Fatalf("error code = %v, want %v", connectErr.Code(), tt.wantCode)
+				}
+
+				var details *isrv1.IncompatibleSchemaDetails
+				for _, detail := range connectErr.Details() {
+					if value, err := detail.Value(); err == nil {
+						if d, ok := value.(*isrv1.IncompatibleSchemaDetails); ok {
+							details = d
+						}
+					}
+				}
+				if details == nil {
+					t.Fatal("IncompatibleSchemaDetails not attached to error")
+				}
+				if details.BaseVersion != tt.wantBase {
+					t.Errorf("BaseVersion = %s, want %s", details.BaseVersion, tt.wantBase)
+				}
+				got = details.Violations
+			}
+
+			if len(got) != len(tt.wantViolations) {
+				t.Fatalf("got %d violations, want %d: %v", len(got), len(tt.wantViolations), got)
+			}
+			for i, v := range got {
+				if key := v.Rule + ":" + v.Element; key != tt.wantViolations[i] {
+					t.Errorf("violation[%d] = %s, want %s", i, key, tt.wantViolations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaHandler_UploadSchema_CompatibilityConfig(t *testing.T) {
+	cfg := compat.DefaultConfig()
+	cfg.Patch[compat.RuleFieldRemoved] = compat.LevelWarn
+
+	repo := memory.New()
+	handler := NewSchemaHandler(repo, WithCompatibilityConfig(cfg))
+
+	_, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.0.0",
+		SchemaBinary: createTestDescriptorBytes(t),
+	}))
+	if err != nil {
+		t.Fatalf("UploadSchema(1.0.0) error = %v", err)
+	}
+
+	resp, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version: "1.0.1",
+		SchemaBinary: modifiedDescriptorBytes(t, func(file *descriptorpb.FileDescriptorProto) {
+			removeField(findMessage(t, file, "SchemaMetadata"), "content_hash")
+		}),
+	}))
+	if err != nil {
+		t.Fatalf("UploadSchema(1.0.1) error = %v, want nil", err)
+	}
+	if len(resp.Msg.Warnings) != 1 || resp.Msg.Warnings[0].Level != "warn" {
+		t.Errorf("Warnings = %v, want one FIELD_REMOVED warning", resp.Msg.Warnings)
+	}
+}
+
+func TestSchemaHandler_DiffSchemas(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	uploads := map[string][]byte{
+		"1.0.0": createTestDescriptorBytes(t),
+		"1.0.1": modifiedDescriptorBytes(t, func(file *descriptorpb.FileDescriptorProto) {
+			field := findMessage(t, file, "GetLatestPatchRequest").Field[2] // known_version
+			field.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(field.Options, validate.E_Field, &validate.FieldRules{
+				Type: &validate.FieldRules_String_{String_: &validate.StringRules{MaxLen: proto.Uint64(32)}},
+			})
+		}),
+	}
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: uploads[version],
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+	}
+
+	resp, err := handler.DiffSchemas(ctx, connect.NewRequest(&isrv1.DiffSchemasRequest{
+		FromVersion: "1.0.0",
+		ToVersion:   "1.0.1",
+	}))
+	if err != nil {
+		t.Fatalf("DiffSchemas() error = %v, want nil", err)
+	}
+
+	if len(resp.Msg.Changes) != 1 {
+		t.Fatalf("got %d changes, want 1: %v", len(resp.Msg.Changes), resp.Msg.Changes)
+	}
+	change := resp.Msg.Changes[0]
+	if change.Element != "isr.v1.GetLatestPatchRequest.known_version" ||
+		change.Rule != "string.max_len" ||
+		change.Type != isrv1.RuleChangeType_RULE_CHANGE_TYPE_ADDED ||
+		change.ToValue != "32" {
+		t.Errorf("change = %v, want string.max_len added to known_version", change)
+	}
+	if !strings.Contains(resp.Msg.Summary, "string.max_len added (32)") {
+		t.Errorf("Summary = %q, want to mention the added max_len", resp.Msg.Summary)
+	}
+
+	// Diffing the other way round reports a removal
+	resp, err = handler.DiffSchemas(ctx, connect.NewRequest(&isrv1.DiffSchemasRequest{
+		FromVersion: "1.0.1",
+		ToVersion:   "1.0.0",
+	}))
+	if err != nil {
+		t.Fatalf("DiffSchemas() error = %v, want nil", err)
+	}
+	if len(resp.Msg.Changes) != 1 || resp.Msg.Changes[0].Type != isrv1.RuleChangeType_RULE_CHANGE_TYPE_REMOVED {
+		t.Errorf("Changes = %v, want one removal", resp.Msg.Changes)
+	}
+}
+
+func TestSchemaHandler_DiffSchemas_NotFound(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+
+	_, err := handler.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.0.0",
+		SchemaBinary: createTestDescriptorBytes(t),
+	}))
+	if err != nil {
+		t.Fatalf("UploadSchema() error = %v", err)
+	}
+
+	_, err = handler.DiffSchemas(context.Background(), connect.NewRequest(&isrv1.DiffSchemasRequest{
+		FromVersion: "1.0.0",
+		ToVersion:   "9.9.9",
+	}))
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		t.Fatalf("error type = %T, want *connect.Error", err)
+	}
+	if connectErr.Code() != connect.CodeNotFound {
+		t.Errorf("error code = %v, want %v", connectErr.Code(), connect.CodeNotFound)
+	}
+}
+
+func TestSchemaHandler_ListSchemas_Pagination(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	for _, version := range []string{"1.0.0", "1.0.1", "1.0.2", "1.1.0", "2.0.0"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+	}
+
+	wantPages := [][]string{
+		{"2.0.0", "1.1.0"},
+		{"1.0.2", "1.0.1"},
+		{"1.0.0"},
+	}
+
+	pageToken := ""
+	for i, want := range wantPages {
+		resp, err := handler.ListSchemas(ctx, connect.NewRequest(&isrv1.ListSchemasRequest{
+			PageSize:  2,
+			PageToken: pageToken,
+		}))
+		if err != nil {
+			t.Fatalf("ListSchemas() page %d error = %v", i, err)
+		}
+
+		var got []string
+		for _, m := range resp.Msg.Schemas {
+			got = append(got, m.Version)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("page %d = %v, want %v", i, got, want)
+		}
+
+		pageToken = resp.Msg.NextPageToken
+		if last := i == len(wantPages)-1; last != (pageToken == "") {
+			t.Errorf("page %d NextPageToken = %q, want empty only on the last page", i, pageToken)
+		}
+	}
+}
+
+func TestSchemaHandler_ListSchemas_Filter(t *testing.T) {
+	var got model.SchemaFilter
+	mockRepo := &mockSchemaRepository{
+		listFunc: func(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error) {
+			got = filter
+			return nil, nil
+		},
+	}
+	handler := NewSchemaHandler(mockRepo)
+
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	_, err := handler.ListSchemas(context.Background(), connect.NewRequest(&isrv1.ListSchemasRequest{
+		Major:         proto.Int32(1),
+		Minor:         proto.Int32(0),
+		CreatedAfter:  timestamppb.New(after),
+		CreatedBefore: timestamppb.New(before),
+	}))
+	if err != nil {
+		t.Fatalf("ListSchemas() error = %v, want nil", err)
+	}
+
+	if got.Major == nil || *got.Major != 1 || got.Minor == nil || *got.Minor != 0 {
+		t.Errorf("filter major/minor = %v/%v, want 1/0", got.Major, got.Minor)
+	}
+	if got.CreatedAfter == nil || !got.CreatedAfter.Equal(after) {
+		t.Errorf("filter CreatedAfter = %v, want %v", got.CreatedAfter, after)
+	}
+	if got.CreatedBefore == nil || !got.CreatedBefore.Equal(before) {
+		t.Errorf("filter CreatedBefore = %v, want %v", got.CreatedBefore, before)
+	}
+	if got.Before != nil {
+		t.Errorf("filter Before = %v, want nil without a page token", got.Before)
+	}
+	if got.Limit != defaultPageSize+1 {
+		t.Errorf("filter Limit = %d, want %d", got.Limit, defaultPageSize+1)
+	}
+}
+
+func TestSchemaHandler_ListSchemas_InvalidPageToken(t *testing.T) {
+	handler := NewSchemaHandler(&mockSchemaRepository{})
+
+	for _, token := range []string{"not base64!", "bm90LWEtdmVyc2lvbg"} {
+		_, err := handler.ListSchemas(context.Background(), connect.NewRequest(&isrv1.ListSchemasRequest{
+			PageToken: token,
+		}))
+
+		var connectErr *connect.Error
+		if !errors.As(err, &connectErr) {
+			t.Fatalf("token %q: error type = %T, want *connect.Error", token, err)
+		}
+		if connectErr.Code() != connect.CodeInvalidArgument {
+			t.Errorf("token %q: error code = %v, want %v", token, connectErr.Code(), connect.CodeInvalidArgument)
+		}
+	}
+}
+
+func TestSchemaHandler_ListVersions(t *testing.T) {
+	var gotMajor *int32
+	mockRepo := &mockSchemaRepository{
+		listSeriesFunc: func(ctx context.Context, major *int32) ([]*model.SchemaSeries, error) {
+			gotMajor = major
+			return []*model.SchemaSeries{
+				{Major: 1, Minor: 1, LatestPatch: 0, PatchCount: 1},
+				{Major: 1, Minor: 0, LatestPatch: 3, PatchCount: 4},
+			}, nil
+		},
+	}
+	handler := NewSchemaHandler(mockRepo)
+
+	resp, err := handler.ListVersions(context.Background(), connect.NewRequest(&isrv1.ListVersionsRequest{
+		Major: proto.Int32(1),
+	}))
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v, want nil", err)
+	}
+
+	if gotMajor == nil || *gotMajor != 1 {
+		t.Errorf("major filter = %v, want 1", gotMajor)
+	}
+	if len(resp.Msg.Series) != 2 {
+		t.Fatalf("got %d series, want 2", len(resp.Msg.Series))
+	}
+	if s := resp.Msg.Series[1]; s.LatestVersion != "1.0.3" || s.PatchCount != 4 {
+		t.Errorf("series[1] = %v, want latest 1.0.3 with 4 patches", s)
+	}
+}
+
+func TestSchemaHandler_YankAndDeprecateSchema(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+	}
+
+	latest := func() *isrv1.SchemaMetadata {
+		t.Helper()
+		resp, err := handler.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{Major: 1, Minor: 0}))
+		if err != nil {
+			t.Fatalf("GetLatestPatch() error = %v", err)
+		}
+		return resp.Msg.Metadata
+	}
+
+	if got := latest(); got.Version != "1.0.1" || got.Status != isrv1.SchemaStatus_SCHEMA_STATUS_ACTIVE {
+		t.Fatalf("latest = %s (%v), want 1.0.1 (active)", got.Version, got.Status)
+	}
+
+	yankResp, err := handler.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{
+		Version: "1.0.1",
+		Reason:  "rejects valid emails",
+	}))
+	if err != nil {
+		t.Fatalf("YankSchema() error = %v, want nil", err)
+	}
+	if yankResp.Msg.Metadata.Status != isrv1.SchemaStatus_SCHEMA_STATUS_YANKED {
+		t.Errorf("YankSchema() status = %v, want yanked", yankResp.Msg.Metadata.Status)
+	}
+
+	// GetLatestPatch falls back to the previous patch
+	if got := latest(); got.Version != "1.0.0" {
+		t.Errorf("latest after yank = %s, want 1.0.0", got.Version)
+	}
+
+	// The yanked version is still available by exact version
+	byVersion, err := handler.GetSchemaByVersion(ctx, connect.NewRequest(&isrv1.GetSchemaByVersionRequest{Version: "1.0.1"}))
+	if err != nil {
+		t.Fatalf("GetSchemaByVersion() error = %v, want nil", err)
+	}
+	if md := byVersion.Msg.Metadata; md.Status != isrv1.SchemaStatus_SCHEMA_STATUS_YANKED || md.StatusReason != "rejects valid emails" {
+		t.Errorf("GetSchemaByVersion() status = %v (%q), want yanked with reason", md.Status, md.StatusReason)
+	}
+
+	// Deprecated versions are still served
+	if _, err := handler.DeprecateSchema(ctx, connect.NewRequest(&isrv1.DeprecateSchemaRequest{Version: "1.0.0"})); err != nil {
+		t.Fatalf("DeprecateSchema() error = %v, want nil", err)
+	}
+	if got := latest(); got.Version != "1.0.0" || got.Status != isrv1.SchemaStatus_SCHEMA_STATUS_DEPRECATED {
+		t.Errorf("latest after deprecate = %s (%v), want 1.0.0 (deprecated)", got.Version, got.Status)
+	}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode connect.Code
+	}{
+		{
+			name: "deprecate yanked version",
+			call: func() error {
+				_, err := handler.DeprecateSchema(ctx, connect.NewRequest(&isrv1.DeprecateSchemaRequest{Version: "1.0.1"}))
+				return err
+			},
+			wantCode: connect.CodeFailedPrecondition,
+		},
+		{
+			name: "yank unknown version",
+			call: func() error {
+				_, err := handler.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{Version: "9.9.9"}))
+				return err
+			},
+			wantCode: connect.CodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var connectErr *connect.Error
+			if err := tt.call(); !errors.As(err, &connectErr) {
+				t.Fatalf("error = %v, want *connect.Error", err)
+			}
+			if connectErr.Code() != tt.wantCode {
+				t.Errorf("error code = %v, want %v", connectErr.Code(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSchemaHandler_PreRelease(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	// Uploaded out of precedence order, so that ordering does not depend on insertion
+	for _, v := range []string{"1.0.0", "1.0.1-rc.2+build.7", "1.0.1-rc.10", "1.0.1-beta"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:         v,
+			SchemaBinary:    createTestDescriptorBytes(t),
+			AllowOutOfOrder: true,
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", v, err)
+		}
+	}
+
+	latest := func(includePreRelease bool) string {
+		t.Helper()
+		resp, err := handler.GetLatestPatch(ctx, connect.NewRequest(&isrv1.GetLatestPatchRequest{
+			Major:             1,
+			Minor:             0,
+			IncludePrerelease: includePreRelease,
+		}))
+		if err != nil {
+			t.Fatalf("GetLatestPatch() error = %v", err)
+		}
+		return resp.Msg.Metadata.Version
+	}
+
+	if got := latest(false); got != "1.0.0" {
+		t.Errorf("latest release = %s, want 1.0.0", got)
+	}
+	if got := latest(true); got != "1.0.1-rc.10" {
+		t.Errorf("latest including pre-releases = %s, want 1.0.1-rc.10", got)
+	}
+
+	// Build metadata is returned, but does not identify the version
+	byVersion, err := handler.GetSchemaByVersion(ctx, connect.NewRequest(&isrv1.GetSchemaByVersionRequest{Version: "1.0.1-rc.2"}))
+	if err != nil {
+		t.Fatalf("GetSchemaByVersion() error = %v, want nil", err)
+	}
+	if got := byVersion.Msg.Metadata.Version; got != "1.0.1-rc.2+build.7" {
+		t.Errorf("GetSchemaByVersion() version = %s, want 1.0.1-rc.2+build.7", got)
+	}
+
+	_, err = handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.0.1-rc.2+build.8",
+		SchemaBinary: createTestDescriptorBytes(t),
+	}))
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Code() != connect.CodeAlreadyExists {
+		t.Errorf("UploadSchema() with different build metadata error = %v, want AlreadyExists", err)
+	}
+
+	// Listing pages through pre-releases in precedence order
+	var listed []string
+	pageToken := ""
+	for {
+		resp, err := handler.ListSchemas(ctx, connect.NewRequest(&isrv1.ListSchemasRequest{
+			PageSize:  2,
+			PageToken: pageToken,
+		}))
+		if err != nil {
+			t.Fatalf("ListSchemas() error = %v", err)
+		}
+		for _, md := range resp.Msg.Schemas {
+			listed = append(listed, md.Version)
+		}
+		if resp.Msg.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.Msg.NextPageToken
+	}
+	want := "1.0.1-rc.10,1.0.1-rc.2+build.7,1.0.1-beta,1.0.0"
+	if got := strings.Join(listed, ","); got != want {
+		t.Errorf("ListSchemas() = %s, want %s", got, want)
+	}
+
+	// A release outranks its pre-releases
+	if _, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "1.0.1",
+		SchemaBinary: createTestDescriptorBytes(t),
+	})); err != nil {
+		t.Fatalf("UploadSchema(1.0.1) error = %v", err)
+	}
+	if got := latest(true); got != "1.0.1" {
+		t.Errorf("latest including pre-releases = %s, want 1.0.1", got)
+	}
+}
+
+func TestSchemaHandler_ResolveVersion(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	for _, v := range []string{"1.0.0", "1.0.1", "1.1.0", "1.2.0-rc.1", "1.3.0", "2.0.0"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      v,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", v, err)
+		}
+	}
+	if _, err := handler.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{Version: "1.3.0"})); err != nil {
+		t.Fatalf("YankSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		constraint        string
+		includePreRelease bool
+		want              string
+		wantCode          connect.Code
+	}{
+		{name: "tilde", constraint: "~1.0", want: "1.0.1"},
+		{name: "caret skips yanked", constraint: "^1", want: "1.1.0"},
+		{name: "caret with pre-releases", constraint: "^1", includePreRelease: true, want: "1.2.0-rc.1"},
+		{name: "range", constraint: ">=1.0.1 <1.1", want: "1.0.1"},
+		{name: "wildcard", constraint: "*", want: "2.0.0"},
+		{name: "no match", constraint: ">=3", wantCode: connect.CodeNotFound},
+		{name: "invalid constraint", constraint: "~>1.0", wantCode: connect.CodeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler.ResolveVersion(ctx, connect.NewRequest(&isrv1.ResolveVersionRequest{
+				Constraint:        tt.constraint,
+				IncludePrerelease: tt.includePreRelease,
+			}))
+			if tt.wantCode != 0 {
+				var connectErr *connect.Error
+				if !errors.As(err, &connectErr) || connectErr.Code() != tt.wantCode {
+					t.Fatalf("ResolveVersion() error = %v, want %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveVersion() error = %v, want nil", err)
+			}
+			if got := resp.Msg.Latest.Metadata.Version; got != tt.want {
+				t.Errorf("ResolveVersion(%q) = %s, want %s", tt.constraint, got, tt.want)
+			}
+			if len(resp.Msg.Latest.SchemaBinary) == 0 {
+				t.Error("ResolveVersion() returned an empty schema_binary")
+			}
+		})
+	}
+
+	resp, err := handler.ResolveVersion(ctx, connect.NewRequest(&isrv1.ResolveVersionRequest{
+		Constraint:   "^1",
+		KnownVersion: "1.1.0",
+	}))
+	if err != nil {
+		t.Fatalf("ResolveVersion() error = %v, want nil", err)
+	}
+	if !resp.Msg.Latest.NotModified || len(resp.Msg.Latest.SchemaBinary) != 0 {
+		t.Errorf("ResolveVersion() with known_version = %v, want not_modified without binary", resp.Msg.Latest)
+	}
+}
+
+func TestSchemaHandler_Tags(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	ctx := context.Background()
+
+	for _, v := range []string{"1.0.0", "1.0.1", "1.0.2"} {
+		_, err := handler.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      v,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", v, err)
+		}
+	}
+	if _, err := handler.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{Version: "1.0.2"})); err != nil {
+		t.Fatalf("YankSchema() error = %v", err)
+	}
+
+	tagged := func(tag, knownVersion string) *isrv1.GetLatestPatchResponse {
+		t.Helper()
+		resp, err := handler.GetSchemaByTag(ctx, connect.NewRequest(&isrv1.GetSchemaByTagRequest{
+			Tag:          tag,
+			KnownVersion: knownVersion,
+		}))
+		if err != nil {
+			t.Fatalf("GetSchemaByTag(%s) error = %v", tag, err)
+		}
+		return resp.Msg.Latest
+	}
+
+	// Promote 1.0.0 to staging, then 1.0.1 to staging and 1.0.0 to prod
+	promotions := []struct {
+		tag, version, wantPrevious string
+	}{
+		{tag: "staging", version: "1.0.0", wantPrevious: ""},
+		{tag: "staging", version: "1.0.1", wantPrevious: "1.0.0"},
+		{tag: "prod", version: "1.0.0", wantPrevious: ""},
+	}
+	for _, p := range promotions {
+		resp, err := handler.SetTag(ctx, connect.NewRequest(&isrv1.SetTagRequest{Tag: p.tag, Version: p.version}))
+		if err != nil {
+			t.Fatalf("SetTag(%s, %s) error = %v", p.tag, p.version, err)
+		}
+		if resp.Msg.Metadata.Version != p.version || resp.Msg.PreviousVersion != p.wantPrevious {
+			t.Errorf("SetTag(%s, %s) = %s (previous %q), want previous %q",
+				p.tag, p.version, resp.Msg.Metadata.Version, resp.Msg.PreviousVersion, p.wantPrevious)
+		}
+	}
+
+	if got := tagged("staging", ""); got.Metadata.Version != "1.0.1" || len(got.SchemaBinary) == 0 {
+		t.Errorf("staging = %s, want 1.0.1 with schema_binary", got.Metadata.Version)
+	}
+	if got := tagged("prod", ""); got.Metadata.Version != "1.0.0" {
+		t.Errorf("prod = %s, want 1.0.0", got.Metadata.Version)
+	}
+	if got := tagged("prod", "1.0.0"); !got.NotModified || len(got.SchemaBinary) != 0 {
+		t.Errorf("prod with known_version = %v, want not_modified without binary", got)
+	}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode connect.Code
+	}{
+		{
+			name: "tag unknown version",
+			call: func() error {
+				_, err := handler.SetTag(ctx, connect.NewRequest(&isrv1.SetTagRequest{Tag: "prod", Version: "9.9.9"}))
+				return err
+			},
+			wantCode: connect.CodeNotFound,
+		},
+		{
+			name: "tag yanked version",
+			call: func() error {
+				_, err := handler.SetTag(ctx, connect.NewRequest(&isrv1.SetTagRequest{Tag: "prod", Version: "1.0.2"}))
+				return err
+			},
+			wantCode: connect.CodeFailedPrecondition,
+		},
+		{
+			name: "unknown tag",
+			call: func() error {
+				_, err := handler.GetSchemaByTag(ctx, connect.NewRequest(&isrv1.GetSchemaByTagRequest{Tag: "canary"}))
+				return err
+			},
+			wantCode: connect.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) || connectErr.Code() != tt.wantCode {
+				t.Errorf("error = %v, want %v", err, tt.wantCode)
+			}
+		})
+	}
+}
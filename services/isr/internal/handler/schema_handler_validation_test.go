@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"connectrpc.com/connect"
@@ -12,6 +13,8 @@ import (
 	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -39,13 +42,34 @@ func newTestClient(t *testing.T, handler *SchemaHandler) (isrv1connect.SchemaReg
 	return client, server.Close
 }
 
+// padDescriptorSet appends an unknown bytes field to a serialized descriptor set
+// so that the result is exactly size bytes and still parses
+func padDescriptorSet(t *testing.T, data []byte, size int) []byte {
+	t.Helper()
+
+	const paddingField = 1000
+	overhead := protowire.SizeTag(paddingField)
+	// The length prefix shrinks or grows with the payload; settle on a consistent size
+	payloadLen := size - len(data) - overhead
+	for payloadLen+protowire.SizeVarint(uint64(payloadLen)) != size-len(data)-overhead {
+		payloadLen = size - len(data) - overhead - protowire.SizeVarint(uint64(payloadLen))
+	}
+
+	padded := protowire.AppendTag(data, paddingField, protowire.BytesType)
+	padded = protowire.AppendBytes(padded, make([]byte, payloadLen))
+	if len(padded) != size {
+		t.Fatalf("padded descriptor set is %d bytes, want %d", len(padded), size)
+	}
+	return padded
+}
+
 // TestUploadSchema_ValidationError_SchemaBinaryTooLarge tests that schema binaries larger than 10MB are rejected
 func TestUploadSchema_ValidationError_SchemaBinaryTooLarge(t *testing.T) {
 	mockRepo := &mockSchemaRepository{
 		versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
 			return false, nil
 		},
-		createFunc: func(ctx context.Context, schema *model.Schema) error {
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
 			return nil
 		},
 	}
@@ -108,7 +132,7 @@ func TestUploadSchema_ValidationSuccess_MaxSize(t *testing.T) {
 		versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
 			return false, nil
 		},
-		createFunc: func(ctx context.Context, schema *model.Schema) error {
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
 			return nil
 		},
 	}
@@ -117,8 +141,8 @@ func TestUploadSchema_ValidationSuccess_MaxSize(t *testing.T) {
 	client, cleanup := newTestClient(t, handler)
 	defer cleanup()
 
-	// Create a binary exactly at the 10MB limit
-	maxBinary := make([]byte, maxSchemaBinarySize)
+	// Create a valid descriptor set exactly at the 10MB limit
+	maxBinary := padDescriptorSet(t, createTestDescriptorBytes(t), maxSchemaBinarySize)
 
 	req := connect.NewRequest(&isrv1.UploadSchemaRequest{
 		Version:      "1.0.0",
@@ -138,6 +162,74 @@ func TestUploadSchema_ValidationSuccess_MaxSize(t *testing.T) {
 	}
 }
 
+// TestUploadSchema_InvalidDescriptorSet tests that binaries which are not a loadable
+// FileDescriptorSet are rejected with per-file details
+func TestUploadSchema_InvalidDescriptorSet(t *testing.T) {
+	mockRepo := &mockSchemaRepository{
+		versionExistsFunc: func(ctx context.Context, version string) (bool, error) {
+			t.Error("VersionExists should not be called for an invalid descriptor set")
+			return false, nil
+		},
+	}
+	handler := NewSchemaHandler(mockRepo)
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	// Keep isr/v1/isr.proto but drop its imports
+	fds := createTestDescriptorSet(t)
+	fds.File = fds.File[len(fds.File)-1:]
+	missingImports, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		binary     []byte
+		wantErrors int
+	}{
+		{"not a descriptor set", []byte("test schema data"), 1},
+		{"missing imports", missingImports, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+				Version:      "1.0.0",
+				SchemaBinary: tc.binary,
+			}))
+			if err == nil {
+				t.Fatal("UploadSchema() with invalid descriptor set should fail, but got nil error")
+			}
+
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) {
+				t.Fatalf("error type = %T, want *connect.Error", err)
+			}
+			if connectErr.Code() != connect.CodeInvalidArgument {
+				t.Errorf("error code = %v, want %v (InvalidArgument)", connectErr.Code(), connect.CodeInvalidArgument)
+			}
+
+			var details *isrv1.InvalidSchemaDetails
+			for _, detail := range connectErr.Details() {
+				value, err := detail.Value()
+				if err != nil {
+					t.Fatalf("failed to decode error detail: %v", err)
+				}
+				if d, ok := value.(*isrv1.InvalidSchemaDetails); ok {
+					details = d
+				}
+			}
+			if details == nil {
+				t.Fatal("InvalidSchemaDetails not attached to error")
+			}
+			if len(details.Errors) != tc.wantErrors {
+				t.Errorf("len(Errors) = %d, want %d: %v", len(details.Errors), tc.wantErrors, details.Errors)
+			}
+		})
+	}
+}
+
 // TestUploadSchema_ValidationError_InvalidVersionFormat tests that invalid version formats are rejected
 func TestUploadSchema_ValidationError_InvalidVersionFormat(t *testing.T) {
 	mockRepo := &mockSchemaRepository{}
@@ -153,6 +245,9 @@ func TestUploadSchema_ValidationError_InvalidVersionFormat(t *testing.T) {
 		{"non-numeric", "v1.0.0"},
 		{"with prefix", "version-1.0.0"},
 		{"empty", ""},
+		{"empty pre-release", "1.0.0-"},
+		{"invalid pre-release character", "1.0.0-rc_1"},
+		{"empty build metadata", "1.0.0+"},
 	}
 
 	for _, tc := range testCases {
@@ -259,3 +354,153 @@ func TestGetSchemaByVersion_ValidationError_InvalidVersionFormat(t *testing.T) {
 		})
 	}
 }
+
+// TestGetSchemaByHash_ValidationError_InvalidHash tests that malformed content hashes are rejected
+func TestGetSchemaByHash_ValidationError_InvalidHash(t *testing.T) {
+	mockRepo := &mockSchemaRepository{
+		getBlobFunc: func(ctx context.Context, hash string) ([]byte, error) {
+			t.Error("GetBlob should not be called for an invalid hash")
+			return nil, nil
+		},
+	}
+	handler := NewSchemaHandler(mockRepo)
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	testCases := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"too short", strings.Repeat("a", 63)},
+		{"uppercase", strings.Repeat("A", 64)},
+		{"non-hex", strings.Repeat("g", 64)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.GetSchemaByHash(context.Background(), connect.NewRequest(&isrv1.GetSchemaByHashRequest{
+				ContentHash: tc.hash,
+			}))
+
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) {
+				t.Fatalf("GetSchemaByHash() with hash %q error = %v, want *connect.Error", tc.hash, err)
+			}
+			if connectErr.Code() != connect.CodeInvalidArgument {
+				t.Errorf("error code = %v, want %v (InvalidArgument)", connectErr.Code(), connect.CodeInvalidArgument)
+			}
+		})
+	}
+}
+
+// TestUploadSchema_ValidationError_InvalidSignature tests that signatures that are not 64 bytes are rejected
+func TestUploadSchema_ValidationError_InvalidSignature(t *testing.T) {
+	mockRepo := &mockSchemaRepository{
+		createFunc: func(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+			return nil
+		},
+	}
+	handler := NewSchemaHandler(mockRepo)
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	testCases := []struct {
+		name      string
+		signature []byte
+		wantCode  connect.Code // 0 for success
+	}{
+		{"unsigned", nil, 0},
+		{"ed25519", make([]byte, 64), 0},
+		{"too short", make([]byte, 63), connect.CodeInvalidArgument},
+		{"too long", make([]byte, 65), connect.CodeInvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.UploadSchema(context.Background(), connect.NewRequest(&isrv1.UploadSchemaRequest{
+				Version:      "1.0.0",
+				SchemaBinary: createTestDescriptorBytes(t),
+				Signature:    tc.signature,
+			}))
+			if tc.wantCode == 0 {
+				if err != nil {
+					t.Errorf("UploadSchema() error = %v, want nil", err)
+				}
+				return
+			}
+			if connect.CodeOf(err) != tc.wantCode {
+				t.Errorf("UploadSchema() error = %v, want code %v", err, tc.wantCode)
+			}
+		})
+	}
+}
+
+// TestListSchemas_ValidationError tests that invalid listing requests are rejected
+func TestListSchemas_ValidationError(t *testing.T) {
+	handler := NewSchemaHandler(&mockSchemaRepository{})
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	testCases := []struct {
+		name string
+		req  *isrv1.ListSchemasRequest
+	}{
+		{"minor without major", &isrv1.ListSchemasRequest{Minor: proto.Int32(0)}},
+		{"negative major", &isrv1.ListSchemasRequest{Major: proto.Int32(-1)}},
+		{"page_size too large", &isrv1.ListSchemasRequest{PageSize: 101}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.ListSchemas(context.Background(), connect.NewRequest(tc.req))
+			if err == nil {
+				t.Fatal("ListSchemas() should fail, but got nil error")
+			}
+
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) {
+				t.Fatalf("error type = %T, want *connect.Error", err)
+			}
+			if connectErr.Code() != connect.CodeInvalidArgument {
+				t.Errorf("error code = %v, want %v (InvalidArgument)", connectErr.Code(), connect.CodeInvalidArgument)
+			}
+		})
+	}
+}
+
+// TestSetTag_ValidationError_InvalidTag tests that malformed tag names are rejected
+func TestSetTag_ValidationError_InvalidTag(t *testing.T) {
+	mockRepo := &mockSchemaRepository{}
+	handler := NewSchemaHandler(mockRepo)
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	testCases := []struct {
+		name string
+		tag  string
+	}{
+		{"empty", ""},
+		{"uppercase", "Prod"},
+		{"trailing separator", "prod-"},
+		{"whitespace", "my tag"},
+		{"too long", strings.Repeat("a", 65)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.SetTag(context.Background(), connect.NewRequest(&isrv1.SetTagRequest{
+				Tag:     tc.tag,
+				Version: "1.0.0",
+			}))
+
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) {
+				t.Fatalf("SetTag() with tag %q error = %v, want *connect.Error", tc.tag, err)
+			}
+			if connectErr.Code() != connect.CodeInvalidArgument {
+				t.Errorf("error code = %v, want %v (InvalidArgument)", connectErr.Code(), connect.CodeInvalidArgument)
+			}
+		})
+	}
+}
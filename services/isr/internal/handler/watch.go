@@ -0,0 +1,75 @@
+package handler
+
+import "sync"
+
+// schemaSeries identifies a major.minor series of patch versions
+type schemaSeries struct {
+	major int32
+	minor int32
+}
+
+// watchHub notifies WatchSchema streams when a schema is uploaded to their series.
+// Notifications are in-process; changes committed by other ISR replicas reach the hub
+// through SchemaHandler.FollowChanges.
+type watchHub struct {
+	mu          sync.Mutex
+	subscribers map[schemaSeries]map[chan struct{}]struct{}
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{
+		subscribers: make(map[schemaSeries]map[chan struct{}]struct{}),
+	}
+}
+
+// subscribe registers a subscriber for the given series.
+// The returned channel receives a signal after each upload; signals are coalesced
+// so a slow subscriber sees at most one pending notification.
+// The returned function unregisters the subscriber.
+func (h *watchHub) subscribe(major, minor int32) (<-chan struct{}, func()) {
+	series := schemaSeries{major: major, minor: minor}
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subscribers[series] == nil {
+		h.subscribers[series] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[series][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[series], ch)
+		if len(h.subscribers[series]) == 0 {
+			delete(h.subscribers, series)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// notify signals every subscriber of the given series without blocking
+func (h *watchHub) notify(major, minor int32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[schemaSeries{major: major, minor: minor}] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyAll signals every subscriber, so that each stream re-reads its latest patch
+func (h *watchHub) notifyAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/memory"
+)
+
+// receiveVersion waits for the next message on the stream and returns its version
+func receiveVersion(t *testing.T, stream *connect.ServerStreamForClient[isrv1.WatchSchemaResponse]) string {
+	t.Helper()
+
+	received := make(chan bool, 1)
+	go func() { received <- stream.Receive() }()
+
+	select {
+	case ok := <-received:
+		if !ok {
+			t.Fatalf("stream closed: %v", stream.Err())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for WatchSchema message")
+	}
+	return stream.Msg().Latest.Metadata.Version
+}
+
+func TestSchemaHandler_WatchSchema_PushesNewerPatches(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	schemaBinary := createTestDescriptorBytes(t)
+	upload := func(version string) {
+		t.Helper()
+		_, err := client.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: schemaBinary,
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+	}
+
+	upload("1.0.0")
+
+	stream, err := client.WatchSchema(ctx, connect.NewRequest(&isrv1.WatchSchemaRequest{Major: 1, Minor: 0}))
+	if err != nil {
+		t.Fatalf("WatchSchema() error = %v", err)
+	}
+	defer stream.Close()
+
+	// The current latest patch is sent immediately
+	if got := receiveVersion(t, stream); got != "1.0.0" {
+		t.Errorf("initial version = %s, want 1.0.0", got)
+	}
+
+	// Uploads to other series are not pushed
+	upload("1.1.0")
+
+	upload("1.0.1")
+	if got := receiveVersion(t, stream); got != "1.0.1" {
+		t.Errorf("pushed version = %s, want 1.0.1", got)
+	}
+	if !bytes.Equal(stream.Msg().Latest.SchemaBinary, schemaBinary) {
+		t.Errorf("SchemaBinary does not match the uploaded descriptor set")
+	}
+}
+
+func TestSchemaHandler_WatchSchema_PushesPreviousPatchOnYank(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		_, err := client.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+	}
+
+	stream, err := client.WatchSchema(ctx, connect.NewRequest(&isrv1.WatchSchemaRequest{Major: 1, Minor: 0}))
+	if err != nil {
+		t.Fatalf("WatchSchema() error = %v", err)
+	}
+	defer stream.Close()
+
+	if got := receiveVersion(t, stream); got != "1.0.1" {
+		t.Errorf("initial version = %s, want 1.0.1", got)
+	}
+
+	if _, err := client.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{Version: "1.0.1"})); err != nil {
+		t.Fatalf("YankSchema() error = %v", err)
+	}
+	if got := receiveVersion(t, stream); got != "1.0.0" {
+		t.Errorf("pushed version after yank = %s, want 1.0.0", got)
+	}
+}
+
+func TestSchemaHandler_WatchSchema_WaitsForFirstUpload(t *testing.T) {
+	handler := NewSchemaHandler(memory.New())
+	client, cleanup := newTestClient(t, handler)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchSchema(ctx, connect.NewRequest(&isrv1.WatchSchemaRequest{Major: 2, Minor: 0}))
+	if err != nil {
+		t.Fatalf("WatchSchema() error = %v", err)
+	}
+	defer stream.Close()
+
+	_, err = client.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+		Version:      "2.0.0",
+		SchemaBinary: createTestDescriptorBytes(t),
+	}))
+	if err != nil {
+		t.Fatalf("UploadSchema() error = %v", err)
+	}
+
+	if got := receiveVersion(t, stream); got != "2.0.0" {
+		t.Errorf("pushed version = %s, want 2.0.0", got)
+	}
+}
+
+func TestWatchHub_NotifyCoalesces(t *testing.T) {
+	hub := newWatchHub()
+	ch, unsubscribe := hub.subscribe(1, 0)
+
+	hub.notify(1, 0)
+	hub.notify(1, 0)
+	hub.notify(1, 1)
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("expected a pending notification")
+	}
+	select {
+	case <-ch:
+		t.Fatal("expected notifications to be coalesced")
+	default:
+	}
+
+	unsubscribe()
+	hub.notify(1, 0)
+	select {
+	case <-ch:
+		t.Fatal("unsubscribed channel should not be notified")
+	default:
+	}
+}
+
+func TestSchemaHandler_WatchSchema_FollowsOtherReplicas(t *testing.T) {
+	// Two ISR replicas sharing one repository; the stream is served by the first,
+	// uploads and yanks are handled by the second
+	repo := memory.New()
+	watched := NewSchemaHandler(repo)
+	other := NewSchemaHandler(repo)
+	client, cleanup := newTestClient(t, watched)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watched.FollowChanges(ctx, repo)
+
+	stream, err := client.WatchSchema(ctx, connect.NewRequest(&isrv1.WatchSchemaRequest{Major: 1, Minor: 0}))
+	if err != nil {
+		t.Fatalf("WatchSchema() error = %v", err)
+	}
+	defer stream.Close()
+
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		_, err := other.UploadSchema(ctx, connect.NewRequest(&isrv1.UploadSchemaRequest{
+			Version:      version,
+			SchemaBinary: createTestDescriptorBytes(t),
+		}))
+		if err != nil {
+			t.Fatalf("UploadSchema(%s) error = %v", version, err)
+		}
+		if got := receiveVersion(t, stream); got != version {
+			t.Errorf("pushed version = %s, want %s", got, version)
+		}
+	}
+
+	if _, err := other.YankSchema(ctx, connect.NewRequest(&isrv1.YankSchemaRequest{Version: "1.0.1"})); err != nil {
+		t.Fatalf("YankSchema() error = %v", err)
+	}
+	if got := receiveVersion(t, stream); got != "1.0.0" {
+		t.Errorf("pushed version after yank = %s, want 1.0.0", got)
+	}
+}
@@ -4,12 +4,61 @@ import "time"
 
 // Schema represents a schema stored in the registry
 type Schema struct {
-	ID           string    `db:"id"`
-	Version      string    `db:"version"`
-	Major        int32     `db:"major"`
-	Minor        int32     `db:"minor"`
-	Patch        int32     `db:"patch"`
-	SchemaBinary []byte    `db:"schema_binary"`
-	SizeBytes    int32     `db:"size_bytes"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID              string       `db:"id"`
+	Version         string       `db:"version"` // Without build metadata (e.g., "1.2.3-rc.1")
+	Major           int32        `db:"major"`
+	Minor           int32        `db:"minor"`
+	Patch           int32        `db:"patch"`
+	PreRelease      string       `db:"pre_release"` // Empty for a release
+	BuildMetadata   string       `db:"build_metadata"`
+	SchemaBinary    []byte       `db:"schema_binary"`
+	SizeBytes       int32        `db:"size_bytes"`        // Uncompressed size of SchemaBinary
+	StoredSizeBytes int32        `db:"stored_size_bytes"` // Size at rest after compression; set on reads
+	ContentHash     string       `db:"content_hash"`
+	Signature       []byte       `db:"signature"` // Detached ed25519 signature of SchemaBinary; nil if unsigned
+	Status          SchemaStatus `db:"status"`
+	StatusReason    string       `db:"status_reason"`
+	CreatedAt       time.Time    `db:"created_at"`
+}
+
+// SchemaStatus is the lifecycle state of a stored version
+type SchemaStatus string
+
+const (
+	StatusActive     SchemaStatus = "active"
+	StatusDeprecated SchemaStatus = "deprecated" // Still served, but callers should move off it
+	StatusYanked     SchemaStatus = "yanked"     // Skipped by GetLatestPatch; only retrievable by exact version
+)
+
+// SchemaFilter narrows down a schema listing. Nil fields are not filtered on.
+// Results are ordered newest version first.
+type SchemaFilter struct {
+	Major         *int32
+	Minor         *int32
+	CreatedAfter  *time.Time // Inclusive
+	CreatedBefore *time.Time // Exclusive
+	// Only versions with lower precedence than this one are returned (keyset pagination)
+	Before *SchemaCursor
+	Limit  int
+}
+
+// SchemaCursor identifies a position in the version ordering
+type SchemaCursor struct {
+	Major, Minor, Patch int32
+	PreRelease          string
+}
+
+// SchemaSeries summarizes the releases published for one major.minor. Pre-releases are not counted.
+type SchemaSeries struct {
+	Major       int32 `db:"major"`
+	Minor       int32 `db:"minor"`
+	LatestPatch int32 `db:"latest_patch"`
+	PatchCount  int32 `db:"patch_count"`
+}
+
+// SchemaTag is a named pointer to a version (e.g., "staging", "prod")
+type SchemaTag struct {
+	Name      string    `db:"name"`
+	SchemaID  string    `db:"schema_id"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -0,0 +1,203 @@
+package rulediff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ChangeType is the kind of change made to a single constraint
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "ADDED"
+	ChangeRemoved ChangeType = "REMOVED"
+	ChangeChanged ChangeType = "CHANGED"
+)
+
+// Change is a single protovalidate constraint that differs between two schemas
+type Change struct {
+	Element string // Fully-qualified message, field or oneof name
+	Rule    string // Constraint path, e.g. "string.min_len" or "cel[name.not_admin].expression"
+	Type    ChangeType
+	From    string // Empty when Type is ChangeAdded
+	To      string // Empty when Type is ChangeRemoved
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: %s added (%s)", c.Element, c.Rule, c.To)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: %s removed (was %s)", c.Element, c.Rule, c.From)
+	default:
+		return fmt.Sprintf("%s: %s changed %s -> %s", c.Element, c.Rule, c.From, c.To)
+	}
+}
+
+// Diff returns every buf.validate constraint that was added, removed or changed
+// from one schema to the other, sorted by element and rule.
+// Message, oneof and field rules are compared; elements that only exist
+// in one of the schemas have all their constraints reported as added or removed.
+func Diff(from, to *protoregistry.Files) []Change {
+	fromRules, toRules := collect(from), collect(to)
+
+	var changes []Change
+	for element, rules := range fromRules {
+		for rule, fromValue := range rules {
+			toValue, ok := toRules[element][rule]
+			switch {
+			case !ok:
+				changes = append(changes, Change{Element: element, Rule: rule, Type: ChangeRemoved, From: fromValue})
+			case toValue != fromValue:
+				changes = append(changes, Change{Element: element, Rule: rule, Type: ChangeChanged, From: fromValue, To: toValue})
+			}
+		}
+	}
+	for element, rules := range toRules {
+		for rule, toValue := range rules {
+			if _, ok := fromRules[element][rule]; !ok {
+				changes = append(changes, Change{Element: element, Rule: rule, Type: ChangeAdded, To: toValue})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Element != changes[j].Element {
+			return changes[i].Element < changes[j].Element
+		}
+		return changes[i].Rule < changes[j].Rule
+	})
+	return changes
+}
+
+// Summary renders changes as human-readable text, one change per line
+func Summary(changes []Change) string {
+	if len(changes) == 0 {
+		return "No validation rule changes"
+	}
+
+	elements := make(map[string]bool)
+	for _, c := range changes {
+		elements[c.Element] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d validation rule change(s) in %d element(s)", len(changes), len(elements))
+	for _, c := range changes {
+		b.WriteString("\n")
+		b.WriteString(c.String())
+	}
+	return b.String()
+}
+
+// collect returns the flattened constraints of every message, oneof and field, keyed by element name
+func collect(files *protoregistry.Files) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+	add := func(element protoreflect.FullName, rules proto.Message) {
+		if rules == nil || !rules.ProtoReflect().IsValid() {
+			return
+		}
+		flat := make(map[string]string)
+		flatten("", rules.ProtoReflect(), flat)
+		if len(flat) > 0 {
+			out[string(element)] = flat
+		}
+	}
+
+	var walk func(msgs protoreflect.MessageDescriptors)
+	walk = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			md := msgs.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+
+			if rules, err := protovalidate.ResolveMessageRules(md); err == nil {
+				add(md.FullName(), rules)
+			}
+			for j := 0; j < md.Oneofs().Len(); j++ {
+				od := md.Oneofs().Get(j)
+				if rules, err := protovalidate.ResolveOneofRules(od); err == nil {
+					add(od.FullName(), rules)
+				}
+			}
+			for j := 0; j < md.Fields().Len(); j++ {
+				fd := md.Fields().Get(j)
+				if rules, err := protovalidate.ResolveFieldRules(fd); err == nil {
+					add(fd.FullName(), rules)
+				}
+			}
+
+			walk(md.Messages())
+		}
+	}
+
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		walk(fd.Messages())
+		return true
+	})
+	return out
+}
+
+// flatten writes every populated field of a rules message into out as path -> value.
+// Repeated messages with an "id" field (CEL rules) are keyed by id so that
+// reordering them is not reported as a change.
+func flatten(prefix string, msg protoreflect.Message, out map[string]string) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := prefix + string(fd.Name())
+		if fd.IsExtension() {
+			name = prefix + "[" + string(fd.FullName()) + "]"
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				item := list.Get(i).Message()
+				key := fmt.Sprintf("%s[%d]", name, i)
+				if idField := fd.Message().Fields().ByName("id"); idField != nil && item.Has(idField) {
+					key = fmt.Sprintf("%s[%s]", name, item.Get(idField).String())
+				}
+				flatten(key+".", item, out)
+			}
+		case fd.IsList():
+			list := v.List()
+			values := make([]string, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				values[i] = formatValue(fd, list.Get(i))
+			}
+			out[name] = "[" + strings.Join(values, ", ") + "]"
+		case fd.Message() != nil:
+			before := len(out)
+			flatten(name+".", v.Message(), out)
+			if len(out) == before {
+				out[name] = "{}" // Set but empty, e.g. `string: {}`
+			}
+		default:
+			out[name] = formatValue(fd, v)
+		}
+		return true
+	})
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,156 @@
+package rulediff
+
+import (
+	"strings"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// createTestDescriptorSet builds a FileDescriptorSet from the isr.v1 package
+// and its transitive imports, dependencies first
+func createTestDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if visited[fd.Path()] {
+			return
+		}
+		visited[fd.Path()] = true
+
+		for i := 0; i < fd.Imports().Len(); i++ {
+			addFile(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	addFile(isrv1.File_isr_v1_isr_proto)
+
+	return fds
+}
+
+// buildFiles returns the isr.v1 test schema with fieldRules applied to the named
+// fields ("Message.field"); a nil value removes the field's rules
+func buildFiles(t *testing.T, fieldRules map[string]*validate.FieldRules) *protoregistry.Files {
+	t.Helper()
+
+	fds := createTestDescriptorSet(t)
+	file := fds.File[len(fds.File)-1]
+	for _, msg := range file.MessageType {
+		for _, field := range msg.Field {
+			rules, ok := fieldRules[msg.GetName()+"."+field.GetName()]
+			if !ok {
+				continue
+			}
+			field.Options = &descriptorpb.FieldOptions{}
+			if rules != nil {
+				proto.SetExtension(field.Options, validate.E_Field, rules)
+			}
+		}
+	}
+
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		t.Fatalf("failed to build descriptors: %v", err)
+	}
+	return files
+}
+
+func celRule(id, expression string) *validate.Rule {
+	return &validate.Rule{Id: proto.String(id), Expression: proto.String(expression)}
+}
+
+func TestDiff(t *testing.T) {
+	from := buildFiles(t, map[string]*validate.FieldRules{
+		"GetLatestPatchRequest.known_version": {
+			Cel: []*validate.Rule{
+				celRule("known_version.prefix", "!this.startsWith('v')"),
+				celRule("known_version.short", "size(this) < 20"),
+			},
+		},
+	})
+	to := buildFiles(t, map[string]*validate.FieldRules{
+		// Tighten: add max_len to a field without rules
+		"SchemaMetadata.version": {
+			Type: &validate.FieldRules_String_{String_: &validate.StringRules{
+				MaxLen: proto.Uint64(32),
+			}},
+		},
+		// Loosen: drop gte 0
+		"GetLatestPatchRequest.major": nil,
+		// Reordered CEL rules with one expression changed
+		"GetLatestPatchRequest.known_version": {
+			Cel: []*validate.Rule{
+				celRule("known_version.short", "size(this) < 10"),
+				celRule("known_version.prefix", "!this.startsWith('v')"),
+			},
+		},
+	})
+
+	want := []Change{
+		{
+			Element: "isr.v1.GetLatestPatchRequest.known_version",
+			Rule:    "cel[known_version.short].expression",
+			Type:    ChangeChanged,
+			From:    `"size(this) < 20"`,
+			To:      `"size(this) < 10"`,
+		},
+		{
+			Element: "isr.v1.GetLatestPatchRequest.major",
+			Rule:    "int32.gte",
+			Type:    ChangeRemoved,
+			From:    "0",
+		},
+		{
+			Element: "isr.v1.SchemaMetadata.version",
+			Rule:    "string.max_len",
+			Type:    ChangeAdded,
+			To:      "32",
+		},
+	}
+
+	got := Diff(from, to)
+	if len(got) != len(want) {
+		t.Fatalf("Diff() returned %d changes, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	files := buildFiles(t, nil)
+	if got := Diff(files, files); len(got) != 0 {
+		t.Errorf("Diff() = %v, want no changes", got)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	if got := Summary(nil); got != "No validation rule changes" {
+		t.Errorf("Summary(nil) = %q", got)
+	}
+
+	got := Summary([]Change{
+		{Element: "user.v1.CreateUserRequest.name", Rule: "string.min_len", Type: ChangeChanged, From: "1", To: "5"},
+		{Element: "user.v1.CreateUserRequest.name", Rule: "string.max_len", Type: ChangeRemoved, From: "100"},
+	})
+	for _, want := range []string{
+		"2 validation rule change(s) in 1 element(s)",
+		"user.v1.CreateUserRequest.name: string.min_len changed 1 -> 5",
+		"user.v1.CreateUserRequest.name: string.max_len removed (was 100)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Summary() = %q, want to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package schemacheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Problem describes a single reason a descriptor set was rejected
+type Problem struct {
+	File        string // Proto file path; empty if the problem applies to the whole set
+	Element     string // Fully-qualified message or field name; empty if not applicable
+	Description string
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		b.WriteString(": ")
+	}
+	if p.Element != "" {
+		b.WriteString(p.Element)
+		b.WriteString(": ")
+	}
+	b.WriteString(p.Description)
+	return b.String()
+}
+
+// Error is returned by Check when the descriptor set is invalid
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.String()
+	}
+	return fmt.Sprintf("invalid descriptor set: %s", strings.Join(msgs, "; "))
+}
+
+// Check verifies that data is a serialized FileDescriptorSet that BE can load:
+// it must parse, be self-contained (every import is part of the set),
+// build with protodesc.NewFiles, and every buf.validate rule must compile.
+// On success the built file registry is returned.
+func Check(data []byte) (*protoregistry.Files, error) {
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fds); err != nil {
+		return nil, &Error{Problems: []Problem{{Description: fmt.Sprintf("failed to parse FileDescriptorSet: %v", err)}}}
+	}
+	if len(fds.File) == 0 {
+		return nil, &Error{Problems: []Problem{{Description: "descriptor set contains no files"}}}
+	}
+
+	if problems := checkImports(fds); len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, &Error{Problems: []Problem{{Description: fmt.Sprintf("failed to build descriptors: %v", err)}}}
+	}
+
+	if problems := checkRules(files); len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+
+	return files, nil
+}
+
+// Parse builds a file registry from a serialized FileDescriptorSet without
+// checking validation rules. Use it for descriptor sets that already passed Check.
+func Parse(data []byte) (*protoregistry.Files, error) {
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fds); err != nil {
+		return nil, fmt.Errorf("failed to parse FileDescriptorSet: %w", err)
+	}
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors: %w", err)
+	}
+	return files, nil
+}
+
+// checkImports reports duplicate files and imports that are not part of the set
+func checkImports(fds *descriptorpb.FileDescriptorSet) []Problem {
+	var problems []Problem
+
+	names := make(map[string]bool, len(fds.File))
+	for _, file := range fds.File {
+		if names[file.GetName()] {
+			problems = append(problems, Problem{File: file.GetName(), Description: "file appears more than once"})
+		}
+		names[file.GetName()] = true
+	}
+
+	for _, file := range fds.File {
+		for _, dep := range file.GetDependency() {
+			if !names[dep] {
+				problems = append(problems, Problem{File: file.GetName(), Description: fmt.Sprintf("import %q is not included in the descriptor set", dep)})
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkRules compiles the buf.validate rules of every message in files.
+// protovalidate compiles rules lazily, so each message is validated once
+// as an empty dynamic message; only compilation errors are reported.
+func checkRules(files *protoregistry.Files) []Problem {
+	validator, err := protovalidate.New()
+	if err != nil {
+		return []Problem{{Description: fmt.Sprintf("failed to create validator: %v", err)}}
+	}
+
+	var problems []Problem
+	var checkMessages func(file string, msgs protoreflect.MessageDescriptors)
+	checkMessages = func(file string, msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			md := msgs.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+
+			var compileErr *protovalidate.CompilationError
+			if err := validator.Validate(dynamicpb.NewMessage(md)); errors.As(err, &compileErr) {
+				problems = append(problems, Problem{
+					File:        file,
+					Element:     string(md.FullName()),
+					Description: compileErr.Error(),
+				})
+			}
+
+			checkMessages(file, md.Messages())
+		}
+	}
+
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		checkMessages(fd.Path(), fd.Messages())
+		return true
+	})
+
+	return problems
+}
@@ -0,0 +1,245 @@
+package schemacheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	isrv1 "github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// createTestDescriptorSet builds a FileDescriptorSet from the isr.v1 package
+// and its transitive imports, dependencies first
+func createTestDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if visited[fd.Path()] {
+			return
+		}
+		visited[fd.Path()] = true
+
+		for i := 0; i < fd.Imports().Len(); i++ {
+			addFile(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	addFile(isrv1.File_isr_v1_isr_proto)
+
+	return fds
+}
+
+// createTestDescriptorBytes returns the serialized form of createTestDescriptorSet
+func createTestDescriptorBytes(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := proto.Marshal(createTestDescriptorSet(t))
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+// withTestFile appends a proto3 file importing buf/validate/validate.proto with a single
+// message holding one string field, optionally carrying the given field rules
+func withTestFile(t *testing.T, fds *descriptorpb.FileDescriptorSet, rules *validate.FieldRules) []byte {
+	t.Helper()
+
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("name"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		JsonName: proto.String("name"),
+	}
+	if rules != nil {
+		field.Options = &descriptorpb.FieldOptions{}
+		proto.SetExtension(field.Options, validate.E_Field, rules)
+	}
+
+	fds.File = append(fds.File, &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/test.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("TestMessage"),
+			Field: []*descriptorpb.FieldDescriptorProto{field},
+		}},
+	})
+
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return data
+}
+
+func TestCheck_Success(t *testing.T) {
+	files, err := Check(createTestDescriptorBytes(t))
+	if err != nil {
+		t.Fatalf("Check() error = %v, want nil", err)
+	}
+
+	if _, err := files.FindDescriptorByName("isr.v1.UploadSchemaRequest"); err != nil {
+		t.Errorf("FindDescriptorByName() error = %v, want nil", err)
+	}
+}
+
+func TestCheck_ValidRules(t *testing.T) {
+	data := withTestFile(t, createTestDescriptorSet(t), &validate.FieldRules{
+		Cel: []*validate.Rule{{
+			Id:         proto.String("name.not_admin"),
+			Message:    proto.String("name must not be admin"),
+			Expression: proto.String("this != 'admin'"),
+		}},
+	})
+
+	if _, err := Check(data); err != nil {
+		t.Fatalf("Check() error = %v, want nil", err)
+	}
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        func(t *testing.T) []byte
+		wantFile    string
+		wantElement string
+		wantDesc    string
+		wantCount   int // Number of problems; 0 means 1
+	}{
+		{
+			name:     "not a descriptor set",
+			data:     func(t *testing.T) []byte { return []byte("test schema data") },
+			wantDesc: "failed to parse FileDescriptorSet",
+		},
+		{
+			name:     "empty descriptor set",
+			data:     func(t *testing.T) []byte { return []byte{} },
+			wantDesc: "descriptor set contains no files",
+		},
+		{
+			name: "missing import",
+			data: func(t *testing.T) []byte {
+				fds := createTestDescriptorSet(t)
+				// Drop everything except isr/v1/isr.proto itself
+				fds.File = fds.File[len(fds.File)-1:]
+				data, err := proto.Marshal(fds)
+				if err != nil {
+					t.Fatalf("failed to marshal descriptor set: %v", err)
+				}
+				return data
+			},
+			wantFile:  "isr/v1/isr.proto",
+			wantDesc:  `import "buf/validate/validate.proto" is not included`,
+			wantCount: 2, // buf/validate/validate.proto and google/protobuf/timestamp.proto
+		},
+		{
+			name: "unresolvable type reference",
+			data: func(t *testing.T) []byte {
+				fds := createTestDescriptorSet(t)
+				fds.File = append(fds.File, &descriptorpb.FileDescriptorProto{
+					Name:    proto.String("test/v1/broken.proto"),
+					Package: proto.String("test.v1"),
+					Syntax:  proto.String("proto3"),
+					MessageType: []*descriptorpb.DescriptorProto{{
+						Name: proto.String("Broken"),
+						Field: []*descriptorpb.FieldDescriptorProto{{
+							Name:     proto.String("missing"),
+							Number:   proto.Int32(1),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+							TypeName: proto.String(".test.v1.DoesNotExist"),
+						}},
+					}},
+				})
+				data, err := proto.Marshal(fds)
+				if err != nil {
+					t.Fatalf("failed to marshal descriptor set: %v", err)
+				}
+				return data
+			},
+			wantDesc: "failed to build descriptors",
+		},
+		{
+			name: "CEL expression does not compile",
+			data: func(t *testing.T) []byte {
+				return withTestFile(t, createTestDescriptorSet(t), &validate.FieldRules{
+					Cel: []*validate.Rule{{
+						Id:         proto.String("name.broken"),
+						Expression: proto.String("this >"),
+					}},
+				})
+			},
+			wantFile:    "test/v1/test.proto",
+			wantElement: "test.v1.TestMessage",
+			wantDesc:    "name.broken",
+		},
+		{
+			name: "CEL expression has wrong output type",
+			data: func(t *testing.T) []byte {
+				return withTestFile(t, createTestDescriptorSet(t), &validate.FieldRules{
+					Cel: []*validate.Rule{{
+						Id:         proto.String("name.size"),
+						Expression: proto.String("size(this)"),
+					}},
+				})
+			},
+			wantFile:    "test/v1/test.proto",
+			wantElement: "test.v1.TestMessage",
+			wantDesc:    "name.size",
+		},
+		{
+			name: "rule type does not match field type",
+			data: func(t *testing.T) []byte {
+				return withTestFile(t, createTestDescriptorSet(t), &validate.FieldRules{
+					Type: &validate.FieldRules_Int32{Int32: &validate.Int32Rules{}},
+				})
+			},
+			wantFile:    "test/v1/test.proto",
+			wantElement: "test.v1.TestMessage",
+			wantDesc:    "compilation error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Check(tt.data(t))
+			if err == nil {
+				t.Fatal("Check() error = nil, want error")
+			}
+
+			var checkErr *Error
+			if !errors.As(err, &checkErr) {
+				t.Fatalf("error type = %T, want *Error", err)
+			}
+			wantCount := tt.wantCount
+			if wantCount == 0 {
+				wantCount = 1
+			}
+			if len(checkErr.Problems) != wantCount {
+				t.Fatalf("len(Problems) = %d, want %d: %v", len(checkErr.Problems), wantCount, checkErr.Problems)
+			}
+
+			got := checkErr.Problems[0]
+			if got.File != tt.wantFile {
+				t.Errorf("File = %q, want %q", got.File, tt.wantFile)
+			}
+			if got.Element != tt.wantElement {
+				t.Errorf("Element = %q, want %q", got.Element, tt.wantElement)
+			}
+			if !strings.Contains(got.Description, tt.wantDesc) {
+				t.Errorf("Description = %q, want to contain %q", got.Description, tt.wantDesc)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression identifies how a blob is encoded at rest
+const (
+	CompressionNone = "none"
+	CompressionZstd = "zstd"
+)
+
+// maxBlobSize bounds the memory used to decode a blob. UploadSchema accepts at most 10MB.
+const maxBlobSize = 64 << 20
+
+var (
+	// EncodeAll and DecodeAll are safe for concurrent use
+	blobEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	blobDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxBlobSize))
+)
+
+// EncodeBlob compresses a schema binary for storage and returns it with its compression.
+// Data that does not shrink is stored as is.
+func EncodeBlob(data []byte) ([]byte, string) {
+	compressed := blobEncoder.EncodeAll(data, nil)
+	if len(compressed) >= len(data) {
+		return data, CompressionNone
+	}
+	return compressed, CompressionZstd
+}
+
+// DecodeBlob returns the schema binary stored by EncodeBlob
+func DecodeBlob(stored []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return stored, nil
+	case CompressionZstd:
+		data, err := blobDecoder.DecodeAll(stored, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress blob: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown blob compression %q", compression)
+	}
+}
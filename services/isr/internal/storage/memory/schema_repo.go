@@ -0,0 +1,501 @@
+// Package memory implements storage.SchemaRepository in memory, optionally persisted to a directory.
+//
+// With a directory, each version's metadata is written to schemas/<version>.json, binaries to
+// blobs/<sha256>.binpb (blobs/<sha256>.binpb.zst when compressed) and tags to tags.json, and everything is loaded back on Open. It is meant for local development and tests;
+// the whole registry is held in memory.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+)
+
+type SchemaRepository struct {
+	mu      sync.RWMutex
+	dir     string                   // Empty when not persisted
+	schemas map[string]*model.Schema // By version, without SchemaBinary
+	blobs   map[string]blob          // By content hash
+	tags    map[string]model.SchemaTag
+
+	listenMu     sync.Mutex
+	listeners    map[int]func(major, minor int32)
+	nextListener int
+}
+
+// blob is a schema binary as stored by storage.EncodeBlob
+type blob struct {
+	data        []byte
+	compression string
+}
+
+// blobExtensions maps each compression to the file extension of its persisted blobs
+var blobExtensions = map[string]string{
+	storage.CompressionNone: ".binpb",
+	storage.CompressionZstd: ".binpb.zst",
+}
+
+var (
+	_ storage.SchemaRepository = (*SchemaRepository)(nil)
+	_ storage.ChangeNotifier   = (*SchemaRepository)(nil)
+)
+
+// New returns an empty repository that is not persisted
+func New() *SchemaRepository {
+	return &SchemaRepository{
+		schemas: make(map[string]*model.Schema),
+		blobs:   make(map[string]blob),
+		tags:    make(map[string]model.SchemaTag),
+
+		listeners: make(map[int]func(major, minor int32)),
+	}
+}
+
+// Open loads the repository persisted in dir, creating the directory if needed
+func Open(dir string) (*SchemaRepository, error) {
+	r := New()
+	r.dir = dir
+	for _, sub := range []string{"schemas", "blobs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "schemas", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	for _, path := range paths {
+		var schema model.Schema
+		if err := readJSON(path, &schema); err != nil {
+			return nil, err
+		}
+		// Directories written before blobs were split out embed the binary
+		if schema.SchemaBinary != nil {
+			if err := r.persistBlob(schema.ContentHash, blob{schema.SchemaBinary, storage.CompressionNone}); err != nil {
+				return nil, err
+			}
+			schema.SchemaBinary = nil
+			if err := r.persistSchema(&schema); err != nil {
+				return nil, err
+			}
+		}
+		r.schemas[schema.Version] = &schema
+	}
+
+	for compression, ext := range blobExtensions {
+		blobPaths, err := filepath.Glob(filepath.Join(dir, "blobs", "*"+ext))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs: %w", err)
+		}
+		for _, path := range blobPaths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			r.blobs[strings.TrimSuffix(filepath.Base(path), ext)] = blob{data, compression}
+		}
+	}
+
+	if err := readJSON(filepath.Join(dir, "tags.json"), &r.tags); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Create stores a new schema
+func (r *SchemaRepository) Create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schemas[schema.Version]; ok {
+		return fmt.Errorf("failed to insert schema: %w", storage.ErrAlreadyExists)
+	}
+	if !allowOutOfOrder {
+		var latest *model.Schema
+		for _, s := range r.schemas {
+			if s.Major == schema.Major && s.Minor == schema.Minor && compare(s, schema) > 0 &&
+				(latest == nil || compare(s, latest) > 0) {
+				latest = s
+			}
+		}
+		if latest != nil {
+			return fmt.Errorf("failed to insert schema: %w", &storage.OutOfOrderError{Version: schema.Version, Latest: latest.Version})
+		}
+	}
+	stored := *schema
+	stored.SchemaBinary = nil
+	stored.StoredSizeBytes = 0
+	if stored.Status == "" {
+		stored.Status = model.StatusActive
+	}
+
+	// Identical descriptor sets share one blob
+	if _, ok := r.blobs[schema.ContentHash]; !ok {
+		data, compression := storage.EncodeBlob(schema.SchemaBinary)
+		b := blob{slices.Clone(data), compression}
+		if err := r.persistBlob(schema.ContentHash, b); err != nil {
+			return fmt.Errorf("failed to insert schema: %w", err)
+		}
+		r.blobs[schema.ContentHash] = b
+	}
+	if err := r.persistSchema(&stored); err != nil {
+		return fmt.Errorf("failed to insert schema: %w", err)
+	}
+	r.schemas[stored.Version] = &stored
+	schema.StoredSizeBytes = int32(len(r.blobs[schema.ContentHash].data))
+	r.notify(stored.Major, stored.Minor)
+	return nil
+}
+
+// GetByVersion retrieves a schema by its version
+func (r *SchemaRepository) GetByVersion(ctx context.Context, version string) (*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schema, ok := r.schemas[version]
+	if !ok {
+		return nil, fmt.Errorf("failed to get schema by version: %w", storage.ErrNotFound)
+	}
+	schema, err := r.withBlob(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema by version: %w", err)
+	}
+	return schema, nil
+}
+
+// GetLatestPatch retrieves the latest patch version for a given major.minor.
+// Pre-releases are only considered when includePreRelease is set.
+func (r *SchemaRepository) GetLatestPatch(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
+	latest, err := r.latest(func(s *model.Schema) bool {
+		return s.Major == major && s.Minor == minor && (includePreRelease || s.PreRelease == "")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest patch: %w", err)
+	}
+	return latest, nil
+}
+
+// GetLatestBeforeMinor retrieves the latest release in the given major whose minor is lower than minor
+func (r *SchemaRepository) GetLatestBeforeMinor(ctx context.Context, major, minor int32) (*model.Schema, error) {
+	latest, err := r.latest(func(s *model.Schema) bool {
+		return s.Major == major && s.Minor < minor && s.PreRelease == ""
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest version before minor: %w", err)
+	}
+	return latest, nil
+}
+
+// latest returns a copy of the highest non-yanked version matching fn, or storage.ErrNotFound
+func (r *SchemaRepository) latest(fn func(*model.Schema) bool) (*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *model.Schema
+	for _, s := range r.schemas {
+		if s.Status != model.StatusYanked && fn(s) && (latest == nil || compare(s, latest) > 0) {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return nil, storage.ErrNotFound
+	}
+	return r.withBlob(latest)
+}
+
+// List retrieves schema metadata matching filter, newest version first. SchemaBinary is not loaded.
+func (r *SchemaRepository) List(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var before *model.Schema
+	if filter.Before != nil {
+		before = &model.Schema{
+			Major:      filter.Before.Major,
+			Minor:      filter.Before.Minor,
+			Patch:      filter.Before.Patch,
+			PreRelease: filter.Before.PreRelease,
+		}
+	}
+
+	var schemas []*model.Schema
+	for _, s := range r.schemas {
+		switch {
+		case filter.Major != nil && s.Major != *filter.Major,
+			filter.Minor != nil && s.Minor != *filter.Minor,
+			filter.CreatedAfter != nil && s.CreatedAt.Before(*filter.CreatedAfter),
+			filter.CreatedBefore != nil && !s.CreatedAt.Before(*filter.CreatedBefore),
+			before != nil && compare(s, before) >= 0:
+			continue
+		}
+		metadata := *s
+		metadata.StoredSizeBytes = int32(len(r.blobs[s.ContentHash].data))
+		schemas = append(schemas, &metadata)
+	}
+
+	slices.SortFunc(schemas, func(a, b *model.Schema) int { return compare(b, a) })
+	if filter.Limit > 0 && len(schemas) > filter.Limit {
+		schemas = schemas[:filter.Limit]
+	}
+	return schemas, nil
+}
+
+// ListSeries summarizes each major.minor, newest first. If major is non-nil only that major is listed.
+func (r *SchemaRepository) ListSeries(ctx context.Context, major *int32) ([]*model.SchemaSeries, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type key struct{ major, minor int32 }
+	seriesByKey := make(map[key]*model.SchemaSeries)
+	for _, s := range r.schemas {
+		if (major != nil && s.Major != *major) || s.Status == model.StatusYanked || s.PreRelease != "" {
+			continue
+		}
+		k := key{s.Major, s.Minor}
+		series, ok := seriesByKey[k]
+		if !ok {
+			series = &model.SchemaSeries{Major: s.Major, Minor: s.Minor}
+			seriesByKey[k] = series
+		}
+		series.LatestPatch = max(series.LatestPatch, s.Patch)
+		series.PatchCount++
+	}
+
+	var series []*model.SchemaSeries
+	for _, s := range seriesByKey {
+		series = append(series, s)
+	}
+	slices.SortFunc(series, func(a, b *model.SchemaSeries) int {
+		return cmp.Or(cmp.Compare(b.Major, a.Major), cmp.Compare(b.Minor, a.Minor))
+	})
+	return series, nil
+}
+
+// UpdateStatus sets the status of a version. Returns storage.ErrNotFound if the version does not exist.
+func (r *SchemaRepository) UpdateStatus(ctx context.Context, version string, status model.SchemaStatus, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schema, ok := r.schemas[version]
+	if !ok {
+		return fmt.Errorf("failed to update schema status: %w", storage.ErrNotFound)
+	}
+	updated := *schema
+	updated.Status = status
+	updated.StatusReason = reason
+	if err := r.persistSchema(&updated); err != nil {
+		return fmt.Errorf("failed to update schema status: %w", err)
+	}
+	r.schemas[version] = &updated
+	r.notify(updated.Major, updated.Minor)
+	return nil
+}
+
+// SetTag points a tag at a schema, creating the tag if it does not exist
+func (r *SchemaRepository) SetTag(ctx context.Context, tag *model.SchemaTag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schema := r.byID(tag.SchemaID)
+	if schema == nil {
+		return fmt.Errorf("failed to set schema tag: schema %s does not exist", tag.SchemaID)
+	}
+	tags := make(map[string]model.SchemaTag, len(r.tags)+1)
+	for name, t := range r.tags {
+		tags[name] = t
+	}
+	tags[tag.Name] = *tag
+	if r.dir != "" {
+		if err := writeJSON(filepath.Join(r.dir, "tags.json"), tags); err != nil {
+			return fmt.Errorf("failed to set schema tag: %w", err)
+		}
+	}
+	r.tags = tags
+	r.notify(schema.Major, schema.Minor)
+	return nil
+}
+
+// GetByTag retrieves the schema a tag points to
+func (r *SchemaRepository) GetByTag(ctx context.Context, tag string) (*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tags[tag]
+	if !ok {
+		return nil, fmt.Errorf("failed to get schema by tag: %w", storage.ErrNotFound)
+	}
+	schema := r.byID(t.SchemaID)
+	if schema == nil {
+		return nil, fmt.Errorf("failed to get schema by tag: %w", storage.ErrNotFound)
+	}
+	schema, err := r.withBlob(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema by tag: %w", err)
+	}
+	return schema, nil
+}
+
+// GetBlob retrieves a schema binary by the hex SHA-256 of its content
+func (r *SchemaRepository) GetBlob(ctx context.Context, hash string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.blobs[hash]
+	if !ok {
+		return nil, fmt.Errorf("failed to get schema blob: %w", storage.ErrNotFound)
+	}
+	data, err := b.decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema blob: %w", err)
+	}
+	return data, nil
+}
+
+// VersionExists checks if a version already exists
+func (r *SchemaRepository) VersionExists(ctx context.Context, version string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.schemas[version]
+	return ok, nil
+}
+
+// Listen delivers the changes of this repository to handlers sharing it in one process.
+// onChange is called while the repository is locked.
+func (r *SchemaRepository) Listen(ctx context.Context, onListen func(), onChange func(major, minor int32)) error {
+	r.listenMu.Lock()
+	id := r.nextListener
+	r.nextListener++
+	r.listeners[id] = onChange
+	r.listenMu.Unlock()
+
+	defer func() {
+		r.listenMu.Lock()
+		defer r.listenMu.Unlock()
+		delete(r.listeners, id)
+	}()
+
+	onListen()
+	<-ctx.Done()
+	return nil
+}
+
+// notify calls every listener with a changed major.minor
+func (r *SchemaRepository) notify(major, minor int32) {
+	r.listenMu.Lock()
+	defer r.listenMu.Unlock()
+	for _, onChange := range r.listeners {
+		onChange(major, minor)
+	}
+}
+
+func (r *SchemaRepository) byID(id string) *model.Schema {
+	for _, s := range r.schemas {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// persistSchema writes schema to disk when the repository is persisted
+func (r *SchemaRepository) persistSchema(schema *model.Schema) error {
+	if r.dir == "" {
+		return nil
+	}
+	return writeJSON(filepath.Join(r.dir, "schemas", schema.Version+".json"), schema)
+}
+
+// persistBlob writes a blob to disk when the repository is persisted
+func (r *SchemaRepository) persistBlob(hash string, b blob) error {
+	if r.dir == "" {
+		return nil
+	}
+	return writeFile(filepath.Join(r.dir, "blobs", hash+blobExtensions[b.compression]), b.data)
+}
+
+// compare orders schemas by SemVer precedence, the same way as the SQL backends
+func compare(a, b *model.Schema) int {
+	return cmp.Or(
+		cmp.Compare(a.Major, b.Major),
+		cmp.Compare(a.Minor, b.Minor),
+		cmp.Compare(a.Patch, b.Patch),
+		strings.Compare(version.PreReleaseKey(a.PreRelease), version.PreReleaseKey(b.PreRelease)),
+	)
+}
+
+// withBlob returns a copy of schema with its uncompressed binary
+func (r *SchemaRepository) withBlob(schema *model.Schema) (*model.Schema, error) {
+	b := r.blobs[schema.ContentHash]
+	data, err := b.decode()
+	if err != nil {
+		return nil, err
+	}
+	c := *schema
+	c.SchemaBinary = data
+	c.StoredSizeBytes = int32(len(b.data))
+	return &c, nil
+}
+
+// decode returns a copy of the uncompressed binary
+func (b blob) decode() ([]byte, error) {
+	data, err := storage.DecodeBlob(b.data, b.compression)
+	if err != nil {
+		return nil, err
+	}
+	if b.compression == storage.CompressionNone {
+		data = slices.Clone(data)
+	}
+	return data, nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces path atomically so that a crash never leaves a partial file behind
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
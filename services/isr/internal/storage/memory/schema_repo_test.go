@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/storagetest"
+)
+
+func TestSchemaRepository_Conformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.SchemaRepository {
+			return New()
+		})
+	})
+	t.Run("filesystem", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.SchemaRepository {
+			repo, err := Open(t.TempDir())
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			return repo
+		})
+	})
+}
+
+func TestSchemaRepository_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.Create(ctx, &model.Schema{
+		ID:           "id-1.0.0",
+		Version:      "1.0.0",
+		Major:        1,
+		SchemaBinary: []byte("binary"),
+		SizeBytes:    6,
+		ContentHash:  storagetest.ContentHash([]byte("binary")),
+		CreatedAt:    createdAt,
+	}, false); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Stored as blobs/<hash>.binpb.zst
+	compressible := []byte(strings.Repeat("binary", 1000))
+	if err := repo.Create(ctx, &model.Schema{
+		ID:           "id-1.0.1",
+		Version:      "1.0.1",
+		Major:        1,
+		Patch:        1,
+		SchemaBinary: compressible,
+		SizeBytes:    int32(len(compressible)),
+		ContentHash:  storagetest.ContentHash(compressible),
+		CreatedAt:    createdAt,
+	}, false); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.UpdateStatus(ctx, "1.0.0", model.StatusDeprecated, "use 1.1"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if err := repo.SetTag(ctx, &model.SchemaTag{Name: "prod", SchemaID: "id-1.0.0", UpdatedAt: createdAt}); err != nil {
+		t.Fatalf("SetTag failed: %v", err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	schema, err := reopened.GetByTag(ctx, "prod")
+	if err != nil {
+		t.Fatalf("GetByTag after reopening failed: %v", err)
+	}
+	if schema.Version != "1.0.0" || string(schema.SchemaBinary) != "binary" || !schema.CreatedAt.Equal(createdAt) {
+		t.Errorf("reopened schema = %s (%q, %v), want 1.0.0", schema.Version, schema.SchemaBinary, schema.CreatedAt)
+	}
+	if schema.Status != model.StatusDeprecated || schema.StatusReason != "use 1.1" {
+		t.Errorf("reopened status = %s (%q), want deprecated", schema.Status, schema.StatusReason)
+	}
+
+	compressed, err := reopened.GetByVersion(ctx, "1.0.1")
+	if err != nil {
+		t.Fatalf("GetByVersion after reopening failed: %v", err)
+	}
+	if string(compressed.SchemaBinary) != string(compressible) || compressed.StoredSizeBytes >= compressed.SizeBytes {
+		t.Errorf("reopened 1.0.1 = %d bytes (%d stored), want the uncompressed binary stored compressed",
+			len(compressed.SchemaBinary), compressed.StoredSizeBytes)
+	}
+}
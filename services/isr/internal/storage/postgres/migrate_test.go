@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int
+		wantErr string
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"m/0010_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t(a);")},
+				"m/0010_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+				"m/0002_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+				"m/0002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+			},
+			want: []int{2, 10},
+		},
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"m/0001_create_table.up.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+			},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"m/0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+				"m/0001_create_other.down.sql": {Data: []byte("DROP TABLE t;")},
+			},
+			wantErr: "has two names",
+		},
+		{
+			name: "invalid file name",
+			files: fstest.MapFS{
+				"m/create_table.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+			},
+			wantErr: "invalid migration file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files, "m")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadMigrations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations() error = %v", err)
+			}
+			var got []int
+			for _, m := range migrations {
+				got = append(got, m.Version)
+				if len(m.Checksum) != 64 {
+					t.Errorf("migration %d checksum = %q, want SHA-256 hex", m.Version, m.Checksum)
+				}
+			}
+			if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+				t.Errorf("versions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d_%s: versions must be numbered from 1 without gaps", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	pool := setupTestDB(t) // Applies every migration
+	ctx := context.Background()
+
+	// Running again is a no-op
+	if err := Migrate(ctx, pool); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	states, err := MigrationStatus(ctx, pool)
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, s := range states {
+		if s.AppliedAt == nil || s.ChecksumMismatch {
+			t.Errorf("migration %d_%s: applied = %v, checksum mismatch = %v", s.Version, s.Name, s.AppliedAt, s.ChecksumMismatch)
+		}
+	}
+
+	// Revert the tags and blobs tables, then apply them again
+	if err := MigrateDown(ctx, pool, 4); err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_tags') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatalf("failed to check schema_tags: %v", err)
+	}
+	if exists {
+		t.Error("schema_tags still exists after MigrateDown(4)")
+	}
+	if err := Migrate(ctx, pool); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// An applied migration that was edited afterwards is refused
+	if _, err := pool.Exec(ctx, `UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`); err != nil {
+		t.Fatalf("failed to update checksum: %v", err)
+	}
+	if err := Migrate(ctx, pool); err == nil || !strings.Contains(err.Error(), "modified after it was applied") {
+		t.Errorf("Migrate() error = %v, want checksum mismatch", err)
+	}
+	states, err = MigrationStatus(ctx, pool)
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	if !states[0].ChecksumMismatch {
+		t.Error("MigrationStatus did not report the checksum mismatch")
+	}
+}
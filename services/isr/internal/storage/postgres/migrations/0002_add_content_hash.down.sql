@@ -0,0 +1 @@
+ALTER TABLE schemas DROP COLUMN IF EXISTS content_hash;
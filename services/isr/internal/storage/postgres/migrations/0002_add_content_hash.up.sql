@@ -0,0 +1,4 @@
+-- SHA-256 of schema_binary, backfilled for schemas uploaded before it existed
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+UPDATE schemas SET content_hash = encode(sha256(schema_binary), 'hex') WHERE content_hash IS NULL;
+ALTER TABLE schemas ALTER COLUMN content_hash SET NOT NULL;
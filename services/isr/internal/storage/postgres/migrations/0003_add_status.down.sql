@@ -0,0 +1,2 @@
+ALTER TABLE schemas DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE schemas DROP COLUMN IF EXISTS status;
@@ -0,0 +1,3 @@
+-- Lifecycle status: active, deprecated or yanked
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
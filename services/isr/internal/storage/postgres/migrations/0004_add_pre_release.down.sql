@@ -0,0 +1,5 @@
+-- Fails if a stored version no longer fits; yank or delete those versions first
+ALTER TABLE schemas DROP COLUMN IF EXISTS build_metadata;
+ALTER TABLE schemas DROP COLUMN IF EXISTS pre_release_key;
+ALTER TABLE schemas DROP COLUMN IF EXISTS pre_release;
+ALTER TABLE schemas ALTER COLUMN version TYPE VARCHAR(20);
@@ -0,0 +1,6 @@
+-- SemVer 2.0 pre-release and build metadata. version holds the version without build metadata.
+-- pre_release_key sorts pre-releases by precedence below their release ('~'); see version.PreReleaseKey
+ALTER TABLE schemas ALTER COLUMN version TYPE VARCHAR(128);
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS pre_release VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS pre_release_key TEXT COLLATE "C" NOT NULL DEFAULT '~';
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS build_metadata VARCHAR(128) NOT NULL DEFAULT '';
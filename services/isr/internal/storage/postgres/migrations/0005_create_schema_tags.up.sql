@@ -0,0 +1,6 @@
+-- Named tags (e.g., "staging", "prod") pointing at a version; promoting moves the pointer
+CREATE TABLE IF NOT EXISTS schema_tags (
+	name VARCHAR(64) PRIMARY KEY,
+	schema_id VARCHAR(36) NOT NULL REFERENCES schemas(id),
+	updated_at TIMESTAMP NOT NULL
+);
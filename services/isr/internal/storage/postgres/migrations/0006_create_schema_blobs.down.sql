@@ -0,0 +1,6 @@
+ALTER TABLE schemas ADD COLUMN schema_binary BYTEA;
+UPDATE schemas SET schema_binary = b.data FROM schema_blobs b WHERE b.hash = schemas.content_hash;
+ALTER TABLE schemas ALTER COLUMN schema_binary SET NOT NULL;
+
+ALTER TABLE schemas DROP CONSTRAINT fk_schemas_content_hash;
+DROP TABLE schema_blobs;
@@ -0,0 +1,12 @@
+-- Content-addressed schema binaries: versions with identical descriptor sets share one row
+CREATE TABLE schema_blobs (
+	hash VARCHAR(64) PRIMARY KEY, -- Hex-encoded SHA-256 of data
+	data BYTEA NOT NULL
+);
+
+INSERT INTO schema_blobs (hash, data)
+SELECT DISTINCT ON (content_hash) content_hash, schema_binary FROM schemas
+ON CONFLICT (hash) DO NOTHING;
+
+ALTER TABLE schemas ADD CONSTRAINT fk_schemas_content_hash FOREIGN KEY (content_hash) REFERENCES schema_blobs(hash);
+ALTER TABLE schemas DROP COLUMN schema_binary;
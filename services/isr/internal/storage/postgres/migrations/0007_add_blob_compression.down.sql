@@ -0,0 +1,9 @@
+-- Compressed blobs cannot be decoded without the column
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM schema_blobs WHERE compression <> 'none') THEN
+		RAISE EXCEPTION 'schema_blobs contains compressed blobs';
+	END IF;
+END $$;
+
+ALTER TABLE schema_blobs DROP COLUMN compression;
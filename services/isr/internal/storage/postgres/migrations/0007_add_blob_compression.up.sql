@@ -0,0 +1,2 @@
+-- How schema_blobs.data is encoded at rest. Existing blobs stay uncompressed.
+ALTER TABLE schema_blobs ADD COLUMN compression VARCHAR(16) NOT NULL DEFAULT 'none';
@@ -0,0 +1 @@
+ALTER TABLE schemas DROP COLUMN signature;
@@ -0,0 +1,3 @@
+-- Detached ed25519 signature of the schema binary. It belongs to the version rather than the blob,
+-- since identical binaries may be signed by different keys. NULL for unsigned versions.
+ALTER TABLE schemas ADD COLUMN signature BYTEA;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_schemas_semver;
+CREATE INDEX idx_schemas_semver ON schemas(major DESC, minor DESC, patch DESC);
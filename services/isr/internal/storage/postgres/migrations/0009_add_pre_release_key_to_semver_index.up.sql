@@ -0,0 +1,4 @@
+-- List orders and pages by (major, minor, patch, pre_release_key); index the whole key so that
+-- the keyset cursor and the ORDER BY are served by the index
+DROP INDEX IF EXISTS idx_schemas_semver;
+CREATE INDEX idx_schemas_semver ON schemas(major DESC, minor DESC, patch DESC, pre_release_key DESC);
@@ -0,0 +1,425 @@
+// Package postgres implements storage.SchemaRepository on PostgreSQL
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+)
+
+type SchemaRepository struct {
+	pool *pgxpool.Pool
+}
+
+var (
+	_ storage.SchemaRepository = (*SchemaRepository)(nil)
+	_ storage.ChangeNotifier   = (*SchemaRepository)(nil)
+)
+
+func NewSchemaRepository(pool *pgxpool.Pool) *SchemaRepository {
+	return &SchemaRepository{pool: pool}
+}
+
+// queryError wraps err, translating pgx.ErrNoRows to storage.ErrNotFound
+func queryError(msg string, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = storage.ErrNotFound
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
+// changesChannel is the NOTIFY channel on which every change announces its major.minor (e.g. "1.2").
+// Notifications are sent in the transaction of the change and delivered to every replica on commit.
+const changesChannel = "isr_schema_changes"
+
+const schemaColumns = `id, version, major, minor, patch, pre_release, build_metadata, data, compression,
+	size_bytes, octet_length(data), content_hash, signature, status, status_reason, created_at`
+
+// metadataColumns are schemaColumns with an empty, uncompressed blob in place of data and compression,
+// for listings that do not load schema binaries. scanSchema reads both.
+const metadataColumns = `id, version, major, minor, patch, pre_release, build_metadata, NULL, 'none',
+	size_bytes, octet_length(data), content_hash, signature, status, status_reason, created_at`
+
+// schemasWithBlobs is the FROM clause for queries selecting schemaColumns or metadataColumns
+const schemasWithBlobs = `schemas JOIN schema_blobs ON hash = content_hash`
+
+// scanSchema scans the columns in schemaColumns order and decompresses the blob
+func scanSchema(row pgx.Row) (*model.Schema, error) {
+	var schema model.Schema
+	var compression string
+	if err := row.Scan(
+		&schema.ID,
+		&schema.Version,
+		&schema.Major,
+		&schema.Minor,
+		&schema.Patch,
+		&schema.PreRelease,
+		&schema.BuildMetadata,
+		&schema.SchemaBinary,
+		&compression,
+		&schema.SizeBytes,
+		&schema.StoredSizeBytes,
+		&schema.ContentHash,
+		&schema.Signature,
+		&schema.Status,
+		&schema.StatusReason,
+		&schema.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	data, err := storage.DecodeBlob(schema.SchemaBinary, compression)
+	if err != nil {
+		return nil, err
+	}
+	schema.SchemaBinary = data
+	return &schema, nil
+}
+
+// Create inserts a new schema into the database. Uploads to the same major.minor are serialized
+// with a transaction-level advisory lock, so that the ordering check cannot race with another insert.
+func (r *SchemaRepository) Create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+	query := `
+		INSERT INTO schemas (id, version, major, minor, patch, pre_release, pre_release_key, build_metadata,
+			size_bytes, content_hash, signature, status, status_reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	status := schema.Status
+	if status == "" {
+		status = model.StatusActive
+	}
+	preReleaseKey := version.PreReleaseKey(schema.PreRelease)
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, schema.Major, schema.Minor); err != nil {
+			return err
+		}
+
+		if !allowOutOfOrder {
+			var latest string
+			err := tx.QueryRow(ctx, `
+				SELECT version
+				FROM schemas
+				WHERE major = $1 AND minor = $2 AND (patch, pre_release_key) > ($3, $4)
+				ORDER BY patch DESC, pre_release_key DESC
+				LIMIT 1
+			`, schema.Major, schema.Minor, schema.Patch, preReleaseKey).Scan(&latest)
+			if err == nil {
+				return &storage.OutOfOrderError{Version: schema.Version, Latest: latest}
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
+
+		// Identical descriptor sets share one blob
+		data, compression := storage.EncodeBlob(schema.SchemaBinary)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO schema_blobs (hash, data, compression) VALUES ($1, $2, $3)
+			ON CONFLICT (hash) DO NOTHING
+		`, schema.ContentHash, data, compression)
+		if err != nil {
+			return err
+		}
+		// The blob may predate this upload and be encoded differently
+		err = tx.QueryRow(ctx, `SELECT octet_length(data) FROM schema_blobs WHERE hash = $1`, schema.ContentHash).
+			Scan(&schema.StoredSizeBytes)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, query,
+			schema.ID,
+			schema.Version,
+			schema.Major,
+			schema.Minor,
+			schema.Patch,
+			schema.PreRelease,
+			preReleaseKey,
+			schema.BuildMetadata,
+			schema.SizeBytes,
+			schema.ContentHash,
+			schema.Signature,
+			status,
+			schema.StatusReason,
+			schema.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, changesChannel, fmt.Sprintf("%d.%d", schema.Major, schema.Minor))
+		return err
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			err = storage.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert schema: %w", err)
+	}
+	return nil
+}
+
+// GetByVersion retrieves a schema by its version
+func (r *SchemaRepository) GetByVersion(ctx context.Context, version string) (*model.Schema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM ` + schemasWithBlobs + ` WHERE version = $1`
+	schema, err := scanSchema(r.pool.QueryRow(ctx, query, version))
+	if err != nil {
+		return nil, queryError("failed to get schema by version", err)
+	}
+	return schema, nil
+}
+
+// GetLatestPatch retrieves the latest patch version for a given major.minor.
+// Pre-releases are only considered when includePreRelease is set.
+func (r *SchemaRepository) GetLatestPatch(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE major = $1 AND minor = $2 AND status <> 'yanked' AND ($3 OR pre_release = '')
+		ORDER BY patch DESC, pre_release_key DESC
+		LIMIT 1
+	`
+	schema, err := scanSchema(r.pool.QueryRow(ctx, query, major, minor, includePreRelease))
+	if err != nil {
+		return nil, queryError("failed to get latest patch", err)
+	}
+	return schema, nil
+}
+
+// GetLatestBeforeMinor retrieves the latest release in the given major whose minor is lower than minor
+func (r *SchemaRepository) GetLatestBeforeMinor(ctx context.Context, major, minor int32) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE major = $1 AND minor < $2 AND status <> 'yanked' AND pre_release = ''
+		ORDER BY minor DESC, patch DESC
+		LIMIT 1
+	`
+	schema, err := scanSchema(r.pool.QueryRow(ctx, query, major, minor))
+	if err != nil {
+		return nil, queryError("failed to get latest version before minor", err)
+	}
+	return schema, nil
+}
+
+// List retrieves schema metadata matching filter, newest version first.
+// The blob is not loaded. The ordering and the keyset cursor on (major, minor, patch, pre_release_key)
+// use idx_schemas_semver; pre_release_key orders the pre-releases of a patch below the release.
+func (r *SchemaRepository) List(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(format string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(format, placeholders...))
+	}
+
+	if filter.Major != nil {
+		addCondition("major = %s", *filter.Major)
+	}
+	if filter.Minor != nil {
+		addCondition("minor = %s", *filter.Minor)
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= %s", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at < %s", *filter.CreatedBefore)
+	}
+	if filter.Before != nil {
+		addCondition("(major, minor, patch, pre_release_key) < (%s, %s, %s, %s)",
+			filter.Before.Major, filter.Before.Minor, filter.Before.Patch, version.PreReleaseKey(filter.Before.PreRelease))
+	}
+
+	query := `SELECT ` + metadataColumns + ` FROM ` + schemasWithBlobs
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY major DESC, minor DESC, patch DESC, pre_release_key DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []*model.Schema
+	for rows.Next() {
+		schema, err := scanSchema(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// ListSeries summarizes each major.minor, newest first. If major is non-nil only that major is listed.
+func (r *SchemaRepository) ListSeries(ctx context.Context, major *int32) ([]*model.SchemaSeries, error) {
+	query := `
+		SELECT major, minor, MAX(patch) AS latest_patch, COUNT(*) AS patch_count
+		FROM schemas
+		WHERE ($1::INTEGER IS NULL OR major = $1) AND status <> 'yanked' AND pre_release = ''
+		GROUP BY major, minor
+		ORDER BY major DESC, minor DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, major)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema series: %w", err)
+	}
+	defer rows.Close()
+
+	var series []*model.SchemaSeries
+	for rows.Next() {
+		var s model.SchemaSeries
+		if err := rows.Scan(&s.Major, &s.Minor, &s.LatestPatch, &s.PatchCount); err != nil {
+			return nil, fmt.Errorf("failed to scan schema series: %w", err)
+		}
+		series = append(series, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list schema series: %w", err)
+	}
+	return series, nil
+}
+
+// UpdateStatus sets the status of a version. Returns storage.ErrNotFound if the version does not exist.
+func (r *SchemaRepository) UpdateStatus(ctx context.Context, version string, status model.SchemaStatus, reason string) error {
+	query := `
+		WITH updated AS (
+			UPDATE schemas SET status = $2, status_reason = $3 WHERE version = $1
+			RETURNING major, minor
+		)
+		SELECT pg_notify($4, format('%s.%s', major, minor)) FROM updated
+	`
+	tag, err := r.pool.Exec(ctx, query, version, status, reason, changesChannel)
+	if err != nil {
+		return fmt.Errorf("failed to update schema status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update schema status: %w", storage.ErrNotFound)
+	}
+	return nil
+}
+
+// SetTag points a tag at a schema, creating the tag if it does not exist
+func (r *SchemaRepository) SetTag(ctx context.Context, tag *model.SchemaTag) error {
+	query := `
+		WITH tagged AS (
+			INSERT INTO schema_tags (name, schema_id, updated_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE SET schema_id = EXCLUDED.schema_id, updated_at = EXCLUDED.updated_at
+			RETURNING schema_id
+		)
+		SELECT pg_notify($4, format('%s.%s', major, minor)) FROM tagged JOIN schemas ON id = schema_id
+	`
+	if _, err := r.pool.Exec(ctx, query, tag.Name, tag.SchemaID, tag.UpdatedAt, changesChannel); err != nil {
+		return fmt.Errorf("failed to set schema tag: %w", err)
+	}
+	return nil
+}
+
+// GetByTag retrieves the schema a tag points to
+func (r *SchemaRepository) GetByTag(ctx context.Context, tag string) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE id = (SELECT schema_id FROM schema_tags WHERE name = $1)
+	`
+	schema, err := scanSchema(r.pool.QueryRow(ctx, query, tag))
+	if err != nil {
+		return nil, queryError("failed to get schema by tag", err)
+	}
+	return schema, nil
+}
+
+// GetBlob retrieves a schema binary by the hex SHA-256 of its content
+func (r *SchemaRepository) GetBlob(ctx context.Context, hash string) ([]byte, error) {
+	var stored []byte
+	var compression string
+	err := r.pool.QueryRow(ctx, `SELECT data, compression FROM schema_blobs WHERE hash = $1`, hash).Scan(&stored, &compression)
+	if err != nil {
+		return nil, queryError("failed to get schema blob", err)
+	}
+	data, err := storage.DecodeBlob(stored, compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema blob: %w", err)
+	}
+	return data, nil
+}
+
+// VersionExists checks if a version already exists
+func (r *SchemaRepository) VersionExists(ctx context.Context, version string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM schemas WHERE version = $1)`
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check version existence: %w", err)
+	}
+	return exists, nil
+}
+
+// Listen receives the changes committed by every ISR replica over LISTEN/NOTIFY.
+// It holds a dedicated connection, which is taken out of the pool.
+func (r *SchemaRepository) Listen(ctx context.Context, onListen func(), onChange func(major, minor int32)) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to acquire a connection to listen on: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, `LISTEN `+changesChannel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to listen for schema changes: %w", err)
+	}
+	onListen()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for schema changes: %w", err)
+		}
+		var major, minor int32
+		if _, err := fmt.Sscanf(notification.Payload, "%d.%d", &major, &minor); err != nil {
+			continue // Not sent by ISR
+		}
+		onChange(major, minor)
+	}
+}
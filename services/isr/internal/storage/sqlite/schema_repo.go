@@ -0,0 +1,467 @@
+// Package sqlite implements storage.SchemaRepository on a SQLite database file,
+// for local development without PostgreSQL
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+)
+
+// migrations mirror the PostgreSQL migrations. PRAGMA user_version records how many have been applied;
+// the first one uses IF NOT EXISTS to adopt databases created before user_version was tracked.
+// Timestamps are Unix nanoseconds in UTC, so that they sort as integers;
+// pre_release_key uses the default BINARY collation, which sorts like COLLATE "C".
+var migrations = []string{
+	`
+	CREATE TABLE IF NOT EXISTS schemas (
+		id TEXT PRIMARY KEY,
+		version TEXT UNIQUE NOT NULL,
+		major INTEGER NOT NULL,
+		minor INTEGER NOT NULL,
+		patch INTEGER NOT NULL,
+		pre_release TEXT NOT NULL DEFAULT '',
+		pre_release_key TEXT NOT NULL DEFAULT '~',
+		build_metadata TEXT NOT NULL DEFAULT '',
+		schema_binary BLOB NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		content_hash TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'active',
+		status_reason TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_schemas_semver ON schemas(major DESC, minor DESC, patch DESC);
+
+	CREATE TABLE IF NOT EXISTS schema_tags (
+		name TEXT PRIMARY KEY,
+		schema_id TEXT NOT NULL REFERENCES schemas(id),
+		updated_at INTEGER NOT NULL
+	);
+	`,
+	// Content-addressed schema binaries. SQLite cannot add a foreign key to an existing table,
+	// so schemas.content_hash is not constrained.
+	`
+	CREATE TABLE schema_blobs (
+		hash TEXT PRIMARY KEY,
+		data BLOB NOT NULL
+	);
+
+	INSERT OR IGNORE INTO schema_blobs (hash, data) SELECT content_hash, schema_binary FROM schemas;
+
+	ALTER TABLE schemas DROP COLUMN schema_binary;
+	`,
+	// Existing blobs stay uncompressed
+	`
+	ALTER TABLE schema_blobs ADD COLUMN compression TEXT NOT NULL DEFAULT 'none';
+	`,
+	// Signatures belong to the version, not the blob: identical binaries may be signed by different keys
+	`
+	ALTER TABLE schemas ADD COLUMN signature BLOB;
+	`,
+	// List orders and pages by the whole (major, minor, patch, pre_release_key) key
+	`
+	DROP INDEX IF EXISTS idx_schemas_semver;
+	CREATE INDEX idx_schemas_semver ON schemas(major DESC, minor DESC, patch DESC, pre_release_key DESC);
+	`,
+}
+
+// migrate applies the migrations newer than the database's user_version
+func migrate(ctx context.Context, db *sql.DB) error {
+	var applied int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read user_version: %w", err)
+	}
+	for i := applied; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		// PRAGMA does not accept parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+const schemaColumns = `id, version, major, minor, patch, pre_release, build_metadata, data, compression,
+	size_bytes, length(data), content_hash, signature, status, status_reason, created_at`
+
+// metadataColumns are schemaColumns with an empty, uncompressed blob in place of data and compression,
+// for listings that do not load schema binaries. scanSchema reads both.
+const metadataColumns = `id, version, major, minor, patch, pre_release, build_metadata, NULL, 'none',
+	size_bytes, length(data), content_hash, signature, status, status_reason, created_at`
+
+// schemasWithBlobs is the FROM clause for queries selecting schemaColumns or metadataColumns
+const schemasWithBlobs = `schemas JOIN schema_blobs ON hash = content_hash`
+
+type SchemaRepository struct {
+	db *sql.DB
+}
+
+var _ storage.SchemaRepository = (*SchemaRepository)(nil)
+
+// Open opens (creating if needed) the database file at path and migrates it
+func Open(ctx context.Context, path string) (*SchemaRepository, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY under concurrent uploads
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SchemaRepository{db: db}, nil
+}
+
+// Close closes the database
+func (r *SchemaRepository) Close() error {
+	return r.db.Close()
+}
+
+// queryError wraps err, translating sql.ErrNoRows to storage.ErrNotFound
+func queryError(msg string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = storage.ErrNotFound
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSchema scans the columns in schemaColumns order and decompresses the blob
+func scanSchema(row rowScanner) (*model.Schema, error) {
+	var schema model.Schema
+	var compression string
+	var createdAt int64
+	if err := row.Scan(
+		&schema.ID,
+		&schema.Version,
+		&schema.Major,
+		&schema.Minor,
+		&schema.Patch,
+		&schema.PreRelease,
+		&schema.BuildMetadata,
+		&schema.SchemaBinary,
+		&compression,
+		&schema.SizeBytes,
+		&schema.StoredSizeBytes,
+		&schema.ContentHash,
+		&schema.Signature,
+		&schema.Status,
+		&schema.StatusReason,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+	data, err := storage.DecodeBlob(schema.SchemaBinary, compression)
+	if err != nil {
+		return nil, err
+	}
+	schema.SchemaBinary = data
+	schema.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &schema, nil
+}
+
+// Create inserts a new schema into the database. The ordering check and the insert run in one transaction.
+func (r *SchemaRepository) Create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+	if err := r.create(ctx, schema, allowOutOfOrder); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			err = storage.ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to insert schema: %w", err)
+	}
+	return nil
+}
+
+func (r *SchemaRepository) create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error {
+	status := schema.Status
+	if status == "" {
+		status = model.StatusActive
+	}
+	preReleaseKey := version.PreReleaseKey(schema.PreRelease)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !allowOutOfOrder {
+		var latest string
+		err := tx.QueryRowContext(ctx, `
+			SELECT version
+			FROM schemas
+			WHERE major = ? AND minor = ? AND (patch, pre_release_key) > (?, ?)
+			ORDER BY patch DESC, pre_release_key DESC
+			LIMIT 1
+		`, schema.Major, schema.Minor, schema.Patch, preReleaseKey).Scan(&latest)
+		if err == nil {
+			return &storage.OutOfOrderError{Version: schema.Version, Latest: latest}
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	// Identical descriptor sets share one blob
+	data, compression := storage.EncodeBlob(schema.SchemaBinary)
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO schema_blobs (hash, data, compression) VALUES (?, ?, ?)`,
+		schema.ContentHash, data, compression)
+	if err != nil {
+		return err
+	}
+	// The blob may predate this upload and be encoded differently
+	err = tx.QueryRowContext(ctx, `SELECT length(data) FROM schema_blobs WHERE hash = ?`, schema.ContentHash).
+		Scan(&schema.StoredSizeBytes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schemas (id, version, major, minor, patch, pre_release, pre_release_key, build_metadata,
+			size_bytes, content_hash, signature, status, status_reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		schema.ID,
+		schema.Version,
+		schema.Major,
+		schema.Minor,
+		schema.Patch,
+		schema.PreRelease,
+		preReleaseKey,
+		schema.BuildMetadata,
+		schema.SizeBytes,
+		schema.ContentHash,
+		schema.Signature,
+		status,
+		schema.StatusReason,
+		schema.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByVersion retrieves a schema by its version
+func (r *SchemaRepository) GetByVersion(ctx context.Context, version string) (*model.Schema, error) {
+	query := `SELECT ` + schemaColumns + ` FROM ` + schemasWithBlobs + ` WHERE version = ?`
+	schema, err := scanSchema(r.db.QueryRowContext(ctx, query, version))
+	if err != nil {
+		return nil, queryError("failed to get schema by version", err)
+	}
+	return schema, nil
+}
+
+// GetLatestPatch retrieves the latest patch version for a given major.minor.
+// Pre-releases are only considered when includePreRelease is set.
+func (r *SchemaRepository) GetLatestPatch(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE major = ? AND minor = ? AND status <> 'yanked' AND (? OR pre_release = '')
+		ORDER BY patch DESC, pre_release_key DESC
+		LIMIT 1
+	`
+	schema, err := scanSchema(r.db.QueryRowContext(ctx, query, major, minor, includePreRelease))
+	if err != nil {
+		return nil, queryError("failed to get latest patch", err)
+	}
+	return schema, nil
+}
+
+// GetLatestBeforeMinor retrieves the latest release in the given major whose minor is lower than minor
+func (r *SchemaRepository) GetLatestBeforeMinor(ctx context.Context, major, minor int32) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE major = ? AND minor < ? AND status <> 'yanked' AND pre_release = ''
+		ORDER BY minor DESC, patch DESC
+		LIMIT 1
+	`
+	schema, err := scanSchema(r.db.QueryRowContext(ctx, query, major, minor))
+	if err != nil {
+		return nil, queryError("failed to get latest version before minor", err)
+	}
+	return schema, nil
+}
+
+// List retrieves schema metadata matching filter, newest version first. The blob is not loaded.
+func (r *SchemaRepository) List(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error) {
+	var conditions []string
+	var args []any
+	if filter.Major != nil {
+		conditions = append(conditions, "major = ?")
+		args = append(args, *filter.Major)
+	}
+	if filter.Minor != nil {
+		conditions = append(conditions, "minor = ?")
+		args = append(args, *filter.Minor)
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UnixNano())
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UnixNano())
+	}
+	if filter.Before != nil {
+		conditions = append(conditions, "(major, minor, patch, pre_release_key) < (?, ?, ?, ?)")
+		args = append(args, filter.Before.Major, filter.Before.Minor, filter.Before.Patch,
+			version.PreReleaseKey(filter.Before.PreRelease))
+	}
+
+	query := `SELECT ` + metadataColumns + ` FROM ` + schemasWithBlobs
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY major DESC, minor DESC, patch DESC, pre_release_key DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []*model.Schema
+	for rows.Next() {
+		schema, err := scanSchema(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// ListSeries summarizes each major.minor, newest first. If major is non-nil only that major is listed.
+func (r *SchemaRepository) ListSeries(ctx context.Context, major *int32) ([]*model.SchemaSeries, error) {
+	query := `
+		SELECT major, minor, MAX(patch) AS latest_patch, COUNT(*) AS patch_count
+		FROM schemas
+		WHERE (?1 IS NULL OR major = ?1) AND status <> 'yanked' AND pre_release = ''
+		GROUP BY major, minor
+		ORDER BY major DESC, minor DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, major)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema series: %w", err)
+	}
+	defer rows.Close()
+
+	var series []*model.SchemaSeries
+	for rows.Next() {
+		var s model.SchemaSeries
+		if err := rows.Scan(&s.Major, &s.Minor, &s.LatestPatch, &s.PatchCount); err != nil {
+			return nil, fmt.Errorf("failed to scan schema series: %w", err)
+		}
+		series = append(series, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list schema series: %w", err)
+	}
+	return series, nil
+}
+
+// UpdateStatus sets the status of a version. Returns storage.ErrNotFound if the version does not exist.
+func (r *SchemaRepository) UpdateStatus(ctx context.Context, version string, status model.SchemaStatus, reason string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE schemas SET status = ?, status_reason = ? WHERE version = ?`,
+		status, reason, version)
+	if err != nil {
+		return fmt.Errorf("failed to update schema status: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update schema status: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to update schema status: %w", storage.ErrNotFound)
+	}
+	return nil
+}
+
+// SetTag points a tag at a schema, creating the tag if it does not exist
+func (r *SchemaRepository) SetTag(ctx context.Context, tag *model.SchemaTag) error {
+	query := `
+		INSERT INTO schema_tags (name, schema_id, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET schema_id = excluded.schema_id, updated_at = excluded.updated_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, tag.Name, tag.SchemaID, tag.UpdatedAt.UnixNano()); err != nil {
+		return fmt.Errorf("failed to set schema tag: %w", err)
+	}
+	return nil
+}
+
+// GetByTag retrieves the schema a tag points to
+func (r *SchemaRepository) GetByTag(ctx context.Context, tag string) (*model.Schema, error) {
+	query := `
+		SELECT ` + schemaColumns + `
+		FROM ` + schemasWithBlobs + `
+		WHERE id = (SELECT schema_id FROM schema_tags WHERE name = ?)
+	`
+	schema, err := scanSchema(r.db.QueryRowContext(ctx, query, tag))
+	if err != nil {
+		return nil, queryError("failed to get schema by tag", err)
+	}
+	return schema, nil
+}
+
+// GetBlob retrieves a schema binary by the hex SHA-256 of its content
+func (r *SchemaRepository) GetBlob(ctx context.Context, hash string) ([]byte, error) {
+	var stored []byte
+	var compression string
+	err := r.db.QueryRowContext(ctx, `SELECT data, compression FROM schema_blobs WHERE hash = ?`, hash).Scan(&stored, &compression)
+	if err != nil {
+		return nil, queryError("failed to get schema blob", err)
+	}
+	data, err := storage.DecodeBlob(stored, compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema blob: %w", err)
+	}
+	return data, nil
+}
+
+// VersionExists checks if a version already exists
+func (r *SchemaRepository) VersionExists(ctx context.Context, version string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM schemas WHERE version = ?)`, version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check version existence: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/storagetest"
+)
+
+func TestSchemaRepository_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.SchemaRepository {
+		repo, err := Open(context.Background(), filepath.Join(t.TempDir(), "isr.db"))
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestOpen_MigratesBinariesToBlobs(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "isr.db")
+
+	// A database at the first migration stores binaries inline
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	if _, err := db.Exec(migrations[0] + `PRAGMA user_version = 1;`); err != nil {
+		t.Fatalf("failed to apply the first migration: %v", err)
+	}
+	binary := []byte("binary")
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		if _, err := db.Exec(`
+			INSERT INTO schemas (id, version, major, minor, patch, schema_binary, size_bytes, content_hash, created_at)
+			VALUES (?, ?, 1, 0, ?, ?, ?, ?, 0)
+		`, "id-"+v, v, v[len(v)-1:], binary, len(binary), storagetest.ContentHash(binary)); err != nil {
+			t.Fatalf("failed to insert %s: %v", v, err)
+		}
+	}
+	db.Close()
+
+	repo, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer repo.Close()
+
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		schema, err := repo.GetByVersion(ctx, v)
+		if err != nil {
+			t.Fatalf("GetByVersion(%s) failed: %v", v, err)
+		}
+		if string(schema.SchemaBinary) != "binary" {
+			t.Errorf("GetByVersion(%s) binary = %q, want %q", v, schema.SchemaBinary, binary)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package storage defines the persistence interface of the ISR.
+//
+// Backends live in sub-packages (postgres, sqlite, memory) and must pass the
+// conformance suite in storagetest.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+)
+
+// ErrNotFound is returned (possibly wrapped) when the requested schema or tag does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned (possibly wrapped) by Create when the version already exists
+var ErrAlreadyExists = errors.New("already exists")
+
+// OutOfOrderError is returned (possibly wrapped) by Create when a version with higher precedence
+// already exists in the same major.minor, e.g. when creating 1.0.3 after 1.0.5
+type OutOfOrderError struct {
+	Version string // The version being created
+	Latest  string // The highest existing version of its major.minor, yanked ones included
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("version %s is lower than the existing %s", e.Version, e.Latest)
+}
+
+// SchemaRepository stores schema versions and tags
+type SchemaRepository interface {
+	// Create stores a new version. Status defaults to model.StatusActive.
+	// ContentHash must be the hex SHA-256 of SchemaBinary; it is the key of the stored blob,
+	// which is compressed with EncodeBlob. On success StoredSizeBytes is set to the size of the blob at rest.
+	// It returns ErrAlreadyExists if the version exists, and *OutOfOrderError if a higher version of
+	// the same major.minor exists unless allowOutOfOrder is set. The checks and the insert are atomic.
+	Create(ctx context.Context, schema *model.Schema, allowOutOfOrder bool) error
+	// GetByVersion returns the version without build metadata (e.g., "1.2.3-rc.1")
+	GetByVersion(ctx context.Context, version string) (*model.Schema, error)
+	// GetLatestPatch returns the highest non-yanked version of major.minor.
+	// Pre-releases are only considered when includePreRelease is set.
+	GetLatestPatch(ctx context.Context, major, minor int32, includePreRelease bool) (*model.Schema, error)
+	// GetLatestBeforeMinor returns the highest non-yanked release of major whose minor is lower than minor
+	GetLatestBeforeMinor(ctx context.Context, major, minor int32) (*model.Schema, error)
+	VersionExists(ctx context.Context, version string) (bool, error)
+	// List returns metadata matching filter, newest version first. SchemaBinary is not loaded.
+	List(ctx context.Context, filter model.SchemaFilter) ([]*model.Schema, error)
+	// ListSeries summarizes each major.minor, newest first, counting non-yanked releases only
+	ListSeries(ctx context.Context, major *int32) ([]*model.SchemaSeries, error)
+	// UpdateStatus returns ErrNotFound if the version does not exist
+	UpdateStatus(ctx context.Context, version string, status model.SchemaStatus, reason string) error
+	// SetTag creates or moves a tag. The schema it points to must exist.
+	SetTag(ctx context.Context, tag *model.SchemaTag) error
+	GetByTag(ctx context.Context, tag string) (*model.Schema, error)
+	// GetBlob returns the uncompressed schema binary whose hex SHA-256 is hash.
+	// Versions with identical binaries share one blob.
+	GetBlob(ctx context.Context, hash string) ([]byte, error)
+}
+
+// ChangeNotifier is implemented by backends whose changes must reach WatchSchema streams
+// on every ISR replica sharing them (postgres, and memory for handlers in one process).
+type ChangeNotifier interface {
+	// Listen calls onListen once it is subscribed, then onChange with the major.minor of every
+	// version created, tagged or whose status changed, by any replica, until ctx is done or the
+	// subscription fails. It returns nil only when ctx is done. onChange must not block.
+	// Changes made while no listener is subscribed are not delivered, so callers re-read
+	// their state in onListen.
+	Listen(ctx context.Context, onListen func(), onChange func(major, minor int32)) error
+}
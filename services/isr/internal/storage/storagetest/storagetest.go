@@ -0,0 +1,671 @@
+// Package storagetest is the conformance suite every storage.SchemaRepository backend must pass
+package storagetest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/model"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/version"
+)
+
+// Run runs the conformance suite. newRepo must return an empty repository for each call.
+func Run(t *testing.T, newRepo func(t *testing.T) storage.SchemaRepository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo storage.SchemaRepository)
+	}{
+		{"Create", testCreate},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"CreateOutOfOrder", testCreateOutOfOrder},
+		{"GetLatestPatch", testGetLatestPatch},
+		{"PreRelease", testPreRelease},
+		{"GetLatestBeforeMinor", testGetLatestBeforeMinor},
+		{"List", testList},
+		{"UpdateStatus", testUpdateStatus},
+		{"VersionExists", testVersionExists},
+		{"Tags", testTags},
+		{"Blobs", testBlobs},
+		{"Compression", testCompression},
+		{"Signatures", testSignatures},
+		{"ChangeNotifier", testChangeNotifier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// ContentHash returns the hex SHA-256 of data, the key of its blob
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// create stores v (e.g., "1.0.1-rc.1+build.7") with a binary of "binary-" + v.
+// Fixtures may be created in any order; testCreateOutOfOrder covers the ordering check.
+func create(t *testing.T, repo storage.SchemaRepository, v string, createdAt time.Time) *model.Schema {
+	t.Helper()
+	parsed, err := version.Parse(v)
+	if err != nil {
+		t.Fatalf("Parse(%s) failed: %v", v, err)
+	}
+	schema := &model.Schema{
+		ID:            "id-" + v,
+		Version:       parsed.WithoutBuild(),
+		Major:         parsed.Major,
+		Minor:         parsed.Minor,
+		Patch:         parsed.Patch,
+		PreRelease:    parsed.PreRelease,
+		BuildMetadata: parsed.Build,
+		SchemaBinary:  []byte("binary-" + v),
+		SizeBytes:     int32(len("binary-" + v)),
+		ContentHash:   ContentHash([]byte("binary-" + v)),
+		CreatedAt:     createdAt,
+	}
+	if err := repo.Create(context.Background(), schema, true); err != nil {
+		t.Fatalf("Failed to create schema %s: %v", v, err)
+	}
+	return schema
+}
+
+func versions(schemas []*model.Schema) string {
+	var got []string
+	for _, s := range schemas {
+		got = append(got, s.Version)
+	}
+	return strings.Join(got, ",")
+}
+
+func testCreate(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	createdAt := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+	schema := create(t, repo, "1.0.0-rc.1+build.7", createdAt)
+
+	retrieved, err := repo.GetByVersion(ctx, "1.0.0-rc.1")
+	if err != nil {
+		t.Fatalf("GetByVersion failed: %v", err)
+	}
+	if retrieved.ID != schema.ID || retrieved.Version != "1.0.0-rc.1" {
+		t.Errorf("GetByVersion = %s (%s), want %s (1.0.0-rc.1)", retrieved.ID, retrieved.Version, schema.ID)
+	}
+	if retrieved.Major != 1 || retrieved.Minor != 0 || retrieved.Patch != 0 ||
+		retrieved.PreRelease != "rc.1" || retrieved.BuildMetadata != "build.7" {
+		t.Errorf("GetByVersion version fields = %+v", retrieved)
+	}
+	if string(retrieved.SchemaBinary) != string(schema.SchemaBinary) || retrieved.SizeBytes != schema.SizeBytes {
+		t.Errorf("GetByVersion binary = %q (%d bytes), want %q", retrieved.SchemaBinary, retrieved.SizeBytes, schema.SchemaBinary)
+	}
+	if retrieved.ContentHash != schema.ContentHash {
+		t.Errorf("ContentHash = %s, want %s", retrieved.ContentHash, schema.ContentHash)
+	}
+	if retrieved.Status != model.StatusActive || retrieved.StatusReason != "" {
+		t.Errorf("Status = %s (%q), want active", retrieved.Status, retrieved.StatusReason)
+	}
+	if !retrieved.CreatedAt.Equal(createdAt) {
+		t.Errorf("CreatedAt = %v, want %v", retrieved.CreatedAt, createdAt)
+	}
+
+	if _, err := repo.GetByVersion(ctx, "9.9.9"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetByVersion(9.9.9) error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testCreateDuplicate(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	create(t, repo, "1.0.0", time.Now())
+
+	// Build metadata is not part of the identity
+	duplicate := &model.Schema{
+		ID:            "id-duplicate",
+		Version:       "1.0.0",
+		Major:         1,
+		BuildMetadata: "build.2",
+		SchemaBinary:  []byte("other"),
+		SizeBytes:     5,
+		ContentHash:   ContentHash([]byte("other")),
+		CreatedAt:     time.Now(),
+	}
+	for _, allowOutOfOrder := range []bool{false, true} {
+		if err := repo.Create(ctx, duplicate, allowOutOfOrder); !errors.Is(err, storage.ErrAlreadyExists) {
+			t.Errorf("Create(duplicate, %v) error = %v, want storage.ErrAlreadyExists", allowOutOfOrder, err)
+		}
+	}
+}
+
+func testCreateOutOfOrder(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	for _, v := range []string{"1.0.5", "1.1.0", "2.0.0"} {
+		create(t, repo, v, time.Now())
+	}
+	if err := repo.UpdateStatus(ctx, "1.0.5", model.StatusYanked, ""); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+
+	schema := func(v string) *model.Schema {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", v, err)
+		}
+		return &model.Schema{
+			ID:           "id-" + v,
+			Version:      v,
+			Major:        parsed.Major,
+			Minor:        parsed.Minor,
+			Patch:        parsed.Patch,
+			PreRelease:   parsed.PreRelease,
+			SchemaBinary: []byte("binary-" + v),
+			SizeBytes:    int32(len("binary-" + v)),
+			ContentHash:  ContentHash([]byte("binary-" + v)),
+			CreatedAt:    time.Now(),
+		}
+	}
+
+	// Lower than the yanked 1.0.5, including its own pre-release
+	for _, v := range []string{"1.0.3", "1.0.5-rc.1"} {
+		var outOfOrder *storage.OutOfOrderError
+		err := repo.Create(ctx, schema(v), false)
+		if !errors.As(err, &outOfOrder) || outOfOrder.Latest != "1.0.5" {
+			t.Errorf("Create(%s) error = %v, want OutOfOrderError against 1.0.5", v, err)
+		}
+		if exists, _ := repo.VersionExists(ctx, v); exists {
+			t.Errorf("Create(%s) stored the version despite the error", v)
+		}
+	}
+
+	// Higher versions of the series, and other series, are in order
+	for _, v := range []string{"1.0.6-rc.1", "1.0.6", "1.2.0"} {
+		if err := repo.Create(ctx, schema(v), false); err != nil {
+			t.Errorf("Create(%s) failed: %v", v, err)
+		}
+	}
+
+	// Explicitly allowed backfill
+	if err := repo.Create(ctx, schema("1.0.3"), true); err != nil {
+		t.Fatalf("Create(1.0.3, allowOutOfOrder) failed: %v", err)
+	}
+	latest, err := repo.GetLatestPatch(ctx, 1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLatestPatch failed: %v", err)
+	}
+	if latest.Version != "1.0.6" {
+		t.Errorf("GetLatestPatch = %s, want 1.0.6", latest.Version)
+	}
+}
+
+func testGetLatestPatch(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	for _, v := range []string{"1.0.0", "1.0.2", "1.0.1", "1.1.0"} {
+		create(t, repo, v, time.Now())
+	}
+
+	latest, err := repo.GetLatestPatch(ctx, 1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLatestPatch failed: %v", err)
+	}
+	if latest.Version != "1.0.2" || string(latest.SchemaBinary) != "binary-1.0.2" {
+		t.Errorf("GetLatestPatch = %s (%q), want 1.0.2", latest.Version, latest.SchemaBinary)
+	}
+
+	if _, err := repo.GetLatestPatch(ctx, 2, 0, false); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetLatestPatch(2, 0) error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testPreRelease(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	latest := func(includePreRelease bool) string {
+		t.Helper()
+		schema, err := repo.GetLatestPatch(ctx, 1, 0, includePreRelease)
+		if err != nil {
+			t.Fatalf("GetLatestPatch(%v) failed: %v", includePreRelease, err)
+		}
+		return schema.Version
+	}
+
+	// Inserted out of precedence order on purpose
+	for _, v := range []string{"1.0.0", "1.0.1-beta.2", "1.0.1-beta.11+build.7", "1.0.1-alpha"} {
+		create(t, repo, v, time.Now())
+	}
+
+	if got := latest(false); got != "1.0.0" {
+		t.Errorf("GetLatestPatch without pre-releases = %s, want 1.0.0", got)
+	}
+	if got := latest(true); got != "1.0.1-beta.11" {
+		t.Errorf("GetLatestPatch with pre-releases = %s, want 1.0.1-beta.11", got)
+	}
+
+	create(t, repo, "1.0.1", time.Now())
+	if got := latest(true); got != "1.0.1" {
+		t.Errorf("GetLatestPatch with pre-releases = %s, want the 1.0.1 release", got)
+	}
+
+	schemas, err := repo.List(ctx, model.SchemaFilter{
+		Before: &model.SchemaCursor{Major: 1, Minor: 0, Patch: 1, PreRelease: "beta.11"},
+	})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if got, want := versions(schemas), "1.0.1-beta.2,1.0.1-alpha,1.0.0"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+
+	series, err := repo.ListSeries(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	if len(series) != 1 || series[0].LatestPatch != 1 || series[0].PatchCount != 2 {
+		t.Errorf("ListSeries() = %+v, want 1.0 with 2 releases", series)
+	}
+}
+
+func testGetLatestBeforeMinor(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	for _, v := range []string{"1.0.0", "1.0.3", "1.2.1", "1.4.0", "1.4.1-rc.1", "2.0.0"} {
+		create(t, repo, v, time.Now())
+	}
+
+	tests := []struct {
+		major, minor int32
+		want         string
+	}{
+		{1, 1, "1.0.3"},
+		{1, 3, "1.2.1"}, // skips the missing 1.3 series
+		{1, 5, "1.4.0"}, // pre-releases are never the base
+	}
+	for _, tt := range tests {
+		got, err := repo.GetLatestBeforeMinor(ctx, tt.major, tt.minor)
+		if err != nil {
+			t.Fatalf("GetLatestBeforeMinor(%d, %d) failed: %v", tt.major, tt.minor, err)
+		}
+		if got.Version != tt.want {
+			t.Errorf("GetLatestBeforeMinor(%d, %d) = %s, want %s", tt.major, tt.minor, got.Version, tt.want)
+		}
+	}
+
+	// Nothing below the first minor of a major
+	if _, err := repo.GetLatestBeforeMinor(ctx, 2, 0); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetLatestBeforeMinor(2, 0) error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testList(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []string{"1.0.0", "1.0.1", "1.1.0", "2.0.0"} {
+		create(t, repo, v, base.Add(time.Duration(i)*time.Hour))
+	}
+
+	major1, minor0 := int32(1), int32(0)
+	after := base.Add(time.Hour)
+	before := base.Add(3 * time.Hour)
+
+	tests := []struct {
+		name   string
+		filter model.SchemaFilter
+		want   string
+	}{
+		{"all", model.SchemaFilter{}, "2.0.0,1.1.0,1.0.1,1.0.0"},
+		{"major", model.SchemaFilter{Major: &major1}, "1.1.0,1.0.1,1.0.0"},
+		{"major and minor", model.SchemaFilter{Major: &major1, Minor: &minor0}, "1.0.1,1.0.0"},
+		{"created_at range", model.SchemaFilter{CreatedAfter: &after, CreatedBefore: &before}, "1.1.0,1.0.1"},
+		{"limit", model.SchemaFilter{Limit: 2}, "2.0.0,1.1.0"},
+		{"before cursor", model.SchemaFilter{Before: &model.SchemaCursor{Major: 1, Minor: 1, Patch: 0}, Limit: 2}, "1.0.1,1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemas, err := repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			for _, s := range schemas {
+				if s.SchemaBinary != nil {
+					t.Errorf("List should not load schema_binary for %s", s.Version)
+				}
+			}
+			if got := versions(schemas); got != tt.want {
+				t.Errorf("List() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	series, err := repo.ListSeries(ctx, &major1)
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("ListSeries(1) returned %d series, want 2", len(series))
+	}
+	if series[0].Minor != 1 || series[0].PatchCount != 1 {
+		t.Errorf("series[0] = %+v, want 1.1 with 1 patch", *series[0])
+	}
+	if series[1].Minor != 0 || series[1].LatestPatch != 1 || series[1].PatchCount != 2 {
+		t.Errorf("series[1] = %+v, want 1.0 with latest patch 1 and 2 patches", *series[1])
+	}
+}
+
+func testUpdateStatus(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	for _, patch := range []int32{0, 1, 2} {
+		create(t, repo, fmt.Sprintf("1.0.%d", patch), time.Now())
+	}
+
+	if err := repo.UpdateStatus(ctx, "1.0.2", model.StatusYanked, "broke email validation"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+
+	// GetLatestPatch falls back to the previous patch
+	latest, err := repo.GetLatestPatch(ctx, 1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLatestPatch failed: %v", err)
+	}
+	if latest.Version != "1.0.1" || latest.Status != model.StatusActive {
+		t.Errorf("GetLatestPatch = %s (%s), want 1.0.1 (active)", latest.Version, latest.Status)
+	}
+
+	// The yanked version is still retrievable by exact version
+	yanked, err := repo.GetByVersion(ctx, "1.0.2")
+	if err != nil {
+		t.Fatalf("GetByVersion failed: %v", err)
+	}
+	if yanked.Status != model.StatusYanked || yanked.StatusReason != "broke email validation" {
+		t.Errorf("GetByVersion status = %s (%q), want yanked with reason", yanked.Status, yanked.StatusReason)
+	}
+
+	// Yanked versions are not counted in the series
+	series, err := repo.ListSeries(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListSeries failed: %v", err)
+	}
+	if len(series) != 1 || series[0].LatestPatch != 1 || series[0].PatchCount != 2 {
+		t.Errorf("ListSeries() = %+v, want 1.0 with latest patch 1 and 2 patches", series)
+	}
+
+	if err := repo.UpdateStatus(ctx, "9.9.9", model.StatusYanked, ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateStatus(9.9.9) error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testVersionExists(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+
+	exists, err := repo.VersionExists(ctx, "2.0.0")
+	if err != nil {
+		t.Fatalf("VersionExists failed: %v", err)
+	}
+	if exists {
+		t.Error("Expected version 2.0.0 to not exist")
+	}
+
+	create(t, repo, "2.0.0", time.Now())
+
+	exists, err = repo.VersionExists(ctx, "2.0.0")
+	if err != nil {
+		t.Fatalf("VersionExists failed: %v", err)
+	}
+	if !exists {
+		t.Error("Expected version 2.0.0 to exist")
+	}
+}
+
+func testTags(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	for _, patch := range []int32{0, 1} {
+		create(t, repo, fmt.Sprintf("1.0.%d", patch), time.Now())
+	}
+
+	if _, err := repo.GetByTag(ctx, "prod"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetByTag for a missing tag error = %v, want storage.ErrNotFound", err)
+	}
+
+	// Promote 1.0.0, then move the tag to 1.0.1
+	for _, id := range []string{"id-1.0.0", "id-1.0.1"} {
+		if err := repo.SetTag(ctx, &model.SchemaTag{Name: "prod", SchemaID: id, UpdatedAt: time.Now()}); err != nil {
+			t.Fatalf("SetTag(%s) failed: %v", id, err)
+		}
+		schema, err := repo.GetByTag(ctx, "prod")
+		if err != nil {
+			t.Fatalf("GetByTag failed: %v", err)
+		}
+		if schema.ID != id || string(schema.SchemaBinary) != "binary-"+schema.Version {
+			t.Errorf("GetByTag = %s (%q), want %s", schema.ID, schema.SchemaBinary, id)
+		}
+	}
+
+	if err := repo.SetTag(ctx, &model.SchemaTag{Name: "prod", SchemaID: "missing", UpdatedAt: time.Now()}); err == nil {
+		t.Error("SetTag to a missing schema error = nil, want error")
+	}
+}
+
+func testBlobs(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	shared := []byte("shared descriptor set")
+
+	// Both versions reference the same blob
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", v, err)
+		}
+		if err := repo.Create(ctx, &model.Schema{
+			ID:           "id-" + v,
+			Version:      v,
+			Major:        parsed.Major,
+			Minor:        parsed.Minor,
+			SchemaBinary: shared,
+			SizeBytes:    int32(len(shared)),
+			ContentHash:  ContentHash(shared),
+			CreatedAt:    time.Now(),
+		}, false); err != nil {
+			t.Fatalf("Create(%s) failed: %v", v, err)
+		}
+	}
+
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		schema, err := repo.GetByVersion(ctx, v)
+		if err != nil {
+			t.Fatalf("GetByVersion(%s) failed: %v", v, err)
+		}
+		if string(schema.SchemaBinary) != string(shared) || schema.ContentHash != ContentHash(shared) {
+			t.Errorf("GetByVersion(%s) = %q (%s), want the shared blob", v, schema.SchemaBinary, schema.ContentHash)
+		}
+	}
+
+	blob, err := repo.GetBlob(ctx, ContentHash(shared))
+	if err != nil {
+		t.Fatalf("GetBlob failed: %v", err)
+	}
+	if string(blob) != string(shared) {
+		t.Errorf("GetBlob = %q, want %q", blob, shared)
+	}
+
+	if _, err := repo.GetBlob(ctx, ContentHash([]byte("missing"))); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetBlob for a missing hash error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testCompression(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	binaries := map[string][]byte{
+		"1.0.0": []byte(strings.Repeat("compressible descriptor set ", 1000)),
+		"1.0.1": []byte("tiny"), // Does not shrink, so it is stored as is
+	}
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", v, err)
+		}
+		data := binaries[v]
+		schema := &model.Schema{
+			ID:           "id-" + v,
+			Version:      v,
+			Major:        parsed.Major,
+			Minor:        parsed.Minor,
+			Patch:        parsed.Patch,
+			SchemaBinary: data,
+			SizeBytes:    int32(len(data)),
+			ContentHash:  ContentHash(data),
+			CreatedAt:    time.Now(),
+		}
+		if err := repo.Create(ctx, schema, false); err != nil {
+			t.Fatalf("Create(%s) failed: %v", v, err)
+		}
+		if schema.StoredSizeBytes <= 0 {
+			t.Errorf("Create(%s) StoredSizeBytes = %d, want it set", v, schema.StoredSizeBytes)
+		}
+	}
+
+	compressed, err := repo.GetByVersion(ctx, "1.0.0")
+	if err != nil {
+		t.Fatalf("GetByVersion failed: %v", err)
+	}
+	if string(compressed.SchemaBinary) != string(binaries["1.0.0"]) {
+		t.Error("GetByVersion did not return the uncompressed binary")
+	}
+	if compressed.StoredSizeBytes <= 0 || compressed.StoredSizeBytes >= compressed.SizeBytes {
+		t.Errorf("StoredSizeBytes = %d, want between 0 and SizeBytes (%d)", compressed.StoredSizeBytes, compressed.SizeBytes)
+	}
+
+	blob, err := repo.GetBlob(ctx, ContentHash(binaries["1.0.0"]))
+	if err != nil {
+		t.Fatalf("GetBlob failed: %v", err)
+	}
+	if string(blob) != string(binaries["1.0.0"]) {
+		t.Error("GetBlob did not return the uncompressed binary")
+	}
+
+	listed, err := repo.List(ctx, model.SchemaFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	want := map[string]int32{"1.0.0": compressed.StoredSizeBytes, "1.0.1": int32(len(binaries["1.0.1"]))}
+	for _, s := range listed {
+		if s.StoredSizeBytes != want[s.Version] {
+			t.Errorf("List: %s StoredSizeBytes = %d, want %d", s.Version, s.StoredSizeBytes, want[s.Version])
+		}
+	}
+}
+
+func testSignatures(t *testing.T, repo storage.SchemaRepository) {
+	ctx := context.Background()
+	shared := []byte("shared descriptor set")
+	// Versions sharing a blob keep their own signatures
+	signatures := map[string][]byte{
+		"1.0.0": []byte(strings.Repeat("a", 64)),
+		"1.0.1": []byte(strings.Repeat("b", 64)),
+		"1.0.2": nil,
+	}
+	for _, v := range []string{"1.0.0", "1.0.1", "1.0.2"} {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", v, err)
+		}
+		if err := repo.Create(ctx, &model.Schema{
+			ID:           "id-" + v,
+			Version:      v,
+			Major:        parsed.Major,
+			Minor:        parsed.Minor,
+			Patch:        parsed.Patch,
+			SchemaBinary: shared,
+			SizeBytes:    int32(len(shared)),
+			ContentHash:  ContentHash(shared),
+			Signature:    signatures[v],
+			CreatedAt:    time.Now(),
+		}, false); err != nil {
+			t.Fatalf("Create(%s) failed: %v", v, err)
+		}
+	}
+
+	for v, want := range signatures {
+		schema, err := repo.GetByVersion(ctx, v)
+		if err != nil {
+			t.Fatalf("GetByVersion(%s) failed: %v", v, err)
+		}
+		if string(schema.Signature) != string(want) {
+			t.Errorf("GetByVersion(%s) Signature = %q, want %q", v, schema.Signature, want)
+		}
+	}
+
+	latest, err := repo.GetLatestPatch(ctx, 1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLatestPatch failed: %v", err)
+	}
+	if latest.Version != "1.0.2" || len(latest.Signature) != 0 {
+		t.Errorf("GetLatestPatch = %s with signature %q, want unsigned 1.0.2", latest.Version, latest.Signature)
+	}
+
+	listed, err := repo.List(ctx, model.SchemaFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, s := range listed {
+		if string(s.Signature) != string(signatures[s.Version]) {
+			t.Errorf("List: %s Signature = %q, want %q", s.Version, s.Signature, signatures[s.Version])
+		}
+	}
+}
+
+// testChangeNotifier checks that creating, yanking and tagging a version notify its major.minor.
+// Backends that do not implement storage.ChangeNotifier are skipped.
+func testChangeNotifier(t *testing.T, repo storage.SchemaRepository) {
+	notifier, ok := repo.(storage.ChangeNotifier)
+	if !ok {
+		t.Skip("backend does not implement storage.ChangeNotifier")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listening := make(chan struct{})
+	changes := make(chan string, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- notifier.Listen(ctx, func() { close(listening) }, func(major, minor int32) {
+			changes <- fmt.Sprintf("%d.%d", major, minor)
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Listen() after cancel error = %v, want nil", err)
+		}
+	}()
+
+	select {
+	case <-listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Listen to subscribe")
+	}
+
+	expectChange := func(action, want string) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("change after %s = %s, want %s", action, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the change after %s", action)
+		}
+	}
+
+	schema := create(t, repo, "1.2.0", time.Now())
+	expectChange("Create", "1.2")
+
+	if err := repo.UpdateStatus(ctx, "1.2.0", model.StatusYanked, "broken"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	expectChange("UpdateStatus", "1.2")
+
+	if err := repo.SetTag(ctx, &model.SchemaTag{Name: "stable", SchemaID: schema.ID, UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("SetTag failed: %v", err)
+	}
+	expectChange("SetTag", "1.2")
+}
@@ -0,0 +1,289 @@
+package version
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a parsed npm/cargo-style version range such as "~1.2", "^1" or ">=1.2.3 <2".
+//
+// Comparators separated by spaces or commas must all match; "||" separates alternatives.
+// Supported comparators are =, >, >=, <, <=, ~ (patch-level changes), ^ (changes that do
+// not modify the left-most non-zero component) and bare partial versions ("1.2", "1.x", "*").
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+// comparator is a primitive bound; tilde, caret and partial versions are expanded into these
+type comparator struct {
+	op string // "=", ">", ">=", "<" or "<="
+	v  SemVer
+}
+
+// none matches no version: 0.0.0-0 is the lowest possible version
+var none = comparator{op: "<", v: SemVer{PreRelease: "0"}}
+
+// ParseConstraint parses a version range expression
+func ParseConstraint(s string) (Constraint, error) {
+	if strings.TrimSpace(s) == "" {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+
+	c := Constraint{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(alternative)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Matches reports whether v satisfies the constraint. Unless includePreRelease is set,
+// a pre-release only matches when a comparator of the same alternative names a
+// pre-release of the same major.minor.patch (e.g., ">=1.2.3-rc.1" matches "1.2.3-rc.2" but not "1.2.4-rc.1").
+func (c Constraint) Matches(v SemVer, includePreRelease bool) bool {
+	for _, set := range c.sets {
+		if setMatches(set, v, includePreRelease) {
+			return true
+		}
+	}
+	return false
+}
+
+func setMatches(set []comparator, v SemVer, includePreRelease bool) bool {
+	for _, cmp := range set {
+		if !cmp.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPreRelease() || includePreRelease {
+		return true
+	}
+	for _, cmp := range set {
+		if cmp.v.IsPreRelease() && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmp comparator) matches(v SemVer) bool {
+	c := Compare(v, cmp.v)
+	switch cmp.op {
+	case "=":
+		return c == 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	default:
+		return false
+	}
+}
+
+// parseComparatorSet parses comparators separated by spaces or commas
+func parseComparatorSet(s string) ([]comparator, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		token := fields[i]
+		// Allow a space between the operator and the version (">= 1.2.3")
+		if strings.TrimLeft(token, "<>=~^") == "" && i+1 < len(fields) {
+			i++
+			token += fields[i]
+		}
+		comparators, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// parseComparator expands one comparator token into primitive bounds
+func parseComparator(token string) ([]comparator, error) {
+	version := strings.TrimLeft(token, "<>=~^")
+	op := token[:len(token)-len(version)]
+
+	p, err := parsePartial(version)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=":
+		return p.xRange(), nil
+	case "~":
+		return p.tildeRange(), nil
+	case "^":
+		return p.caretRange(), nil
+	case ">":
+		if p.n == 0 {
+			return []comparator{none}, nil
+		}
+		if p.n < 3 {
+			next, ok := p.next()
+			if !ok {
+				return []comparator{none}, nil
+			}
+			return []comparator{{op: ">=", v: next}}, nil
+		}
+		return []comparator{{op: ">", v: p.v}}, nil
+	case ">=":
+		if p.n == 0 {
+			return nil, nil
+		}
+		return []comparator{{op: ">=", v: p.v}}, nil
+	case "<":
+		if p.n == 0 {
+			return []comparator{none}, nil
+		}
+		lower := p.v
+		if p.n < 3 {
+			lower.PreRelease = "0" // "<2" excludes 2.0.0 pre-releases
+		}
+		return []comparator{{op: "<", v: lower}}, nil
+	case "<=":
+		if p.n == 0 {
+			return nil, nil
+		}
+		if p.n < 3 {
+			return p.below(), nil
+		}
+		return []comparator{{op: "<=", v: p.v}}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+}
+
+// partial is a version with 0 to 3 components given, e.g. "*", "1", "1.2" or "1.2.3-rc.1"
+type partial struct {
+	v SemVer // Missing components are zero
+	n int    // Number of components given
+}
+
+func parsePartial(s string) (partial, error) {
+	if s == "" {
+		return partial{}, fmt.Errorf("missing version")
+	}
+
+	core, preRelease, hasPreRelease := strings.Cut(s, "-")
+	var p partial
+	for _, part := range strings.Split(core, ".") {
+		if isWildcard(part) {
+			break // Components after a wildcard are ignored ("1.x.3" == "1.x")
+		}
+		if p.n == 3 {
+			return partial{}, fmt.Errorf("too many components in %q", s)
+		}
+		n, err := strconv.ParseInt(part, 10, 32)
+		if err != nil || n < 0 {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		switch p.n {
+		case 0:
+			p.v.Major = int32(n)
+		case 1:
+			p.v.Minor = int32(n)
+		case 2:
+			p.v.Patch = int32(n)
+		}
+		p.n++
+	}
+
+	if hasPreRelease {
+		if p.n != 3 {
+			return partial{}, fmt.Errorf("pre-release requires a full version in %q", s)
+		}
+		if err := checkIdentifiers(preRelease, true); err != nil {
+			return partial{}, fmt.Errorf("invalid pre-release in %q: %w", s, err)
+		}
+		p.v.PreRelease = preRelease
+	}
+	return p, nil
+}
+
+func isWildcard(s string) bool {
+	return s == "*" || s == "x" || s == "X"
+}
+
+// xRange expands "1.2" to ">=1.2.0 <1.3.0-0" and "1.2.3" to "=1.2.3"
+func (p partial) xRange() []comparator {
+	switch p.n {
+	case 0:
+		return nil
+	case 3:
+		return []comparator{{op: "=", v: p.v}}
+	default:
+		return append([]comparator{{op: ">=", v: p.v}}, p.below()...)
+	}
+}
+
+// tildeRange allows patch-level changes if a minor version is given, minor-level changes if not
+func (p partial) tildeRange() []comparator {
+	if p.n == 0 {
+		return nil
+	}
+	prefix := partial{v: p.v, n: min(p.n, 2)}
+	return append([]comparator{{op: ">=", v: p.v}}, prefix.below()...)
+}
+
+// caretRange allows changes that do not modify the left-most non-zero component given
+func (p partial) caretRange() []comparator {
+	if p.n == 0 {
+		return nil
+	}
+	prefix := partial{v: p.v, n: 3}
+	switch {
+	case p.v.Major > 0 || p.n == 1:
+		prefix.n = 1
+	case p.v.Minor > 0 || p.n == 2:
+		prefix.n = 2
+	}
+	return append([]comparator{{op: ">=", v: p.v}}, prefix.below()...)
+}
+
+// next returns the first version after every version starting with the given components
+// ("1" -> 2.0.0, "1.2" -> 1.3.0, "1.2.3" -> 1.2.4). It reports false if the component overflows.
+func (p partial) next() (SemVer, bool) {
+	switch p.n {
+	case 1:
+		return SemVer{Major: p.v.Major + 1}, p.v.Major < math.MaxInt32
+	case 2:
+		return SemVer{Major: p.v.Major, Minor: p.v.Minor + 1}, p.v.Minor < math.MaxInt32
+	default:
+		return SemVer{Major: p.v.Major, Minor: p.v.Minor, Patch: p.v.Patch + 1}, p.v.Patch < math.MaxInt32
+	}
+}
+
+// below returns an exclusive upper bound before next(), excluding the pre-releases of next()
+// as well; otherwise "^1" would match 2.0.0-rc.1 when pre-releases are included.
+func (p partial) below() []comparator {
+	next, ok := p.next()
+	if !ok {
+		return nil // Nothing comes after the largest major, minor or patch
+	}
+	next.PreRelease = "0"
+	return []comparator{{op: "<", v: next}}
+}
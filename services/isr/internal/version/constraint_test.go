@@ -0,0 +1,103 @@
+package version
+
+import "testing"
+
+func TestConstraint_Matches(t *testing.T) {
+	tests := []struct {
+		constraint        string
+		version           string
+		includePreRelease bool
+		want              bool
+	}{
+		// Tilde: patch-level changes
+		{constraint: "~1.2", version: "1.2.0", want: true},
+		{constraint: "~1.2", version: "1.2.9", want: true},
+		{constraint: "~1.2", version: "1.3.0", want: false},
+		{constraint: "~1.2.3", version: "1.2.2", want: false},
+		{constraint: "~1.2.3", version: "1.2.4", want: true},
+		{constraint: "~1", version: "1.9.0", want: true},
+		{constraint: "~1", version: "2.0.0", want: false},
+
+		// Caret: left-most non-zero component is fixed
+		{constraint: "^1", version: "1.0.0", want: true},
+		{constraint: "^1", version: "1.9.3", want: true},
+		{constraint: "^1", version: "2.0.0", want: false},
+		{constraint: "^1.2.3", version: "1.2.2", want: false},
+		{constraint: "^1.2.3", version: "1.5.0", want: true},
+		{constraint: "^0.2.3", version: "0.2.9", want: true},
+		{constraint: "^0.2.3", version: "0.3.0", want: false},
+		{constraint: "^0.0.3", version: "0.0.3", want: true},
+		{constraint: "^0.0.3", version: "0.0.4", want: false},
+		{constraint: "^0.0", version: "0.0.9", want: true},
+		{constraint: "^0.0", version: "0.1.0", want: false},
+		{constraint: "^0", version: "0.9.0", want: true},
+
+		// Comparators
+		{constraint: ">=1.2.3 <2", version: "1.2.3", want: true},
+		{constraint: ">=1.2.3 <2", version: "1.9.9", want: true},
+		{constraint: ">=1.2.3 <2", version: "2.0.0", want: false},
+		{constraint: ">=1.2.3 <2", version: "1.2.2", want: false},
+		{constraint: ">=1.2.3, <2", version: "1.5.0", want: true},
+		{constraint: ">= 1.2.3", version: "1.2.3", want: true},
+		{constraint: ">1.2", version: "1.2.9", want: false},
+		{constraint: ">1.2", version: "1.3.0", want: true},
+		{constraint: ">1.2.3", version: "1.2.3", want: false},
+		{constraint: "<=1.2", version: "1.2.9", want: true},
+		{constraint: "<=1.2", version: "1.3.0", want: false},
+		{constraint: "=1.2.3", version: "1.2.3", want: true},
+		{constraint: "1.2.3", version: "1.2.4", want: false},
+
+		// Partial and wildcard versions
+		{constraint: "1.2", version: "1.2.7", want: true},
+		{constraint: "1.x", version: "1.7.0", want: true},
+		{constraint: "1.x", version: "2.0.0", want: false},
+		{constraint: "*", version: "3.4.5", want: true},
+
+		// Alternatives
+		{constraint: "~1.0 || ^2", version: "2.3.0", want: true},
+		{constraint: "~1.0 || ^2", version: "1.1.0", want: false},
+
+		// Pre-releases are opt-in, or matched by a comparator on the same major.minor.patch
+		{constraint: "^1", version: "1.3.0-rc.1", want: false},
+		{constraint: "^1", version: "1.3.0-rc.1", includePreRelease: true, want: true},
+		{constraint: "^1", version: "2.0.0-rc.1", includePreRelease: true, want: false},
+		{constraint: "<2", version: "2.0.0-rc.1", includePreRelease: true, want: false},
+		{constraint: ">=1.3.0-rc.1", version: "1.3.0-rc.2", want: true},
+		{constraint: ">=1.3.0-rc.1", version: "1.3.1-rc.1", want: false},
+		{constraint: ">=1.3.0-rc.1", version: "1.3.0", want: true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) error = %v", tt.constraint, err)
+		}
+		v, err := Parse(tt.version)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.version, err)
+		}
+		if got := c.Matches(v, tt.includePreRelease); got != tt.want {
+			t.Errorf("ParseConstraint(%q).Matches(%s, %v) = %v, want %v",
+				tt.constraint, tt.version, tt.includePreRelease, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, constraint := range []string{
+		"",
+		"   ",
+		"abc",
+		"^",
+		"~1.2 ||",
+		">=1.2.3.4",
+		"!1.2.3",
+		"1.2-rc.1",
+		"1.2.3-rc..1",
+		"^-1",
+	} {
+		if _, err := ParseConstraint(constraint); err == nil {
+			t.Errorf("ParseConstraint(%q) error = nil, want error", constraint)
+		}
+	}
+}
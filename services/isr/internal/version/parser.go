@@ -6,32 +6,222 @@ import (
 	"strings"
 )
 
-// ParseSemVer parses a semantic version string (e.g., "1.2.3") into major, minor, patch components.
-// Returns an error if the version format is invalid or contains non-numeric components.
-func ParseSemVer(version string) (major, minor, patch int32, err error) {
-	parts := strings.Split(version, ".")
+// MaxLength is the longest version string accepted, build metadata included
+const MaxLength = 128
+
+// SemVer is a parsed SemVer 2.0 version
+type SemVer struct {
+	Major, Minor, Patch int32
+	PreRelease          string // Dot-separated identifiers after "-", empty for a release
+	Build               string // Dot-separated identifiers after "+"; ignored for precedence
+}
+
+// Parse parses a SemVer 2.0 version string (e.g., "1.2.3", "1.2.3-rc.1", "1.2.3-rc.1+sha.5114f85").
+func Parse(version string) (SemVer, error) {
+	if len(version) > MaxLength {
+		return SemVer{}, fmt.Errorf("version is longer than %d characters", MaxLength)
+	}
+
+	var v SemVer
+	rest, build, hasBuild := strings.Cut(version, "+")
+	if hasBuild {
+		if err := checkIdentifiers(build, false); err != nil {
+			return SemVer{}, fmt.Errorf("invalid build metadata: %w", err)
+		}
+		v.Build = build
+	}
+	core, preRelease, hasPreRelease := strings.Cut(rest, "-")
+	if hasPreRelease {
+		if err := checkIdentifiers(preRelease, true); err != nil {
+			return SemVer{}, fmt.Errorf("invalid pre-release: %w", err)
+		}
+		v.PreRelease = preRelease
+	}
+
+	parts := strings.Split(core, ".")
 	if len(parts) != 3 {
-		return 0, 0, 0, fmt.Errorf("invalid version format: expected 3 parts, got %d", len(parts))
+		return SemVer{}, fmt.Errorf("invalid version format: expected 3 parts, got %d", len(parts))
 	}
+	names := []string{"major", "minor", "patch"}
+	values := []*int32{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 32)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("invalid %s version: %w", names[i], err)
+		}
+		if n < 0 {
+			return SemVer{}, fmt.Errorf("version components must be non-negative")
+		}
+		if len(part) > 1 && part[0] == '0' {
+			return SemVer{}, fmt.Errorf("invalid %s version: leading zero in %q", names[i], part)
+		}
+		*values[i] = int32(n)
+	}
+
+	return v, nil
+}
 
-	majorInt, err := strconv.ParseInt(parts[0], 10, 32)
+// ParseSemVer parses a release version string (e.g., "1.2.3") into major, minor, patch components.
+// Returns an error if the version format is invalid, contains non-numeric components
+// or carries pre-release or build metadata.
+func ParseSemVer(version string) (major, minor, patch int32, err error) {
+	v, err := Parse(version)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid major version: %w", err)
+		return 0, 0, 0, err
+	}
+	if v.PreRelease != "" || v.Build != "" {
+		return 0, 0, 0, fmt.Errorf("invalid version format: expected Major.Minor.Patch, got %q", version)
 	}
+	return v.Major, v.Minor, v.Patch, nil
+}
 
-	minorInt, err := strconv.ParseInt(parts[1], 10, 32)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid minor version: %w", err)
+// checkIdentifiers validates dot-separated pre-release or build identifiers
+func checkIdentifiers(s string, preRelease bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("empty identifier in %q", s)
+		}
+		for _, c := range id {
+			if !isIdentifierChar(c) {
+				return fmt.Errorf("invalid character %q in %q", c, id)
+			}
+		}
+		if preRelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("leading zero in numeric identifier %q", id)
+		}
 	}
+	return nil
+}
 
-	patchInt, err := strconv.ParseInt(parts[2], 10, 32)
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("invalid patch version: %w", err)
+func isIdentifierChar(c rune) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-'
+}
+
+func isNumeric(id string) bool {
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the full version, build metadata included
+func (v SemVer) String() string {
+	s := v.WithoutBuild()
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// WithoutBuild returns the version without build metadata.
+// Versions that differ only in build metadata have the same precedence and identify the same schema.
+func (v SemVer) WithoutBuild() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+// IsPreRelease reports whether v has a pre-release component
+func (v SemVer) IsPreRelease() bool {
+	return v.PreRelease != ""
+}
+
+// StripBuild removes build metadata from a version string without validating it
+func StripBuild(version string) string {
+	s, _, _ := strings.Cut(version, "+")
+	return s
+}
+
+// Compare returns -1, 0 or 1 depending on whether a has lower, equal or higher precedence than b.
+// Build metadata is ignored.
+func Compare(a, b SemVer) int {
+	if c := compareInt(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	return comparePreRelease(a.PreRelease, b.PreRelease)
+}
+
+func compareInt(a, b int32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePreRelease orders pre-releases per SemVer 2.0 §11. A release ("") is higher than any pre-release.
+func comparePreRelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifier(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(int32(len(aIDs)), int32(len(bIDs)))
+}
+
+// compareIdentifier compares numeric identifiers numerically and others in ASCII order.
+// Numeric identifiers are lower than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		// No leading zeros, so a longer number is larger
+		if c := compareInt(int32(len(a)), int32(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
 	}
+}
 
-	if majorInt < 0 || minorInt < 0 || patchInt < 0 {
-		return 0, 0, 0, fmt.Errorf("version components must be non-negative")
+// PreReleaseKey encodes a pre-release so that byte-wise ordering of keys matches
+// SemVer precedence. Releases get the highest key. It lets the database order
+// versions with ORDER BY major, minor, patch, key (compared with the "C" collation).
+func PreReleaseKey(preRelease string) string {
+	if preRelease == "" {
+		return "~" // Above every pre-release key, which starts with '0' or '1'
 	}
 
-	return int32(majorInt), int32(minorInt), int32(patchInt), nil
+	var b strings.Builder
+	for i, id := range strings.Split(preRelease, ".") {
+		if i > 0 {
+			b.WriteByte('!') // Below every identifier character, so shorter prefixes come first
+		}
+		if isNumeric(id) {
+			// Length first: MaxLength keeps it within three digits
+			fmt.Fprintf(&b, "0%03d%s", len(id), id)
+		} else {
+			b.WriteString("1" + id)
+		}
+	}
+	return b.String()
 }
@@ -1,6 +1,9 @@
 package version
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseSemVer(t *testing.T) {
 	tests := []struct {
@@ -113,3 +116,158 @@ func TestParseSemVer(t *testing.T) {
 		})
 	}
 }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    SemVer
+		wantErr bool
+	}{
+		{
+			name:    "release",
+			version: "1.2.3",
+			want:    SemVer{Major: 1, Minor: 2, Patch: 3},
+		},
+		{
+			name:    "pre-release",
+			version: "1.1.0-rc.1",
+			want:    SemVer{Major: 1, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		},
+		{
+			name:    "pre-release with hyphens",
+			version: "1.2.3-RC-1",
+			want:    SemVer{Major: 1, Minor: 2, Patch: 3, PreRelease: "RC-1"},
+		},
+		{
+			name:    "build metadata",
+			version: "1.0.0+20130313144700",
+			want:    SemVer{Major: 1, Minor: 0, Patch: 0, Build: "20130313144700"},
+		},
+		{
+			name:    "pre-release and build metadata",
+			version: "1.0.0-beta.11+exp.sha.5114f85",
+			want:    SemVer{Major: 1, Minor: 0, Patch: 0, PreRelease: "beta.11", Build: "exp.sha.5114f85"},
+		},
+		{
+			name:    "build metadata with leading zero",
+			version: "1.0.0+001",
+			want:    SemVer{Major: 1, Minor: 0, Patch: 0, Build: "001"},
+		},
+		{
+			name:    "leading zero in core",
+			version: "01.2.3",
+			wantErr: true,
+		},
+		{
+			name:    "leading zero in numeric pre-release identifier",
+			version: "1.2.3-rc.01",
+			wantErr: true,
+		},
+		{
+			name:    "empty pre-release",
+			version: "1.2.3-",
+			wantErr: true,
+		},
+		{
+			name:    "empty pre-release identifier",
+			version: "1.2.3-rc..1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pre-release character",
+			version: "1.2.3-rc_1",
+			wantErr: true,
+		},
+		{
+			name:    "empty build metadata",
+			version: "1.2.3+",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			version: "1.2.3-" + strings.Repeat("a", MaxLength),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.version, got, tt.want)
+			}
+			if got.String() != tt.version {
+				t.Errorf("String() = %q, want %q", got.String(), tt.version)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Ascending precedence, from the SemVer 2.0 specification
+	ordered := []string{
+		"1.0.0-0.3.7",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1-rc.1",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0-x-y",
+		"2.0.0",
+	}
+
+	versions := make([]SemVer, len(ordered))
+	for i, s := range ordered {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", s, err)
+		}
+		versions[i] = v
+	}
+
+	for i := range versions {
+		for j := range versions {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := Compare(versions[i], versions[j]); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+
+			// The database orders by the pre-release key, so it must agree with Compare
+			if versions[i].Major == versions[j].Major && versions[i].Minor == versions[j].Minor && versions[i].Patch == versions[j].Patch {
+				got := strings.Compare(PreReleaseKey(versions[i].PreRelease), PreReleaseKey(versions[j].PreRelease))
+				if got != want {
+					t.Errorf("PreReleaseKey order of %s and %s = %d, want %d", ordered[i], ordered[j], got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCompare_IgnoresBuildMetadata(t *testing.T) {
+	a, _ := Parse("1.0.0+a")
+	b, _ := Parse("1.0.0+b")
+	if got := Compare(a, b); got != 0 {
+		t.Errorf("Compare(1.0.0+a, 1.0.0+b) = %d, want 0", got)
+	}
+	if a.WithoutBuild() != "1.0.0" || StripBuild("1.0.0-rc.1+b") != "1.0.0-rc.1" {
+		t.Errorf("build metadata not stripped: %q, %q", a.WithoutBuild(), StripBuild("1.0.0-rc.1+b"))
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,13 +16,26 @@ import (
 	"connectrpc.com/validate"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/pkg/gen/go/isr/v1/isrv1connect"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/auth"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/compat"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/compression"
 	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/handler"
-	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/repository"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/memory"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/postgres"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/sqlite"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate failed: %v", err)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
@@ -30,44 +45,60 @@ func run() error {
 	ctx := context.Background()
 
 	// Get configuration from environment
-	dbURL := os.Getenv("CELO_DB_URL")
-	if dbURL == "" {
-		return fmt.Errorf("CELO_DB_URL environment variable is required")
-	}
-
 	port := os.Getenv("CELO_PORT")
 	if port == "" {
 		port = "50051"
 	}
 
-	// Connect to database
-	pool, err := pgxpool.New(ctx, dbURL)
+	// Rule levels for compatibility checks, e.g. "REQUIRED_TIGHTENED=warn,FIELD_REMOVED=error"
+	compatConfig := compat.DefaultConfig()
+	if err := compat.ParseLevels(os.Getenv("CELO_COMPAT_PATCH_RULES"), compatConfig.Patch); err != nil {
+		return fmt.Errorf("invalid CELO_COMPAT_PATCH_RULES: %w", err)
+	}
+	if err := compat.ParseLevels(os.Getenv("CELO_COMPAT_MINOR_RULES"), compatConfig.Minor); err != nil {
+		return fmt.Errorf("invalid CELO_COMPAT_MINOR_RULES: %w", err)
+	}
+
+	// Open storage
+	repo, closeRepo, err := openStorage(ctx, os.Getenv("CELO_STORAGE"))
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
 	}
-	defer pool.Close()
+	defer closeRepo()
 
-	// Ping database to verify connection
-	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	// Initialize handler
+	schemaHandler := handler.NewSchemaHandler(repo, handler.WithCompatibilityConfig(compatConfig))
+
+	// Push changes committed by other replicas sharing the storage to this replica's WatchSchema streams
+	if notifier, ok := repo.(storage.ChangeNotifier); ok {
+		followCtx, stopFollowing := context.WithCancel(ctx)
+		defer stopFollowing()
+		go schemaHandler.FollowChanges(followCtx, notifier)
 	}
-	log.Println("Successfully connected to database")
 
-	// Run migrations
-	if err := runMigrations(ctx, pool); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	// Authentication runs before validation so that anonymous callers learn nothing about the API
+	var interceptorList []connect.Interceptor
+	if policyPath := os.Getenv("CELO_AUTH_CONFIG"); policyPath != "" {
+		policy, err := auth.LoadPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+		interceptorList = append(interceptorList, auth.NewAuthorizer(policy, repo).Interceptor())
+		log.Printf("Loaded auth policy with %d principals from %s", len(policy.Principals), policyPath)
+	} else {
+		log.Println("CELO_AUTH_CONFIG is not set; anyone who can reach ISR may upload schemas")
 	}
+	interceptorList = append(interceptorList, validate.NewInterceptor())
 
-	// Initialize repository and handler
-	repo := repository.NewSchemaRepository(pool)
-	schemaHandler := handler.NewSchemaHandler(repo)
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		return err
+	}
 
 	// Create HTTP server with Connect
 	mux := http.NewServeMux()
-	interceptors := connect.WithInterceptors(
-		validate.NewInterceptor(),
-	)
-	path, connectHandler := isrv1connect.NewSchemaRegistryServiceHandler(schemaHandler, interceptors)
+	interceptors := connect.WithInterceptors(interceptorList...)
+	path, connectHandler := isrv1connect.NewSchemaRegistryServiceHandler(schemaHandler, interceptors, compression.WithZstd())
 	mux.Handle(path, connectHandler)
 
 	// Add health check endpoint
@@ -79,9 +110,13 @@ func run() error {
 	addr := fmt.Sprintf(":%s", port)
 	srv := &http.Server{
 		Addr:              addr,
-		Handler:           h2c.NewHandler(mux, &http2.Server{}),
+		Handler:           auth.WithClientCertificate(mux),
+		TLSConfig:         tlsConfig,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
+	if tlsConfig == nil {
+		srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{})
+	}
 
 	// Graceful shutdown
 	go func() {
@@ -98,8 +133,14 @@ func run() error {
 		}
 	}()
 
-	log.Printf("ISR service listening on %s", addr)
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	if tlsConfig != nil {
+		log.Printf("ISR service listening on %s (TLS)", addr)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("ISR service listening on %s", addr)
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %w", err)
 	}
 
@@ -107,27 +148,102 @@ func run() error {
 	return nil
 }
 
-func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
-	migration := `
-		CREATE TABLE IF NOT EXISTS schemas (
-			id VARCHAR(36) PRIMARY KEY,
-			version VARCHAR(20) UNIQUE NOT NULL,
-			major INTEGER NOT NULL,
-			minor INTEGER NOT NULL,
-			patch INTEGER NOT NULL,
-			schema_binary BYTEA NOT NULL,
-			size_bytes INTEGER NOT NULL,
-			created_at TIMESTAMP NOT NULL
-		);
-
-		CREATE INDEX IF NOT EXISTS idx_schemas_semver ON schemas(major DESC, minor DESC, patch DESC);
-	`
-
-	_, err := pool.Exec(ctx, migration)
+// loadTLSConfig returns the server TLS configuration from CELO_TLS_CERT_FILE and CELO_TLS_KEY_FILE,
+// or nil to serve plaintext h2c. With CELO_TLS_CLIENT_CA_FILE, client certificates signed by that CA
+// are verified and identify mTLS principals; clients without a certificate can still use bearer tokens.
+func loadTLSConfig() (*tls.Config, error) {
+	certFile, keyFile := os.Getenv("CELO_TLS_CERT_FILE"), os.Getenv("CELO_TLS_KEY_FILE")
+	caFile := os.Getenv("CELO_TLS_CLIENT_CA_FILE")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("CELO_TLS_CLIENT_CA_FILE requires CELO_TLS_CERT_FILE and CELO_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
 	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
 
-	log.Println("Database migrations completed successfully")
-	return nil
+// openStorage opens the SchemaRepository backend selected by CELO_STORAGE:
+// "postgres" (default, CELO_DB_URL), "sqlite" (CELO_STORAGE_PATH, a database file),
+// "file" (CELO_STORAGE_PATH, a directory) or "memory" (lost on restart).
+func openStorage(ctx context.Context, backend string) (storage.SchemaRepository, func(), error) {
+	path := os.Getenv("CELO_STORAGE_PATH")
+
+	switch backend {
+	case "", "postgres":
+		dbURL := os.Getenv("CELO_DB_URL")
+		if dbURL == "" {
+			return nil, nil, fmt.Errorf("CELO_DB_URL environment variable is required")
+		}
+
+		// Connect to database
+		pool, err := pgxpool.New(ctx, dbURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		// Ping database to verify connection
+		if err := pool.Ping(ctx); err != nil {
+			pool.Close()
+			return nil, nil, fmt.Errorf("failed to ping database: %w", err)
+		}
+		log.Println("Successfully connected to database")
+
+		// Run migrations
+		if err := postgres.Migrate(ctx, pool); err != nil {
+			pool.Close()
+			return nil, nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+		return postgres.NewSchemaRepository(pool), pool.Close, nil
+
+	case "sqlite":
+		if path == "" {
+			path = "isr.db"
+		}
+		repo, err := sqlite.Open(ctx, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open SQLite storage: %w", err)
+		}
+		log.Printf("Using SQLite storage at %s", path)
+		return repo, func() { repo.Close() }, nil
+
+	case "file":
+		if path == "" {
+			return nil, nil, fmt.Errorf("CELO_STORAGE_PATH environment variable is required for file storage")
+		}
+		repo, err := memory.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open file storage: %w", err)
+		}
+		log.Printf("Using file storage at %s", path)
+		return repo, func() {}, nil
+
+	case "memory":
+		log.Println("Using in-memory storage; schemas are lost on restart")
+		return memory.New(), func() {}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown CELO_STORAGE %q (want postgres, sqlite, file or memory)", backend)
+	}
 }
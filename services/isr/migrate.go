@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/poi2/building-a-schema-first-dynamic-validation-system/services/isr/internal/storage/postgres"
+)
+
+const migrateUsage = `usage: isr migrate <command>
+
+Commands:
+  up              apply all pending migrations
+  down <version>  revert migrations newer than version (0 reverts all)
+  status          list migrations and whether they are applied
+
+The database is read from CELO_DB_URL.`
+
+// runMigrate implements "isr migrate ..." for the PostgreSQL storage
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", migrateUsage)
+	}
+
+	ctx := context.Background()
+	dbURL := os.Getenv("CELO_DB_URL")
+	if dbURL == "" {
+		return fmt.Errorf("CELO_DB_URL environment variable is required")
+	}
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	switch args[0] {
+	case "up":
+		return postgres.Migrate(ctx, pool)
+
+	case "down":
+		if len(args) != 2 {
+			return fmt.Errorf("down requires a target version\n%s", migrateUsage)
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil || target < 0 {
+			return fmt.Errorf("invalid target version %q", args[1])
+		}
+		return postgres.MigrateDown(ctx, pool, target)
+
+	case "status":
+		states, err := postgres.MigrationStatus(ctx, pool)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range states {
+			status, appliedAt := "pending", ""
+			if s.AppliedAt != nil {
+				status, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.ChecksumMismatch {
+				status = "modified"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+}